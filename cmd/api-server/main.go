@@ -10,11 +10,11 @@ import (
 	"syscall"
 	"time"
 
-	"github.com/gin-gonic/gin"
-	"github.com/prometheus/client_golang/prometheus/promhttp"
-	
-	"github.com/flack/chaos-engineering-as-a-platform/pkg/api/handlers"
+	"github.com/flack/chaos-engineering-as-a-platform/pkg/api/routes"
+	"github.com/flack/chaos-engineering-as-a-platform/pkg/chaos/executor"
+	"github.com/flack/chaos-engineering-as-a-platform/pkg/chaos/experiments"
 	"github.com/flack/chaos-engineering-as-a-platform/pkg/config"
+	"github.com/flack/chaos-engineering-as-a-platform/pkg/k8s/operator"
 	"github.com/flack/chaos-engineering-as-a-platform/pkg/monitoring"
 	"github.com/flack/chaos-engineering-as-a-platform/pkg/storage"
 )
@@ -32,36 +32,31 @@ func main() {
 	defer db.Close()
 
 	metrics := monitoring.NewMetrics()
-	
-	r := gin.New()
-	r.Use(gin.Logger())
-	r.Use(gin.Recovery())
 
-	// Serve static files
-	r.StaticFile("/", "./web/dashboard/api-index.html")
+	// Experiments started and stopped through the API run in this process
+	if cfg.OperatorID == "" {
+		cfg.OperatorID = "api-server"
+	}
+	chaosOperator, err := operator.NewChaosOperator(cfg, db, metrics)
+	if err != nil {
+		log.Fatalf("Failed to create chaos operator: %v", err)
+	}
+	if err := chaosOperator.Start(); err != nil {
+		log.Fatalf("Failed to start chaos operator: %v", err)
+	}
 
-	r.GET("/health", func(c *gin.Context) {
-		c.JSON(http.StatusOK, gin.H{
-			"status": "healthy", 
-			"timestamp": time.Now(),
-		})
+	chaosExecutor := executor.NewExecutor(chaosOperator.GetClient(), db, metrics)
+	chaosExecutor.SetBlastRadius(experiments.BlastRadius{
+		MaxPods:          cfg.BlastRadiusMaxPods,
+		MaxPercentage:    cfg.BlastRadiusMaxPercentage,
+		MinReadyReplicas: cfg.BlastRadiusMinReadyReplicas,
+	})
+	chaosExecutor.SetProtection(experiments.Protection{
+		Namespaces: cfg.ProtectedNamespaces,
+		OptIn:      cfg.OptInMode,
 	})
 
-	r.GET("/metrics", gin.WrapH(promhttp.Handler()))
-
-	v1 := r.Group("/api/v1")
-	{
-		experiments := handlers.NewExperimentHandler(db, metrics)
-		v1.POST("/experiments", experiments.CreateExperiment)
-		v1.GET("/experiments", experiments.ListExperiments)
-		v1.GET("/experiments/:id", experiments.GetExperiment)
-		v1.POST("/experiments/:id/execute", experiments.ExecuteExperiment)
-		v1.DELETE("/experiments/:id", experiments.DeleteExperiment)
-
-		targets := handlers.NewTargetHandler(db)
-		v1.GET("/targets", targets.ListTargets)
-		v1.POST("/targets", targets.CreateTarget)
-	}
+	r := routes.SetupRouter(db, metrics, chaosOperator, chaosExecutor, cfg)
 
 	srv := &http.Server{
 		Addr:    fmt.Sprintf("0.0.0.0:%d", cfg.Port),
@@ -88,5 +83,11 @@ func main() {
 		log.Fatalf("Server forced to shutdown: %v", err)
 	}
 	
+	// Recover the faults of experiments still in progress before exiting
+	chaosExecutor.StopAll("API server shutting down")
+	if err := chaosOperator.Stop(); err != nil {
+		log.Printf("Error during operator shutdown: %v", err)
+	}
+
 	log.Println("Server exiting")
 }
\ No newline at end of file
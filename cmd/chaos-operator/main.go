@@ -9,6 +9,7 @@ import (
 	"github.com/flack/chaos-engineering-as-a-platform/pkg/config"
 	"github.com/flack/chaos-engineering-as-a-platform/pkg/k8s/operator"
 	"github.com/flack/chaos-engineering-as-a-platform/pkg/monitoring"
+	"github.com/flack/chaos-engineering-as-a-platform/pkg/storage"
 )
 
 func main() {
@@ -17,9 +18,18 @@ func main() {
 		log.Fatalf("Failed to load config: %v", err)
 	}
 
+	db, err := storage.NewDatabase(cfg.DatabaseURL)
+	if err != nil {
+		log.Fatalf("Failed to connect to database: %v", err)
+	}
+	defer db.Close()
+
 	metrics := monitoring.NewMetrics()
 
-	chaosOperator, err := operator.NewChaosOperator(cfg, metrics)
+	if cfg.OperatorID == "" {
+		cfg.OperatorID = "chaos-operator"
+	}
+	chaosOperator, err := operator.NewChaosOperator(cfg, db, metrics)
 	if err != nil {
 		log.Fatalf("Failed to create chaos operator: %v", err)
 	}
@@ -0,0 +1,59 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// apiURL is the address of the API server the commands talk to
+var apiURL string
+
+// apiClient waits long enough for a stopped experiment to recover
+var apiClient = &http.Client{Timeout: 10 * time.Minute}
+
+// post sends a POST request to the API server, see request
+func post(path string, body interface{}) (map[string]interface{}, error) {
+	return request(http.MethodPost, path, body)
+}
+
+// request sends a request to the API server and decodes its JSON response. A
+// response other than 200 OK or 202 Accepted is returned as an error with its message.
+func request(method, path string, body interface{}) (map[string]interface{}, error) {
+	var payload io.Reader
+	if body != nil {
+		encoded, err := json.Marshal(body)
+		if err != nil {
+			return nil, fmt.Errorf("failed to encode request: %w", err)
+		}
+		payload = bytes.NewReader(encoded)
+	}
+
+	req, err := http.NewRequest(method, strings.TrimSuffix(apiURL, "/")+"/api/v1"+path, payload)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := apiClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to reach the API server: %w", err)
+	}
+	defer resp.Body.Close()
+
+	response := map[string]interface{}{}
+	if err := json.NewDecoder(resp.Body).Decode(&response); err != nil {
+		return nil, fmt.Errorf("failed to decode response: %w", err)
+	}
+	if resp.StatusCode != http.StatusOK && resp.StatusCode != http.StatusAccepted {
+		if message, ok := response["error"].(string); ok {
+			return nil, fmt.Errorf("%s", message)
+		}
+		return nil, fmt.Errorf("unexpected status code: %d", resp.StatusCode)
+	}
+	return response, nil
+}
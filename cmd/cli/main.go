@@ -2,6 +2,7 @@ package main
 
 import (
 	"fmt"
+	"net/http"
 	"os"
 
 	"github.com/spf13/cobra"
@@ -13,6 +14,10 @@ var rootCmd = &cobra.Command{
 	Use:   "chaos-cli",
 	Short: "Chaos Engineering Platform CLI",
 	Long:  `Command line interface for the Chaos Engineering Platform`,
+
+	// main reports errors, usage is only printed on request
+	SilenceErrors: true,
+	SilenceUsage:  true,
 }
 
 var createExperimentCmd = &cobra.Command{
@@ -43,10 +48,97 @@ var executeExperimentCmd = &cobra.Command{
 	},
 }
 
+var stopExperimentCmd = &cobra.Command{
+	Use:   "stop [experiment-id]",
+	Short: "Stop a running chaos experiment and wait for its faults to be recovered",
+	Args:  cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		fmt.Printf("Stopping experiment %s...\n", args[0])
+		response, err := post("/experiments/"+args[0]+"/stop", nil)
+		if err != nil {
+			return err
+		}
+		if response["status"] == "stopping" {
+			fmt.Printf("Stop of experiment %s requested from %v, which has not stopped it yet\n", args[0], response["owners"])
+			return nil
+		}
+		fmt.Printf("Experiment %s cancelled\n", args[0])
+		return nil
+	},
+}
+
+// Who flips the kill switch and why
+var (
+	killSwitchActor  string
+	killSwitchReason string
+)
+
+var killSwitchCmd = &cobra.Command{
+	Use:   "kill-switch",
+	Short: "Show the state of the global kill switch",
+	Args:  cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		state, err := request(http.MethodGet, "/kill-switch", nil)
+		if err != nil {
+			return err
+		}
+		if engaged, _ := state["engaged"].(bool); !engaged {
+			fmt.Println("Kill switch is clear")
+			return nil
+		}
+		fmt.Printf("Kill switch engaged by %v at %v: %v\n", state["actor"], state["changed_at"], state["reason"])
+		return nil
+	},
+}
+
+var engageKillSwitchCmd = &cobra.Command{
+	Use:   "engage",
+	Short: "Halt all chaos, stopping every running experiment and waiting for its faults to be recovered",
+	Args:  cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		fmt.Println("Engaging the kill switch...")
+		response, err := request(http.MethodPut, "/kill-switch", map[string]interface{}{
+			"engaged": true,
+			"actor":   killSwitchActor,
+			"reason":  killSwitchReason,
+		})
+		if err != nil {
+			return err
+		}
+		fmt.Printf("Kill switch engaged, stopped experiments: %v\n", response["stopped"])
+		return nil
+	},
+}
+
+var clearKillSwitchCmd = &cobra.Command{
+	Use:   "clear",
+	Short: "Allow experiments to run again",
+	Args:  cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		_, err := request(http.MethodPut, "/kill-switch", map[string]interface{}{
+			"engaged": false,
+			"actor":   killSwitchActor,
+			"reason":  killSwitchReason,
+		})
+		if err != nil {
+			return err
+		}
+		fmt.Println("Kill switch cleared")
+		return nil
+	},
+}
+
 func init() {
 	rootCmd.AddCommand(createExperimentCmd)
 	rootCmd.AddCommand(listExperimentsCmd)
 	rootCmd.AddCommand(executeExperimentCmd)
+	rootCmd.AddCommand(stopExperimentCmd)
+
+	killSwitchCmd.PersistentFlags().StringVar(&killSwitchActor, "actor", os.Getenv("USER"), "Who flips the kill switch")
+	killSwitchCmd.PersistentFlags().StringVar(&killSwitchReason, "reason", "", "Why the kill switch is flipped")
+	killSwitchCmd.AddCommand(engageKillSwitchCmd)
+	killSwitchCmd.AddCommand(clearKillSwitchCmd)
+	rootCmd.AddCommand(killSwitchCmd)
 }
 
 func main() {
@@ -56,8 +148,8 @@ func main() {
 		os.Exit(1)
 	}
 
-	// Set global config for CLI
-	_ = cfg
+	// Set global config for CLI, commands talk to the API server on the configured port by default
+	rootCmd.PersistentFlags().StringVar(&apiURL, "api-url", fmt.Sprintf("http://localhost:%d", cfg.Port), "URL of the API server")
 
 	if err := rootCmd.Execute(); err != nil {
 		fmt.Println(err)
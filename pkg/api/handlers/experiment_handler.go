@@ -2,12 +2,18 @@ package handlers
 
 import (
 	"encoding/json"
+	"errors"
+	"fmt"
 	"net/http"
+	"strconv"
 	"time"
 
 	"github.com/gin-gonic/gin"
 	"github.com/google/uuid"
 
+	"github.com/flack/chaos-engineering-as-a-platform/pkg/chaos/executor"
+	"github.com/flack/chaos-engineering-as-a-platform/pkg/chaos/experiments"
+	"github.com/flack/chaos-engineering-as-a-platform/pkg/chaos/probes"
 	"github.com/flack/chaos-engineering-as-a-platform/pkg/k8s/operator"
 	"github.com/flack/chaos-engineering-as-a-platform/pkg/monitoring"
 	"github.com/flack/chaos-engineering-as-a-platform/pkg/storage"
@@ -18,6 +24,7 @@ type ExperimentHandler struct {
 	db      *storage.Database
 	metrics *monitoring.Metrics
 	operator *operator.ChaosOperator
+	executor *executor.Executor
 }
 
 // NewExperimentHandler creates a new experiment handler
@@ -33,6 +40,11 @@ func (h *ExperimentHandler) SetOperator(operator *operator.ChaosOperator) {
 	h.operator = operator
 }
 
+// SetExecutor sets the executor whose runs can be stopped along with the operator's
+func (h *ExperimentHandler) SetExecutor(executor *executor.Executor) {
+	h.executor = executor
+}
+
 // CreateExperimentRequest represents a request to create a new experiment
 type CreateExperimentRequest struct {
 	Name        string            `json:"name" binding:"required"`
@@ -41,6 +53,9 @@ type CreateExperimentRequest struct {
 	Target      string            `json:"target" binding:"required"`
 	Parameters  map[string]string `json:"parameters"`
 	Duration    int               `json:"duration" binding:"required"`
+	Probes      []probes.Probe    `json:"probes"`
+
+	AbortConditions *probes.AbortConditions `json:"abort_conditions"`
 }
 
 // CreateExperiment handles the creation of a new experiment
@@ -51,6 +66,12 @@ func (h *ExperimentHandler) CreateExperiment(c *gin.Context) {
 		return
 	}
 
+	// Reject parameters that do not match the schema of the experiment type
+	if fieldErrs := h.validateExperiment(&req); len(fieldErrs) > 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid experiment", "fields": fieldErrs})
+		return
+	}
+
 	// Convert parameters map to JSON string
 	paramsJSON, err := json.Marshal(req.Parameters)
 	if err != nil {
@@ -58,6 +79,21 @@ func (h *ExperimentHandler) CreateExperiment(c *gin.Context) {
 		return
 	}
 
+	// Probes and abort conditions are stored as JSON as well
+	var probesJSON, abortJSON json.RawMessage
+	if len(req.Probes) > 0 {
+		if probesJSON, err = json.Marshal(req.Probes); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to marshal probes"})
+			return
+		}
+	}
+	if req.AbortConditions != nil {
+		if abortJSON, err = json.Marshal(req.AbortConditions); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to marshal abort conditions"})
+			return
+		}
+	}
+
 	// Create a new experiment
 	now := time.Now()
 	experiment := &storage.Experiment{
@@ -71,6 +107,9 @@ func (h *ExperimentHandler) CreateExperiment(c *gin.Context) {
 		CreatedAt:   now,
 		UpdatedAt:   now,
 		Duration:    req.Duration,
+		Probes:      probesJSON,
+
+		AbortConditions: abortJSON,
 	}
 
 	// Save the experiment to the database
@@ -85,6 +124,103 @@ func (h *ExperimentHandler) CreateExperiment(c *gin.Context) {
 	c.JSON(http.StatusCreated, experiment)
 }
 
+// validateExperiment checks a create request the way the experiment will be
+// resolved when it runs and returns every invalid field
+func (h *ExperimentHandler) validateExperiment(req *CreateExperimentRequest) experiments.ValidationError {
+	var fieldErrs experiments.ValidationError
+	if req.Duration <= 0 {
+		fieldErrs = append(fieldErrs, experiments.FieldError{Field: "duration", Message: "must be greater than 0"})
+	}
+
+	definition, exists := experiments.Lookup(req.Type)
+	if !exists {
+		return append(fieldErrs, experiments.FieldError{Field: "type", Message: "is not a registered experiment type"})
+	}
+
+	// Without a selector parameter the operator selects by the target
+	spec := experiments.Spec{
+		Namespace: req.Parameters["namespace"],
+		Selector:  req.Parameters["selector"],
+		Duration:  req.Duration,
+		Params:    req.Parameters,
+	}
+	if spec.Selector == "" {
+		spec.Selector = req.Target
+	}
+
+	// A stored target supplies the namespace and selector
+	if definition.TargetType != "" {
+		if target, err := h.db.GetTarget(req.Target); err == nil {
+			if string(target.Type) != definition.TargetType {
+				fieldErrs = append(fieldErrs, experiments.FieldError{
+					Field:   "target",
+					Message: fmt.Sprintf("has type %s, %s requires a %s target", target.Type, definition.Type, definition.TargetType),
+				})
+			}
+			spec.Namespace = target.Namespace
+			spec.Selector = target.Selector
+			spec.Targeted = true
+		}
+	}
+
+	if paramErrs, ok := parameterErrors(definition.Validate(spec)); ok {
+		fieldErrs = append(fieldErrs, paramErrs...)
+	}
+
+	for i, probe := range req.Probes {
+		if err := probe.Validate(); err != nil {
+			fieldErrs = append(fieldErrs, experiments.FieldError{Field: fmt.Sprintf("probes[%d]", i), Message: err.Error()})
+		}
+	}
+	if req.AbortConditions != nil {
+		if err := req.AbortConditions.Validate(); err != nil {
+			fieldErrs = append(fieldErrs, experiments.FieldError{Field: "abort_conditions", Message: err.Error()})
+		}
+	}
+
+	return fieldErrs
+}
+
+// parameterErrors returns the field errors of an invalid parameters error,
+// named after the request field holding the parameters
+func parameterErrors(err error) (experiments.ValidationError, bool) {
+	var paramErrs experiments.ValidationError
+	if !errors.As(err, &paramErrs) {
+		return nil, false
+	}
+
+	fieldErrs := make(experiments.ValidationError, len(paramErrs))
+	for i, fieldErr := range paramErrs {
+		fieldErr.Field = "parameters." + fieldErr.Field
+		fieldErrs[i] = fieldErr
+	}
+	return fieldErrs, true
+}
+
+// writePlanError responds with the reason an experiment cannot be planned
+func writePlanError(c *gin.Context, err error) {
+	if fieldErrs, ok := parameterErrors(err); ok {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid experiment", "fields": fieldErrs})
+		return
+	}
+	var protectedErr *experiments.ProtectedError
+	if errors.As(err, &protectedErr) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "targets protected resources", "resources": protectedErr.Resources})
+		return
+	}
+	var blastErr *experiments.BlastRadiusError
+	if errors.As(err, &blastErr) {
+		c.JSON(http.StatusUnprocessableEntity, gin.H{"error": "rejected by the blast-radius policy", "reasons": blastErr.Reasons})
+		return
+	}
+	c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+}
+
+// ListExperimentTypes handles listing the registered experiment types and their parameters
+func (h *ExperimentHandler) ListExperimentTypes(c *gin.Context) {
+	c.JSON(http.StatusOK, experiments.Definitions())
+}
+
 // ListExperiments handles listing all experiments
 func (h *ExperimentHandler) ListExperiments(c *gin.Context) {
 	experiments, err := h.db.ListExperiments()
@@ -108,6 +244,23 @@ func (h *ExperimentHandler) GetExperiment(c *gin.Context) {
 	c.JSON(http.StatusOK, experiment)
 }
 
+// GetExperimentResults handles retrieving the results of an experiment's runs
+func (h *ExperimentHandler) GetExperimentResults(c *gin.Context) {
+	id := c.Param("id")
+	if _, err := h.db.GetExperiment(id); err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+
+	results, err := executor.ListResults(h.db, id)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, results)
+}
+
 // ExecuteExperiment handles executing an experiment
 func (h *ExperimentHandler) ExecuteExperiment(c *gin.Context) {
 	id := c.Param("id")
@@ -125,10 +278,12 @@ func (h *ExperimentHandler) ExecuteExperiment(c *gin.Context) {
 		return
 	}
 
-	// Update the experiment status
-	if err := h.db.UpdateExperimentStatus(id, storage.StatusRunning); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
-		return
+	dryRun := false
+	if value := c.Query("dry_run"); value != "" {
+		if dryRun, err = strconv.ParseBool(value); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid dry_run value: " + value})
+			return
+		}
 	}
 
 	// Parse parameters from JSON string
@@ -138,6 +293,12 @@ func (h *ExperimentHandler) ExecuteExperiment(c *gin.Context) {
 		return
 	}
 
+	probeList, abort, err := executor.ExperimentChecks(experiment)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
 	// Create experiment config
 	config := &operator.ExperimentConfig{
 		ID:       experiment.ID,
@@ -145,22 +306,207 @@ func (h *ExperimentHandler) ExecuteExperiment(c *gin.Context) {
 		Target:   experiment.Target,
 		Params:   params,
 		Duration: experiment.Duration,
+		Probes:   probeList,
+		Abort:    abort,
+		Trigger:  storage.RunTriggerAPI,
+	}
+
+	// The experiment is planned first, so one that cannot run is refused
+	// before it is marked as running
+	plan, err := h.operator.PlanExperiment(c.Request.Context(), config)
+	if err != nil {
+		writePlanError(c, err)
+		return
+	}
+
+	// A dry run reports the planned actions and leaves the experiment as it is
+	if dryRun {
+		c.JSON(http.StatusOK, plan)
+		return
 	}
 
 	// Run the experiment
-	if err := h.operator.RunExperiment(config); err != nil {
-		// Revert the status if the experiment fails to start
-		_ = h.db.UpdateExperimentStatus(id, storage.StatusFailed)
+	run, err := h.operator.RunExperiment(config)
+	if err != nil {
+		// Another run holds the target
+		var conflict *storage.LockConflictError
+		if errors.As(err, &conflict) {
+			c.JSON(http.StatusConflict, gin.H{"error": conflict.Error(), "lock": conflict.Lock})
+			return
+		}
+
+		// All chaos is halted
+		var halted *storage.KillSwitchError
+		if errors.As(err, &halted) {
+			c.JSON(http.StatusServiceUnavailable, gin.H{"error": halted.Error(), "kill_switch": halted.Switch})
+			return
+		}
+
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
 	}
 
 	c.JSON(http.StatusOK, gin.H{
 		"id":     experiment.ID,
+		"run_id": run.ID,
 		"status": "running",
 	})
 }
 
+// stopRequestTimeout bounds how long a stop waits for another process to stop its runs
+const stopRequestTimeout = 5 * time.Minute
+
+// StopExperiment handles stopping an experiment in progress, on whichever of
+// the operator and executor runs it. Runs of other processes are asked to stop
+// through the database. It responds once the faults have been recovered, or
+// with 202 Accepted if another process has not stopped its runs in time.
+func (h *ExperimentHandler) StopExperiment(c *gin.Context) {
+	id := c.Param("id")
+
+	var err error
+	notRunning := true
+	if h.operator != nil {
+		err = h.operator.StopExperiment(id)
+		notRunning = errors.Is(err, operator.ErrNotRunning)
+	}
+	if notRunning && h.executor != nil {
+		err = h.executor.StopExperiment(id)
+		notRunning = errors.Is(err, executor.ErrNotRunning)
+	}
+
+	// Only an experiment that is not running here is looked up, to tell a
+	// missing one apart and find the runs of other processes
+	if notRunning {
+		if _, err := h.db.GetExperiment(id); err != nil {
+			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+			return
+		}
+
+		runs, err := h.requestStop(id)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		if len(runs) == 0 {
+			c.JSON(http.StatusConflict, gin.H{"error": "experiment is not running"})
+			return
+		}
+
+		if !h.waitForStop(runs, time.Now().Add(stopRequestTimeout)) {
+			owners := []string{}
+			for _, run := range runs {
+				owners = append(owners, run.Owner)
+			}
+			c.JSON(http.StatusAccepted, gin.H{
+				"id":     id,
+				"status": "stopping",
+				"owners": owners,
+			})
+			return
+		}
+	} else if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"id":     id,
+		"status": "cancelled",
+	})
+}
+
+// requestStop asks the owners of the unfinished runs of an experiment to stop
+// them and returns the runs
+func (h *ExperimentHandler) requestStop(experimentID string) ([]*storage.Run, error) {
+	runs, err := h.db.ListExperimentRuns(experimentID)
+	if err != nil {
+		return nil, err
+	}
+
+	unfinished := []*storage.Run{}
+	for _, run := range runs {
+		if storage.Finished(run.Status) {
+			continue
+		}
+		if err := h.db.RequestRunStop(&storage.StopRequest{RunID: run.ID, Reason: "Stopped", RequestedAt: time.Now()}); err != nil {
+			return nil, err
+		}
+		unfinished = append(unfinished, run)
+	}
+	return unfinished, nil
+}
+
+// waitForStop waits until the runs have ended, or the deadline has passed.
+// It reports whether they have ended.
+func (h *ExperimentHandler) waitForStop(runs []*storage.Run, deadline time.Time) bool {
+	for _, run := range runs {
+		for {
+			stored, err := h.db.GetRun(run.ID)
+			if err == nil && storage.Finished(stored.Status) {
+				break
+			}
+			if time.Now().After(deadline) {
+				return false
+			}
+			time.Sleep(time.Second)
+		}
+	}
+	return true
+}
+
+// ListExperimentRuns handles listing the runs of an experiment
+func (h *ExperimentHandler) ListExperimentRuns(c *gin.Context) {
+	id := c.Param("id")
+	if _, err := h.db.GetExperiment(id); err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+
+	runs, err := h.db.ListExperimentRuns(id)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, runs)
+}
+
+// runResponse is a run with its result as the experiment reported it
+type runResponse struct {
+	*storage.Run
+	Result *experiments.ExperimentResult `json:"result,omitempty"`
+}
+
+// GetRun handles retrieving a single run with its result
+func (h *ExperimentHandler) GetRun(c *gin.Context) {
+	run, err := h.db.GetRun(c.Param("runId"))
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+
+	response := runResponse{Run: run}
+	if run.Result != nil {
+		if response.Result, err = executor.StoredResult(run.Result); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+	}
+
+	c.JSON(http.StatusOK, response)
+}
+
+// ListLocks handles listing the targets locked by running experiments
+func (h *ExperimentHandler) ListLocks(c *gin.Context) {
+	locks, err := h.db.ListTargetLocks()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, locks)
+}
+
 // DeleteExperiment handles deleting an experiment
 func (h *ExperimentHandler) DeleteExperiment(c *gin.Context) {
 	id := c.Param("id")
@@ -0,0 +1,96 @@
+package handlers
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/flack/chaos-engineering-as-a-platform/pkg/chaos/executor"
+	"github.com/flack/chaos-engineering-as-a-platform/pkg/k8s/operator"
+	"github.com/flack/chaos-engineering-as-a-platform/pkg/storage"
+)
+
+// KillSwitchHandler handles the global kill switch
+type KillSwitchHandler struct {
+	db       *storage.Database
+	operator *operator.ChaosOperator
+	executor *executor.Executor
+}
+
+// NewKillSwitchHandler creates a new kill switch handler
+func NewKillSwitchHandler(db *storage.Database) *KillSwitchHandler {
+	return &KillSwitchHandler{
+		db: db,
+	}
+}
+
+// SetOperator sets the chaos operator whose experiments are halted by the kill switch
+func (h *KillSwitchHandler) SetOperator(operator *operator.ChaosOperator) {
+	h.operator = operator
+}
+
+// SetExecutor sets the executor whose runs are halted by the kill switch
+func (h *KillSwitchHandler) SetExecutor(executor *executor.Executor) {
+	h.executor = executor
+}
+
+// SetKillSwitchRequest represents a request to engage or clear the kill
+// switch. The actor is reported by the caller and recorded as given, since
+// the API does not authenticate callers.
+type SetKillSwitchRequest struct {
+	Engaged *bool  `json:"engaged" binding:"required"`
+	Actor   string `json:"actor" binding:"required"`
+	Reason  string `json:"reason"`
+}
+
+// GetKillSwitch handles retrieving the state of the kill switch
+func (h *KillSwitchHandler) GetKillSwitch(c *gin.Context) {
+	state, err := h.db.GetKillSwitch()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, state)
+}
+
+// SetKillSwitch handles engaging or clearing the kill switch. Engaging it
+// stops every experiment in progress and waits until their faults have been
+// recovered.
+func (h *KillSwitchHandler) SetKillSwitch(c *gin.Context) {
+	var req SetKillSwitchRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	state := &storage.KillSwitch{
+		Engaged:   *req.Engaged,
+		Actor:     req.Actor,
+		Reason:    req.Reason,
+		ChangedAt: time.Now(),
+	}
+
+	// The switch is stored first, so no new run starts while these are stopped
+	if err := h.db.SetKillSwitch(state); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	stopped := []string{}
+	if state.Engaged {
+		halted := &storage.KillSwitchError{Switch: state}
+		if h.operator != nil {
+			stopped = append(stopped, h.operator.StopAll(halted.Error())...)
+		}
+		if h.executor != nil {
+			stopped = append(stopped, h.executor.StopAll(halted.Error())...)
+		}
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"kill_switch": state,
+		"stopped":     stopped,
+	})
+}
@@ -1,29 +1,38 @@
 package routes
 
 import (
+	"time"
+
 	"github.com/gin-gonic/gin"
 	"github.com/prometheus/client_golang/prometheus/promhttp"
 	
 	"github.com/flack/chaos-engineering-as-a-platform/pkg/api/handlers"
 	"github.com/flack/chaos-engineering-as-a-platform/pkg/api/middleware"
+	"github.com/flack/chaos-engineering-as-a-platform/pkg/chaos/executor"
 	"github.com/flack/chaos-engineering-as-a-platform/pkg/config"
 	"github.com/flack/chaos-engineering-as-a-platform/pkg/k8s/operator"
 	"github.com/flack/chaos-engineering-as-a-platform/pkg/monitoring"
 	"github.com/flack/chaos-engineering-as-a-platform/pkg/storage"
 )
 
-// SetupRouter sets up the API routes
-func SetupRouter(db *storage.Database, metrics *monitoring.Metrics, chaosOperator *operator.ChaosOperator, cfg *config.Config) *gin.Engine {
+// SetupRouter sets up the API routes. Experiments are run by the operator and
+// the executor, which are also the ones stopped through the API; the executor
+// may be nil when only the operator runs experiments.
+func SetupRouter(db *storage.Database, metrics *monitoring.Metrics, chaosOperator *operator.ChaosOperator, chaosExecutor *executor.Executor, cfg *config.Config) *gin.Engine {
 	r := gin.New()
 	r.Use(gin.Logger())
 	r.Use(gin.Recovery())
 	r.Use(middleware.CORSMiddleware())
 	r.Use(middleware.MetricsMiddleware(metrics))
 
+	// Serve static files
+	r.StaticFile("/", "./web/dashboard/api-index.html")
+
 	// Public endpoints
 	r.GET("/health", func(c *gin.Context) {
 		c.JSON(200, gin.H{
-			"status": "healthy",
+			"status":    "healthy",
+			"timestamp": time.Now(),
 		})
 	})
 	r.GET("/metrics", gin.WrapH(promhttp.Handler()))
@@ -38,12 +47,26 @@ func SetupRouter(db *storage.Database, metrics *monitoring.Metrics, chaosOperato
 		// Experiment endpoints
 		experimentHandler := handlers.NewExperimentHandler(db, metrics)
 		experimentHandler.SetOperator(chaosOperator)
+		experimentHandler.SetExecutor(chaosExecutor)
 		
 		v1.POST("/experiments", experimentHandler.CreateExperiment)
 		v1.GET("/experiments", experimentHandler.ListExperiments)
 		v1.GET("/experiments/:id", experimentHandler.GetExperiment)
+		v1.GET("/experiments/:id/results", experimentHandler.GetExperimentResults)
 		v1.POST("/experiments/:id/execute", experimentHandler.ExecuteExperiment)
+		v1.POST("/experiments/:id/stop", experimentHandler.StopExperiment)
+		v1.GET("/experiments/:id/runs", experimentHandler.ListExperimentRuns)
+		v1.GET("/runs/:runId", experimentHandler.GetRun)
 		v1.DELETE("/experiments/:id", experimentHandler.DeleteExperiment)
+		v1.GET("/experiment-types", experimentHandler.ListExperimentTypes)
+		v1.GET("/locks", experimentHandler.ListLocks)
+
+		// Kill switch endpoints
+		killSwitchHandler := handlers.NewKillSwitchHandler(db)
+		killSwitchHandler.SetOperator(chaosOperator)
+		killSwitchHandler.SetExecutor(chaosExecutor)
+		v1.GET("/kill-switch", killSwitchHandler.GetKillSwitch)
+		v1.PUT("/kill-switch", killSwitchHandler.SetKillSwitch)
 
 		// Target endpoints
 		targetHandler := handlers.NewTargetHandler(db)
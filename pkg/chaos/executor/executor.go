@@ -3,9 +3,10 @@ package executor
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"log"
-	"strconv"
+	"sync"
 	"time"
 
 	"github.com/google/uuid"
@@ -16,19 +17,39 @@ import (
 	"github.com/flack/chaos-engineering-as-a-platform/pkg/storage"
 )
 
-// ExperimentParams holds the common parameters for all experiments
-type ExperimentParams struct {
-	Namespace string
-	Selector  string
-	Value     int    // Generic value (percentage, delay, load, size)
-	ValueUnit string // Unit for the value (%, ms, MB)
+// experimentGracePeriod is the extra time an experiment gets beyond its duration to set up and tear down faults
+const experimentGracePeriod = 2 * time.Minute
+
+// dryRunTimeout bounds the cluster lookups of a dry run
+const dryRunTimeout = 30 * time.Second
+
+// signalInterval is how often a run checks the kill switch and whether its
+// stop was requested, both of which may come from another process
+const signalInterval = 5 * time.Second
+
+// ErrNotRunning reports that the executor has no run of an experiment in progress
+var ErrNotRunning = errors.New("experiment is not running")
+
+// execution is a run in progress that can be stopped
+type execution struct {
+	runID        string
+	experimentID string
+	ctx          context.Context // Cancelled when the run is stopped
+	cancel       context.CancelFunc
+	reason       string        // Why the run was stopped, guarded by executionsMu
+	done         chan struct{} // Closed once the run has recovered and recorded its result
 }
 
 // Executor executes chaos experiments by running them against targets and tracking their results
 type Executor struct {
-	client  *k8s.Client
-	db      *storage.Database
-	metrics *monitoring.Metrics
+	client      *k8s.Client
+	db          *storage.Database
+	metrics     *monitoring.Metrics
+	blastRadius experiments.BlastRadius
+	protection  experiments.Protection
+
+	executionsMu sync.Mutex
+	executions   map[string]*execution // Runs in progress by run ID
 }
 
 // NewExecutor creates a new experiment executor with the provided Kubernetes client, database, and metrics
@@ -44,12 +65,25 @@ func NewExecutor(client *k8s.Client, db *storage.Database, metrics *monitoring.M
 	}
 
 	return &Executor{
-		client:  client,
-		db:      db,
-		metrics: metrics,
+		client:      client,
+		db:          db,
+		metrics:     metrics,
+		blastRadius: experiments.DefaultBlastRadius,
+		protection:  experiments.DefaultProtection,
+		executions:  make(map[string]*execution),
 	}
 }
 
+// SetBlastRadius sets the policy that limits what a single experiment may affect
+func (e *Executor) SetBlastRadius(policy experiments.BlastRadius) {
+	e.blastRadius = policy
+}
+
+// SetProtection sets the namespaces no experiment may touch
+func (e *Executor) SetProtection(protection experiments.Protection) {
+	e.protection = protection
+}
+
 // parseParams parses experiment parameters from JSON string
 func parseParams(experiment *storage.Experiment) (map[string]string, error) {
 	if experiment == nil {
@@ -65,103 +99,70 @@ func parseParams(experiment *storage.Experiment) (map[string]string, error) {
 	return params, nil
 }
 
-// getExperimentParams extracts common parameters for experiments
-func getExperimentParams(params map[string]string, paramName string, defaultValue int, unit string) (*ExperimentParams, error) {
-	// Check for required parameters
-	namespace, ok := params["namespace"]
-	if !ok || namespace == "" {
-		return nil, fmt.Errorf("missing required parameter: namespace")
-	}
-	
-	// Get selector parameter
-	selector, ok := params["selector"]
-	if !ok || selector == "" {
-		return nil, fmt.Errorf("missing required parameter: selector")
+// ExecuteExperiment executes a chaos experiment identified by experimentID as
+// a new run started by trigger and returns the result
+func (e *Executor) ExecuteExperiment(experimentID string, trigger storage.RunTrigger) (*experiments.ExperimentResult, error) {
+	// Validate experiment ID
+	if experimentID == "" {
+		return nil, fmt.Errorf("experiment ID cannot be empty")
 	}
-	
-	// Get value parameter with default
-	valueStr, ok := params[paramName]
-	value := defaultValue
-	if ok && valueStr != "" {
-		parsedValue, err := strconv.Atoi(valueStr)
-		if err != nil {
-			log.Printf("Invalid %s parameter: %v, using default", paramName, err)
-		} else if parsedValue > 0 {
-			value = parsedValue
-		}
+
+	// Get the experiment from the database
+	experiment, err := e.db.GetExperiment(experimentID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get experiment: %w", err)
 	}
-	
-	// Log the value being used
-	log.Printf("Using %s of %d%s for experiment", paramName, value, unit)
-	
-	return &ExperimentParams{
-		Namespace: namespace,
-		Selector:  selector,
-		Value:     value,
-		ValueUnit: unit,
-	}, nil
-}
 
-// executeGenericExperiment executes a generic experiment with common behavior
-func (e *Executor) executeGenericExperiment(ctx context.Context, experiment *storage.Experiment, experimentType string, params *ExperimentParams) (*experiments.ExperimentResult, error) {
-	if ctx == nil {
-		return nil, fmt.Errorf("context cannot be nil")
+	// Nothing runs while the kill switch is engaged
+	if err := e.db.CheckKillSwitch(); err != nil {
+		return nil, err
 	}
-	if experiment == nil {
-		return nil, fmt.Errorf("experiment cannot be nil")
+
+	// An experiment without a duration is refused before it gets a run or a lock
+	if experiment.Duration <= 0 {
+		return nil, fmt.Errorf("invalid experiment duration: %d, must be greater than 0", experiment.Duration)
 	}
-	
-	// Create a result object
-	result := &experiments.ExperimentResult{
-		ExperimentType: experimentType,
-		StartTime:      time.Now(),
-		Success:        true,
+
+	// Every execution is a run of its own, the experiment is left as it is
+	run := &storage.Run{
+		ID:           uuid.New().String(),
+		ExperimentID: experimentID,
+		Trigger:      trigger,
+		Status:       storage.StatusPending,
+		CreatedAt:    time.Now(),
+		Owner:        storage.JournalOwnerExecutor,
 	}
-	
-	// Simulate experiment execution
-	select {
-	case <-time.After(time.Duration(experiment.Duration) * time.Second):
-		// Experiment completed successfully
-	case <-ctx.Done():
-		// Experiment was cancelled
-		result.Error = "Experiment cancelled"
-		result.Success = false
-		return result, ctx.Err()
+	if err := e.db.CreateRun(run, storage.JournalOwnerExecutor); err != nil {
+		return nil, err
 	}
-	
-	result.EndTime = time.Now()
-	return result, nil
-}
 
-// ExecuteExperiment executes a chaos experiment identified by experimentID and returns the result
-func (e *Executor) ExecuteExperiment(experimentID string) (*experiments.ExperimentResult, error) {
-	// Validate experiment ID
-	if experimentID == "" {
-		return nil, fmt.Errorf("experiment ID cannot be empty")
-	}
+	// The run can be stopped from here on
+	tracked, done := e.track(run)
+	defer done()
+	stopCtx := tracked.ctx
+	go e.watchSignals(tracked)
 
-	// Get the experiment from the database
-	experiment, err := e.db.GetExperiment(experimentID)
+	// A run against a target another run holds fails right away
+	release, err := e.lockTarget(experiment)
 	if err != nil {
-		return nil, fmt.Errorf("failed to get experiment: %w", err)
+		e.transitionRun(run, storage.StatusFailed, err.Error())
+		return nil, err
 	}
+	defer release()
 
-	// Update experiment status to running
-	if err := e.db.UpdateExperimentStatus(experimentID, storage.StatusRunning); err != nil {
-		return nil, fmt.Errorf("failed to update experiment status: %w", err)
+	// Update run status to running. A run that cannot be started fails, and
+	// gives up its lock and tracking on return.
+	if err := e.db.TransitionRun(run.ID, storage.StatusRunning, storage.JournalOwnerExecutor, "Started"); err != nil {
+		e.transitionRun(run, storage.StatusFailed, err.Error())
+		return nil, err
 	}
 
 	// Increment active experiments metric
 	e.metrics.ActiveExperiments.Inc()
 	defer e.metrics.ActiveExperiments.Dec()
 
-	// Validate experiment duration
-	if experiment.Duration <= 0 {
-		return nil, fmt.Errorf("invalid experiment duration: %d, must be greater than 0", experiment.Duration)
-	}
-
-	// Create a context with timeout
-	ctx, cancel := context.WithTimeout(context.Background(), time.Duration(experiment.Duration)*time.Second)
+	// Create a context with timeout, leaving room for injection and cleanup around the duration
+	ctx, cancel := context.WithTimeout(stopCtx, time.Duration(experiment.Duration)*time.Second+experimentGracePeriod)
 	defer cancel()
 
 	// Execute the experiment based on its type
@@ -172,25 +173,25 @@ func (e *Executor) ExecuteExperiment(experimentID string) (*experiments.Experime
 	if experiment.Type == "" {
 		execErr = fmt.Errorf("experiment type cannot be empty")
 	} else {
-		// Use a map to simplify experiment type selection
-		executors := map[storage.ExperimentType]func(context.Context, *storage.Experiment) (*experiments.ExperimentResult, error){
-			storage.PodFailure:   e.executePodFailure,
-			storage.NetworkDelay: e.executeNetworkDelay,
-			storage.CPUStress:    e.executeCPUStress,
-			storage.MemoryStress: e.executeMemoryStress,
-		}
-		
-		executor, exists := executors[experiment.Type]
+		definition, exists := experiments.Lookup(string(experiment.Type))
 		if exists {
-			result, execErr = executor(ctx, experiment)
+			result, execErr = e.execute(ctx, definition, experiment)
 		} else {
 			execErr = fmt.Errorf("unsupported experiment type: %s", experiment.Type)
 		}
 	}
 
-	// Update experiment status based on result
+	// Update run status based on result
 	var status storage.ExperimentStatus
-	if execErr != nil {
+	var abortErr *experiments.AbortError
+	reason := "Finished"
+	if stopCtx.Err() != nil {
+		status = storage.StatusCancelled
+		reason = e.stopReason(tracked)
+	} else if errors.As(execErr, &abortErr) {
+		status = storage.StatusAborted
+		e.metrics.ExperimentsAborted.Inc()
+	} else if execErr != nil {
 		status = storage.StatusFailed
 		e.metrics.ExperimentsFailed.Inc()
 	} else {
@@ -198,99 +199,270 @@ func (e *Executor) ExecuteExperiment(experimentID string) (*experiments.Experime
 		e.metrics.ExperimentsSucceeded.Inc()
 	}
 
-	if err := e.db.UpdateExperimentStatus(experimentID, status); err != nil {
-		log.Printf("Failed to update experiment status: %v", err)
+	if execErr != nil && status != storage.StatusCancelled {
+		reason = execErr.Error()
 	}
+	e.transitionRun(run, status, reason)
 
 	// Save the result
 	if result != nil {
 		result.ExperimentID = experimentID
+		result.RunID = run.ID
 		result.ID = uuid.New().String()
 		result.CalculateDuration()
 		
 		// Record experiment duration in metrics
 		e.metrics.ExperimentDuration.Observe(result.Duration)
+
+		if err := SaveResult(e.db, result); err != nil {
+			log.Printf("Failed to save experiment result: %v", err)
+		}
 	}
 
 	return result, execErr
 }
 
-// executePodFailure executes a pod failure experiment
-func (e *Executor) executePodFailure(ctx context.Context, experiment *storage.Experiment) (*experiments.ExperimentResult, error) {
-	// Parse parameters
-	params, err := parseParams(experiment)
-	if err != nil {
-		return nil, err
+// track registers a run in progress. It returns the execution, whose context
+// is cancelled when the run is stopped, and the function that unregisters the
+// run once it has finished.
+func (e *Executor) track(run *storage.Run) (*execution, func()) {
+	ctx, cancel := context.WithCancel(context.Background())
+	tracked := &execution{runID: run.ID, experimentID: run.ExperimentID, ctx: ctx, cancel: cancel, done: make(chan struct{})}
+
+	e.executionsMu.Lock()
+	e.executions[run.ID] = tracked
+	e.executionsMu.Unlock()
+
+	return tracked, func() {
+		e.executionsMu.Lock()
+		delete(e.executions, run.ID)
+		e.executionsMu.Unlock()
+
+		cancel()
+		close(tracked.done)
 	}
-	
-	// Get common parameters
-	experimentParams, err := getExperimentParams(params, "percentage", 100, "%")
-	if err != nil {
-		return nil, err
+}
+
+// stopRuns stops the runs in progress that match for the reason and waits
+// until their faults have been recovered. It returns the experiments of the
+// runs it stopped.
+func (e *Executor) stopRuns(match func(*execution) bool, reason string) []string {
+	e.executionsMu.Lock()
+	stopping := []*execution{}
+	for _, tracked := range e.executions {
+		if match(tracked) {
+			if tracked.ctx.Err() == nil {
+				tracked.reason = reason
+			}
+			tracked.cancel()
+			stopping = append(stopping, tracked)
+		}
 	}
+	e.executionsMu.Unlock()
 
-	// Create and run the experiment
-	podFailure := experiments.NewPodFailureExperiment(
-		e.client.GetClientset(),
-		experimentParams.Namespace,
-		experimentParams.Selector,
-		experiment.Duration,
-		experimentParams.Value,
-	)
+	seen := map[string]bool{}
+	stopped := []string{}
+	for _, tracked := range stopping {
+		<-tracked.done
+		if !seen[tracked.experimentID] {
+			seen[tracked.experimentID] = true
+			stopped = append(stopped, tracked.experimentID)
+		}
+	}
+	return stopped
+}
 
-	return podFailure.Run(ctx)
+// stopReason returns why a run was stopped
+func (e *Executor) stopReason(tracked *execution) string {
+	e.executionsMu.Lock()
+	defer e.executionsMu.Unlock()
+	return tracked.reason
 }
 
-// executeNetworkDelay executes a network delay experiment
-func (e *Executor) executeNetworkDelay(ctx context.Context, experiment *storage.Experiment) (*experiments.ExperimentResult, error) {
-	// Parse parameters
-	params, err := parseParams(experiment)
-	if err != nil {
-		return nil, err
+// StopExperiment stops the runs of an experiment in progress and waits until
+// their faults have been recovered. It returns ErrNotRunning if there are none.
+func (e *Executor) StopExperiment(experimentID string) error {
+	stopped := e.stopRuns(func(tracked *execution) bool {
+		return tracked.experimentID == experimentID
+	}, "Stopped")
+
+	if len(stopped) == 0 {
+		return fmt.Errorf("%w: %s", ErrNotRunning, experimentID)
 	}
-	
-	// Get common parameters
-	experimentParams, err := getExperimentParams(params, "delay", 100, "ms")
+	return nil
+}
+
+// StopAll stops every run in progress for the reason and waits until their
+// faults have been recovered. It returns the experiments it stopped.
+func (e *Executor) StopAll(reason string) []string {
+	return e.stopRuns(func(*execution) bool { return true }, reason)
+}
+
+// watchSignals stops a run as soon as the kill switch is engaged or another
+// process requests its stop
+func (e *Executor) watchSignals(tracked *execution) {
+	ticker := time.NewTicker(signalInterval)
+	defer ticker.Stop()
+
+	stop := func(reason string) {
+		e.stopRuns(func(other *execution) bool { return other == tracked }, reason)
+	}
+	for {
+		select {
+		case <-tracked.ctx.Done():
+			return
+		case <-ticker.C:
+			var halted *storage.KillSwitchError
+			if err := e.db.CheckKillSwitch(); errors.As(err, &halted) {
+				stop(halted.Error())
+				return
+			}
+
+			request, err := e.db.GetStopRequest(tracked.runID)
+			if err != nil {
+				log.Printf("Failed to check stop request of run %s: %v", tracked.runID, err)
+			} else if request != nil {
+				stop(request.Reason)
+				return
+			}
+		}
+	}
+}
+
+// transitionRun moves a run to a new status, logging a change that cannot be recorded
+func (e *Executor) transitionRun(run *storage.Run, status storage.ExperimentStatus, reason string) {
+	if err := e.db.TransitionRun(run.ID, status, storage.JournalOwnerExecutor, reason); err != nil {
+		log.Printf("Failed to update status of run %s: %v", run.ID, err)
+	}
+}
+
+// lockTarget locks the target of the experiment for the run. It returns the
+// function that releases the lock, or a LockConflictError if another run
+// holds it; runs are not queued behind the holder.
+func (e *Executor) lockTarget(experiment *storage.Experiment) (func(), error) {
+	// A type or spec that cannot be resolved fails the run, which needs no lock
+	definition, exists := experiments.Lookup(string(experiment.Type))
+	if !exists {
+		return func() {}, nil
+	}
+	spec, err := e.spec(definition, experiment)
 	if err != nil {
+		return func() {}, nil
+	}
+
+	lock := &storage.TargetLock{
+		Target:       experiments.TargetKey(definition, spec),
+		ExperimentID: experiment.ID,
+		Owner:        storage.JournalOwnerExecutor,
+		AcquiredAt:   time.Now(),
+	}
+	lock.ExpiresAt = lock.AcquiredAt.Add(time.Duration(experiment.Duration)*time.Second + experimentGracePeriod)
+	if err := e.db.AcquireTargetLock(lock); err != nil {
 		return nil, err
 	}
-	
-	// Use generic experiment execution
-	return e.executeGenericExperiment(ctx, experiment, "network-delay", experimentParams)
+
+	return func() {
+		if err := e.db.ReleaseTargetLock(lock.Target, experiment.ID); err != nil {
+			log.Printf("Failed to release target lock of experiment %s: %v", experiment.ID, err)
+		}
+	}, nil
 }
 
-// executeCPUStress executes a CPU stress experiment
-func (e *Executor) executeCPUStress(ctx context.Context, experiment *storage.Experiment) (*experiments.ExperimentResult, error) {
-	// Parse parameters
-	params, err := parseParams(experiment)
+// DryRunExperiment resolves the targets of the experiment identified by
+// experimentID and returns what executing it would do, without changing the
+// cluster or the experiment
+func (e *Executor) DryRunExperiment(experimentID string) (*experiments.Plan, error) {
+	if experimentID == "" {
+		return nil, fmt.Errorf("experiment ID cannot be empty")
+	}
+
+	experiment, err := e.db.GetExperiment(experimentID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get experiment: %w", err)
+	}
+
+	definition, exists := experiments.Lookup(string(experiment.Type))
+	if !exists {
+		return nil, fmt.Errorf("unsupported experiment type: %s", experiment.Type)
+	}
+
+	spec, err := e.spec(definition, experiment)
 	if err != nil {
 		return nil, err
 	}
-	
-	// Get common parameters
-	experimentParams, err := getExperimentParams(params, "load", 80, "%")
+
+	ctx, cancel := context.WithTimeout(context.Background(), dryRunTimeout)
+	defer cancel()
+
+	plan, err := experiments.DryRun(ctx, definition, experiments.Dependencies{
+		Clientset:   e.client.GetClientset(),
+		Executor:    e.client,
+		BlastRadius: &e.blastRadius,
+		Protection:  &e.protection,
+	}, spec)
 	if err != nil {
 		return nil, err
 	}
-	
-	// Use generic experiment execution
-	return e.executeGenericExperiment(ctx, experiment, "cpu-stress", experimentParams)
+	plan.ExperimentID = experimentID
+
+	return plan, nil
 }
 
-// executeMemoryStress executes a memory stress experiment
-func (e *Executor) executeMemoryStress(ctx context.Context, experiment *storage.Experiment) (*experiments.ExperimentResult, error) {
+// spec resolves the input the experiment is built from
+func (e *Executor) spec(definition experiments.Definition, experiment *storage.Experiment) (experiments.Spec, error) {
 	// Parse parameters
 	params, err := parseParams(experiment)
 	if err != nil {
-		return nil, err
+		return experiments.Spec{}, err
 	}
-	
-	// Get common parameters
-	experimentParams, err := getExperimentParams(params, "size", 256, "MB")
+
+	probeList, abort, err := ExperimentChecks(experiment)
+	if err != nil {
+		return experiments.Spec{}, err
+	}
+
+	spec := experiments.Spec{
+		Namespace: params["namespace"],
+		Selector:  params["selector"],
+		Duration:  experiment.Duration,
+		Params:    params,
+		Probes:    probeList,
+		Abort:     abort,
+	}
+
+	// A stored target takes precedence over the parameters and must be of the type the experiment acts on
+	if definition.TargetType != "" {
+		if target, err := e.db.GetTarget(experiment.Target); err == nil {
+			if string(target.Type) != definition.TargetType {
+				return experiments.Spec{}, fmt.Errorf("target %s has type %s, %s requires a %s target", target.ID, target.Type, definition.Type, definition.TargetType)
+			}
+			spec.Namespace = target.Namespace
+			spec.Selector = target.Selector
+			spec.Targeted = true
+		}
+	}
+
+	return spec, nil
+}
+
+// execute builds the experiment from its registered definition and runs it
+func (e *Executor) execute(ctx context.Context, definition experiments.Definition, experiment *storage.Experiment) (*experiments.ExperimentResult, error) {
+	spec, err := e.spec(definition, experiment)
 	if err != nil {
 		return nil, err
 	}
-	
-	// Use generic experiment execution
-	return e.executeGenericExperiment(ctx, experiment, "memory-stress", experimentParams)
-}
\ No newline at end of file
+
+	// Journal every change so a crash mid-run can be recovered. Once the context
+	// deadline and the grace period have passed the run is over for certain.
+	deadline, _ := ctx.Deadline()
+	journal := NewJournal(e.db, experiment.ID, string(experiment.Type), storage.JournalOwnerExecutor, deadline.Add(experimentGracePeriod))
+
+	// Run the inject phase, then recover whatever it left behind
+	return experiments.Execute(ctx, definition, experiments.Dependencies{
+		Clientset:   e.client.GetClientset(),
+		Executor:    e.client,
+		BlastRadius: &e.blastRadius,
+		Protection:  &e.protection,
+		Journal:     journal,
+	}, spec)
+}
@@ -0,0 +1,60 @@
+package executor
+
+import (
+	"time"
+
+	"github.com/flack/chaos-engineering-as-a-platform/pkg/chaos/experiments"
+	"github.com/flack/chaos-engineering-as-a-platform/pkg/storage"
+)
+
+// Journal records the changes of a single experiment run in the database
+type Journal struct {
+	db             *storage.Database
+	experimentID   string
+	experimentType string
+	owner          string
+	deadline       time.Time
+}
+
+// NewJournal creates a journal for a run of the experiment that must end by the deadline
+func NewJournal(db *storage.Database, experimentID, experimentType, owner string, deadline time.Time) *Journal {
+	return &Journal{
+		db:             db,
+		experimentID:   experimentID,
+		experimentType: experimentType,
+		owner:          owner,
+		deadline:       deadline,
+	}
+}
+
+// Record implements experiments.Journal
+func (j *Journal) Record(change experiments.Change) error {
+	return j.db.RecordJournalEntry(&storage.JournalEntry{
+		ID:             change.ID,
+		ExperimentID:   j.experimentID,
+		ExperimentType: j.experimentType,
+		Owner:          j.owner,
+		Kind:           change.Kind,
+		Namespace:      change.Namespace,
+		Name:           change.Name,
+		Data:           change.Data,
+		Deadline:       j.deadline,
+		CreatedAt:      time.Now(),
+	})
+}
+
+// Resolve implements experiments.Journal
+func (j *Journal) Resolve(change experiments.Change) error {
+	return j.db.ResolveJournalEntry(change.ID)
+}
+
+// JournaledChange returns the experiment change recorded by a journal entry
+func JournaledChange(entry *storage.JournalEntry) experiments.Change {
+	return experiments.Change{
+		ID:        entry.ID,
+		Kind:      entry.Kind,
+		Namespace: entry.Namespace,
+		Name:      entry.Name,
+		Data:      entry.Data,
+	}
+}
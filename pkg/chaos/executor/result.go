@@ -0,0 +1,147 @@
+package executor
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/flack/chaos-engineering-as-a-platform/pkg/chaos/experiments"
+	"github.com/flack/chaos-engineering-as-a-platform/pkg/chaos/probes"
+	"github.com/flack/chaos-engineering-as-a-platform/pkg/storage"
+)
+
+// resultDetails holds the parts of an experiment result that have no column of their own
+type resultDetails struct {
+	ExperimentType    string                `json:"experiment_type"`
+	Duration          float64               `json:"duration"`
+	Success           bool                  `json:"success"`
+	Error             string                `json:"error,omitempty"`
+	AffectedResources []string              `json:"affected_resources,omitempty"`
+	Outcomes          map[string]string     `json:"outcomes,omitempty"`
+	Strategy          string                `json:"strategy,omitempty"`
+	Seed              int64                 `json:"seed,omitempty"`
+	Kills             []experiments.PodKill `json:"kills,omitempty"`
+	Hypothesis        *probes.Report        `json:"hypothesis,omitempty"`
+	Aborted           *experiments.Abort    `json:"aborted,omitempty"`
+	BlastRadius       []string              `json:"blast_radius,omitempty"`
+	Excluded          []string              `json:"excluded,omitempty"`
+}
+
+// resultStatus derives the status stored for an experiment result
+func resultStatus(result *experiments.ExperimentResult) storage.ExperimentStatus {
+	switch {
+	case result.Success:
+		return storage.StatusCompleted
+	case result.Aborted != nil:
+		return storage.StatusAborted
+	case result.Error == "Experiment cancelled":
+		return storage.StatusCancelled
+	default:
+		return storage.StatusFailed
+	}
+}
+
+// SaveResult stores the result of an experiment run
+func SaveResult(db *storage.Database, result *experiments.ExperimentResult) error {
+	details, err := json.Marshal(resultDetails{
+		ExperimentType:    result.ExperimentType,
+		Duration:          result.Duration,
+		Success:           result.Success,
+		Error:             result.Error,
+		AffectedResources: result.AffectedResources,
+		Outcomes:          result.Outcomes,
+		Strategy:          result.Strategy,
+		Seed:              result.Seed,
+		Kills:             result.Kills,
+		Hypothesis:        result.Hypothesis,
+		Aborted:           result.Aborted,
+		BlastRadius:       result.BlastRadius,
+		Excluded:          result.Excluded,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to marshal result details: %w", err)
+	}
+
+	return db.SaveExperimentResult(&storage.ExperimentResult{
+		ID:           result.ID,
+		ExperimentID: result.ExperimentID,
+		RunID:        result.RunID,
+		Status:       resultStatus(result),
+		StartTime:    result.StartTime,
+		EndTime:      result.EndTime,
+		Metrics:      result.Metrics,
+		Logs:         result.Logs,
+		Details:      details,
+	})
+}
+
+// StoredResult returns the experiment result a stored result was saved from
+func StoredResult(stored *storage.ExperimentResult) (*experiments.ExperimentResult, error) {
+	result := &experiments.ExperimentResult{
+		ID:           stored.ID,
+		ExperimentID: stored.ExperimentID,
+		RunID:        stored.RunID,
+		StartTime:    stored.StartTime,
+		EndTime:      stored.EndTime,
+		Metrics:      stored.Metrics,
+		Logs:         stored.Logs,
+	}
+
+	if len(stored.Details) > 0 {
+		var details resultDetails
+		if err := json.Unmarshal(stored.Details, &details); err != nil {
+			return nil, fmt.Errorf("failed to parse result details: %w", err)
+		}
+		result.ExperimentType = details.ExperimentType
+		result.Duration = details.Duration
+		result.Success = details.Success
+		result.Error = details.Error
+		result.AffectedResources = details.AffectedResources
+		result.Outcomes = details.Outcomes
+		result.Strategy = details.Strategy
+		result.Seed = details.Seed
+		result.Kills = details.Kills
+		result.Hypothesis = details.Hypothesis
+		result.Aborted = details.Aborted
+		result.BlastRadius = details.BlastRadius
+		result.Excluded = details.Excluded
+	}
+
+	return result, nil
+}
+
+// ListResults retrieves the results of an experiment, most recent first
+func ListResults(db *storage.Database, experimentID string) ([]*experiments.ExperimentResult, error) {
+	stored, err := db.ListExperimentResults(experimentID)
+	if err != nil {
+		return nil, err
+	}
+
+	results := make([]*experiments.ExperimentResult, 0, len(stored))
+	for _, row := range stored {
+		result, err := StoredResult(row)
+		if err != nil {
+			return nil, err
+		}
+		results = append(results, result)
+	}
+	return results, nil
+}
+
+// ExperimentChecks parses the steady-state probes and abort conditions stored with an experiment
+func ExperimentChecks(experiment *storage.Experiment) ([]probes.Probe, *probes.AbortConditions, error) {
+	var probeList []probes.Probe
+	if len(experiment.Probes) > 0 {
+		if err := json.Unmarshal(experiment.Probes, &probeList); err != nil {
+			return nil, nil, fmt.Errorf("failed to parse experiment probes: %w", err)
+		}
+	}
+
+	var abort *probes.AbortConditions
+	if len(experiment.AbortConditions) > 0 {
+		if err := json.Unmarshal(experiment.AbortConditions, &abort); err != nil {
+			return nil, nil, fmt.Errorf("failed to parse experiment abort conditions: %w", err)
+		}
+	}
+
+	return probeList, abort, nil
+}
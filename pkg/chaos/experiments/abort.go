@@ -0,0 +1,82 @@
+package experiments
+
+import (
+	"context"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/flack/chaos-engineering-as-a-platform/pkg/chaos/probes"
+)
+
+// Abort records why and when an abort condition halted an experiment
+type Abort struct {
+	Reason string    `json:"reason"`
+	Time   time.Time `json:"time"`
+}
+
+// AbortError reports that an abort condition halted an experiment. The
+// faults it injected have been recovered like those of a cancelled run.
+type AbortError struct {
+	Abort Abort
+}
+
+func (e *AbortError) Error() string {
+	return "Experiment aborted: " + e.Abort.Reason
+}
+
+// runWatched runs the experiment while the runner samples the probes and the
+// guard checks the abort conditions, either of which may be nil. A tripped
+// condition cancels the run and is returned once it has ended.
+func runWatched(ctx context.Context, experiment Experiment, runner *probes.Runner, guard *probes.Guard) (*ExperimentResult, *Abort, error) {
+	if runner == nil && guard == nil {
+		result, err := experiment.Run(ctx)
+		return result, nil, err
+	}
+
+	runCtx, abortRun := context.WithCancel(ctx)
+	defer abortRun()
+	watchCtx, stopWatching := context.WithCancel(ctx)
+
+	var wg sync.WaitGroup
+	var abort *Abort
+	if runner != nil {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			runner.Watch(watchCtx)
+		}()
+	}
+	if guard != nil {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if reason := guard.Watch(watchCtx); reason != "" {
+				log.Printf("Aborting experiment: %s", reason)
+				abort = &Abort{Reason: reason, Time: time.Now()}
+				abortRun()
+			}
+		}()
+	}
+
+	result, err := experiment.Run(runCtx)
+
+	stopWatching()
+	wg.Wait()
+
+	return result, abort, err
+}
+
+// concludeAbort marks the result of a run halted by an abort condition
+func concludeAbort(abort *Abort, result *ExperimentResult) error {
+	err := &AbortError{Abort: *abort}
+	if result == nil {
+		return err
+	}
+
+	result.Aborted = abort
+	result.Success = false
+	result.Error = err.Error()
+	result.Logf("Experiment aborted at %s: %s", abort.Time.UTC().Format(time.RFC3339), abort.Reason)
+	return err
+}
@@ -0,0 +1,256 @@
+package experiments
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+)
+
+// BlastRadius is the platform policy that limits how much a single
+// experiment may affect. A limit of 0 is not enforced.
+type BlastRadius struct {
+	MaxPods          int `json:"max_pods"`           // Pods a single experiment may affect
+	MaxPercentage    int `json:"max_percentage"`     // Share of the pods of a workload an experiment may affect
+	MinReadyReplicas int `json:"min_ready_replicas"` // Ready pods every affected workload must keep
+}
+
+// DefaultBlastRadius is the policy used when the platform configures none
+var DefaultBlastRadius = BlastRadius{MaxPods: 10, MaxPercentage: 50, MinReadyReplicas: 1}
+
+// BlastRadiusError reports that the blast-radius policy left nothing for an
+// experiment to act on
+type BlastRadiusError struct {
+	Reasons []string
+}
+
+func (e *BlastRadiusError) Error() string {
+	return "Rejected by the blast-radius policy: " + strings.Join(e.Reasons, "; ")
+}
+
+// enforced reports whether the policy sets any limit
+func (b *BlastRadius) enforced() bool {
+	return b != nil && (b.MaxPods > 0 || b.MaxPercentage > 0 || b.MinReadyReplicas > 0)
+}
+
+// workload identifies the controller of a pod, such as deployment/web
+type workload struct {
+	namespace string
+	name      string
+}
+
+// workloadOf returns the workload a pod belongs to. Pods of a ReplicaSet
+// count towards its Deployment, and a pod without a controller is its own
+// workload.
+func workloadOf(pod *corev1.Pod) workload {
+	owner := metav1.GetControllerOf(pod)
+	if owner == nil {
+		return workload{namespace: pod.Namespace, name: "pod/" + pod.Name}
+	}
+
+	if hash := pod.Labels["pod-template-hash"]; owner.Kind == "ReplicaSet" && strings.HasSuffix(owner.Name, "-"+hash) {
+		return workload{namespace: pod.Namespace, name: "deployment/" + strings.TrimSuffix(owner.Name, "-"+hash)}
+	}
+	return workload{namespace: pod.Namespace, name: strings.ToLower(owner.Kind) + "/" + owner.Name}
+}
+
+// podReady reports whether the pod passes its readiness checks
+func podReady(pod *corev1.Pod) bool {
+	for _, condition := range pod.Status.Conditions {
+		if condition.Type == corev1.PodReady {
+			return condition.Status == corev1.ConditionTrue
+		}
+	}
+	return false
+}
+
+// workloadSize counts the pods of a workload
+type workloadSize struct {
+	pods  int
+	ready int
+}
+
+// workloadSizes counts the pods of every workload in the namespaces of the pods
+func workloadSizes(ctx context.Context, clientset kubernetes.Interface, pods []corev1.Pod) (map[workload]*workloadSize, error) {
+	sizes := map[workload]*workloadSize{}
+	listed := map[string]bool{}
+	for _, pod := range pods {
+		if listed[pod.Namespace] {
+			continue
+		}
+		listed[pod.Namespace] = true
+
+		all, err := clientset.CoreV1().Pods(pod.Namespace).List(ctx, metav1.ListOptions{})
+		if err != nil {
+			return nil, fmt.Errorf("failed to list pods in namespace %s: %w", pod.Namespace, err)
+		}
+		for i := range all.Items {
+			if all.Items[i].DeletionTimestamp != nil {
+				continue
+			}
+			key := workloadOf(&all.Items[i])
+			if sizes[key] == nil {
+				sizes[key] = &workloadSize{}
+			}
+			sizes[key].pods++
+			if podReady(&all.Items[i]) {
+				sizes[key].ready++
+			}
+		}
+	}
+	return sizes, nil
+}
+
+// affectedPods remembers the distinct pods a run already affected and the
+// workloads they belong to, so later rounds count against the same limits
+type affectedPods map[string]workload
+
+// add records that the run affected the pod
+func (a affectedPods) add(pod *corev1.Pod) {
+	a[pod.Namespace+"/"+pod.Name] = workloadOf(pod)
+}
+
+// contains reports whether the run already affected the pod
+func (a affectedPods) contains(pod *corev1.Pod) bool {
+	_, exists := a[pod.Namespace+"/"+pod.Name]
+	return exists
+}
+
+// limit returns the pods, in order of preference, that the experiment may
+// still affect given the pods it already affected, with a reason for every
+// pod it leaves out. Pods already affected do not count twice. Leaving out
+// every pod is an error.
+func (b *BlastRadius) limit(ctx context.Context, clientset kubernetes.Interface, pods []corev1.Pod, affected affectedPods) ([]corev1.Pod, []string, error) {
+	if !b.enforced() || len(pods) == 0 {
+		return pods, nil, nil
+	}
+
+	sizes, err := workloadSizes(ctx, clientset, pods)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to check the blast radius: %w", err)
+	}
+
+	allowed := []corev1.Pod{}
+	skipped := []string{}
+	total := len(affected)
+	taken := map[workload]*workloadSize{}
+	for _, key := range affected {
+		if taken[key] == nil {
+			taken[key] = &workloadSize{}
+		}
+		taken[key].pods++
+	}
+	for i := range pods {
+		pod := &pods[i]
+		key := workloadOf(pod)
+		size := sizes[key]
+		if size == nil {
+			size = &workloadSize{pods: 1}
+		}
+		if taken[key] == nil {
+			taken[key] = &workloadSize{}
+		}
+		ready := podReady(pod)
+		again := affected.contains(pod)
+
+		switch {
+		case !again && b.MaxPods > 0 && total >= b.MaxPods:
+			skipped = append(skipped, fmt.Sprintf("pod %s left out, at most %d pods may be affected per experiment", pod.Name, b.MaxPods))
+		case !again && b.MaxPercentage > 0 && (taken[key].pods+1)*100 > size.pods*b.MaxPercentage:
+			skipped = append(skipped, fmt.Sprintf("pod %s left out, at most %d%% of the %d pods of %s may be affected", pod.Name, b.MaxPercentage, size.pods, key.name))
+		case b.MinReadyReplicas > 0 && ready && size.ready-taken[key].ready-1 < b.MinReadyReplicas:
+			skipped = append(skipped, fmt.Sprintf("pod %s left out, %s must keep %d ready replicas", pod.Name, key.name, b.MinReadyReplicas))
+		default:
+			allowed = append(allowed, *pod)
+			if !again {
+				total++
+				taken[key].pods++
+			}
+			if ready {
+				taken[key].ready++
+			}
+		}
+	}
+
+	if len(allowed) == 0 {
+		return nil, skipped, &BlastRadiusError{Reasons: skipped}
+	}
+	return allowed, skipped, nil
+}
+
+// check rejects a fixed set of pods that the policy would not allow in full,
+// for experiments that cannot leave some of their pods out
+func (b *BlastRadius) check(ctx context.Context, clientset kubernetes.Interface, pods []corev1.Pod) error {
+	allowed, skipped, err := b.limit(ctx, clientset, pods, nil)
+	if err != nil {
+		return err
+	}
+	if len(allowed) < len(pods) {
+		return &BlastRadiusError{Reasons: skipped}
+	}
+	return nil
+}
+
+// limitReplicas raises the replica count a workload is scaled down to until
+// the policy allows it, given the replicas already removed from other
+// workloads. The replica count stands in for the ready pods the workload keeps.
+func (b *BlastRadius) limitReplicas(resource string, original, reduced int32, removed int) (int32, string) {
+	if !b.enforced() {
+		return reduced, ""
+	}
+
+	limited, rule := reduced, ""
+	if b.MaxPercentage > 0 {
+		if floor := original - original*int32(b.MaxPercentage)/100; limited < floor {
+			limited, rule = floor, fmt.Sprintf("at most %d%% of its replicas may be removed", b.MaxPercentage)
+		}
+	}
+	if b.MinReadyReplicas > 0 && limited < int32(b.MinReadyReplicas) {
+		limited, rule = int32(b.MinReadyReplicas), fmt.Sprintf("it must keep %d ready replicas", b.MinReadyReplicas)
+	}
+	if b.MaxPods > 0 {
+		budget := int32(b.MaxPods - removed)
+		if budget < 0 {
+			budget = 0
+		}
+		if original-limited > budget {
+			limited, rule = original-budget, fmt.Sprintf("at most %d pods may be affected per experiment", b.MaxPods)
+		}
+	}
+	if limited > original {
+		limited = original
+	}
+
+	if limited == reduced {
+		return reduced, ""
+	}
+	return limited, fmt.Sprintf("%s kept at %d instead of %d replicas, %s", resource, limited, reduced, rule)
+}
+
+// limitedBy logs what the blast-radius policy left out and keeps it in the result
+func (r *ExperimentResult) limitedBy(skipped []string) {
+	for _, reason := range skipped {
+		r.Logf("Blast radius: %s", reason)
+	}
+	r.BlastRadius = append(r.BlastRadius, skipped...)
+}
+
+// leaveOut logs the targets the run left out and keeps them in the result.
+// A target excluded again in a later round is only kept once.
+func (r *ExperimentResult) leaveOut(left leftOut) {
+	r.limitedBy(left.blastRadius)
+	known := make(map[string]bool, len(r.Excluded))
+	for _, resource := range r.Excluded {
+		known[resource] = true
+	}
+	for _, resource := range left.excluded {
+		if !known[resource] {
+			known[resource] = true
+			r.Logf("Excluded %s, it did not opt in to chaos", resource)
+			r.Excluded = append(r.Excluded, resource)
+		}
+	}
+}
@@ -0,0 +1,179 @@
+package experiments
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"k8s.io/client-go/kubernetes"
+)
+
+// DefaultStressImage is the image used to run stress-ng when none is configured
+const DefaultStressImage = "ghcr.io/colinianking/stress-ng:latest"
+
+// CPUStressConfig holds the settings for a CPU stress experiment
+type CPUStressConfig struct {
+	Namespace string
+	Selector  string
+	Duration  int
+	Load      int    // Load per worker in percent
+	Workers   int    // Number of stress workers, 0 uses one per available CPU
+	Cores     string // Optional CPU list to pin the workers to, e.g. "0,2-3"
+	Image     string // Image providing stress-ng, defaults to DefaultStressImage
+}
+
+// CPUStressExperiment puts CPU pressure on matching pods
+type CPUStressExperiment struct {
+	injector *ephemeralInjector
+	config   CPUStressConfig
+}
+
+func init() {
+	Register(Definition{
+		Type:        "cpu-stress",
+		Description: "Load pod CPUs with stress-ng",
+		Params: withPodParams(
+			Param{Name: "load", Description: "CPU load per worker", Type: ParamInt, Unit: "%", Default: "80", Min: limit(1), Max: limit(100)},
+			Param{Name: "workers", Description: "Number of stress workers, 0 uses one per CPU", Type: ParamInt, Default: "0", Min: limit(0)},
+			Param{Name: "cores", Description: "CPU list to pin the workers to, such as 0,2-3", Type: ParamString},
+			Param{Name: "image", Description: "Image providing stress-ng", Type: ParamString, Default: DefaultStressImage},
+		),
+		New: func(deps Dependencies, spec Spec) (Experiment, error) {
+			if err := spec.requirePods(); err != nil {
+				return nil, err
+			}
+
+			cpuStress := NewCPUStressExperiment(deps.Clientset, deps.Executor, CPUStressConfig{
+				Namespace: spec.Namespace,
+				Selector:  spec.Selector,
+				Duration:  spec.Duration,
+				Load:      spec.Int("load", 80),
+				Workers:   spec.Int("workers", 0),
+				Cores:     spec.Params["cores"],
+				Image:     spec.Params["image"],
+			})
+			cpuStress.injector.journal = deps.Journal
+			cpuStress.injector.blastRadius = deps.BlastRadius
+			cpuStress.injector.protection = deps.Protection
+
+			return cpuStress, nil
+		},
+		Recover: recoverEphemeralContainer,
+	})
+}
+
+// NewCPUStressExperiment creates a new CPU stress experiment
+func NewCPUStressExperiment(clientset kubernetes.Interface, executor PodExecutor, config CPUStressConfig) *CPUStressExperiment {
+	if config.Image == "" {
+		config.Image = DefaultStressImage
+	}
+
+	return &CPUStressExperiment{
+		injector: &ephemeralInjector{
+			clientset: clientset,
+			executor:  executor,
+			namespace: config.Namespace,
+		},
+		config: config,
+	}
+}
+
+// script builds the shell script that runs stress-ng and stops it on exit
+func (e *CPUStressExperiment) script() string {
+	command := fmt.Sprintf("stress-ng --cpu %d --cpu-load %d --timeout %ds", e.config.Workers, e.config.Load, e.config.Duration)
+	if e.config.Cores != "" {
+		command += " --taskset " + e.config.Cores
+	}
+
+	return strings.Join([]string{
+		"trap 'kill $PID 2>/dev/null; exit 0' TERM INT",
+		command + " &",
+		"PID=$!",
+		"wait $PID",
+	}, "\n")
+}
+
+// validate checks the configuration before anything is injected
+func (e *CPUStressExperiment) validate() error {
+	if e.config.Load < 1 || e.config.Load > 100 {
+		return fmt.Errorf("Invalid load: %d, must be between 1 and 100", e.config.Load)
+	}
+	if e.config.Cores != "" {
+		return validateShellValue("cores", e.config.Cores)
+	}
+	return nil
+}
+
+// Plan reports the pods the CPU stress would be injected into
+func (e *CPUStressExperiment) Plan(ctx context.Context) (*Plan, error) {
+	if err := e.validate(); err != nil {
+		return nil, err
+	}
+
+	pods, left, err := e.injector.listPods(ctx, e.config.Selector)
+	if err != nil {
+		return nil, err
+	}
+
+	plan := newPlan("cpu-stress")
+	plan.leaveOut(left)
+	planAll(plan, pods, "cpu", e.config.Image, fmt.Sprintf("load the CPU to %d%%", e.config.Load), e.config.Duration)
+	return plan, nil
+}
+
+// Run executes the CPU stress experiment
+func (e *CPUStressExperiment) Run(ctx context.Context) (*ExperimentResult, error) {
+	// Create a result object
+	result := &ExperimentResult{
+		ExperimentType: "cpu-stress",
+		StartTime:      time.Now(),
+		Success:        false,
+		Metrics: map[string]float64{
+			"load_percent": float64(e.config.Load),
+			"workers":      float64(e.config.Workers),
+		},
+	}
+
+	result.Logf("Starting CPU stress experiment in namespace %s with selector %s", e.config.Namespace, e.config.Selector)
+
+	if err := e.validate(); err != nil {
+		result.Error = err.Error()
+		return result, err
+	}
+
+	pods, left, err := e.injector.listPods(ctx, e.config.Selector)
+	if err != nil {
+		result.Error = err.Error()
+		return result, err
+	}
+	result.leaveOut(left)
+
+	injections := e.injector.injectAll(ctx, result, pods, "cpu", e.config.Image, e.script(), nil)
+	result.AffectedResources = injectedPods(injections)
+
+	if len(injections) == 0 {
+		result.Error = "Failed to inject CPU stress into any pod"
+		return result, errors.New(result.Error)
+	}
+
+	// Hold the stress for the experiment duration, then stop it even if cancelled
+	waitErr := waitForDuration(ctx, e.config.Duration)
+	failures := e.injector.stopAll(result, injections)
+
+	result.EndTime = time.Now()
+
+	if waitErr != nil {
+		result.Error = "Experiment cancelled"
+		return result, waitErr
+	}
+
+	if len(failures) > 0 {
+		result.Error = fmt.Sprintf("Failed to stop CPU stress: %s", strings.Join(failures, "; "))
+		return result, errors.New(result.Error)
+	}
+
+	result.Success = true
+	return result, nil
+}
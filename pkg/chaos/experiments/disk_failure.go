@@ -0,0 +1,334 @@
+package experiments
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"path"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/client-go/kubernetes"
+)
+
+// DiskFailureMode selects how a disk failure experiment degrades the disk
+type DiskFailureMode string
+
+const (
+	// DiskFill fills the filesystem holding the path up to a percentage
+	DiskFill DiskFailureMode = "fill"
+
+	// DiskIOStress saturates the filesystem with I/O to add latency for the target
+	DiskIOStress DiskFailureMode = "io-stress"
+)
+
+// DiskFailureConfig holds the settings for a disk failure experiment
+type DiskFailureConfig struct {
+	Namespace  string
+	Selector   string
+	Duration   int
+	Mode       DiskFailureMode
+	Path       string // Path inside the target container, must be on a pod volume
+	Container  string // Target container, defaults to the first container of the pod
+	Percentage int    // Filesystem usage to reach in fill mode
+	Workers    int    // Number of I/O workers in io-stress mode
+	Image      string // Image providing df, fallocate and stress-ng, defaults to DefaultStressImage
+}
+
+// DiskFailureExperiment fills or stresses a volume of matching pods. It does
+// not inject I/O errors: that needs a fault-injecting filesystem or a
+// device-mapper target such as dm-flakey beneath the volume, which cannot be
+// set up from a container attached to the pod. Filling a volume to 100% makes
+// writes to it fail with ENOSPC, which is the closest it gets.
+type DiskFailureExperiment struct {
+	injector *ephemeralInjector
+	config   DiskFailureConfig
+	fillFile string
+}
+
+func init() {
+	Register(Definition{
+		Type:        "disk-failure",
+		Description: "Fill a pod volume or saturate it with I/O",
+		Params: withPodParams(
+			Param{Name: "mode", Description: "fill or io-stress", Type: ParamString, Default: string(DiskFill), Enum: []string{string(DiskFill), string(DiskIOStress)}},
+			Param{Name: "path", Description: "Path inside the target container on the volume to degrade", Type: ParamString, Required: true},
+			Param{Name: "container", Description: "Container that mounts the path, defaults to the first one", Type: ParamString},
+			Param{Name: "percentage", Description: "Volume usage to fill up to in fill mode", Type: ParamInt, Unit: "%", Default: "90", Min: limit(1), Max: limit(100)},
+			Param{Name: "workers", Description: "Number of I/O workers in io-stress mode", Type: ParamInt, Default: "1", Min: limit(1)},
+			Param{Name: "image", Description: "Image providing stress-ng", Type: ParamString, Default: DefaultStressImage},
+		),
+		New: func(deps Dependencies, spec Spec) (Experiment, error) {
+			if err := spec.requirePods(); err != nil {
+				return nil, err
+			}
+
+			diskFailure := NewDiskFailureExperiment(deps.Clientset, deps.Executor, DiskFailureConfig{
+				Namespace:  spec.Namespace,
+				Selector:   spec.Selector,
+				Duration:   spec.Duration,
+				Mode:       DiskFailureMode(spec.Params["mode"]),
+				Path:       spec.Params["path"],
+				Container:  spec.Params["container"],
+				Percentage: spec.Int("percentage", 90),
+				Workers:    spec.Int("workers", 1),
+				Image:      spec.Params["image"],
+			})
+			diskFailure.injector.journal = deps.Journal
+			diskFailure.injector.blastRadius = deps.BlastRadius
+			diskFailure.injector.protection = deps.Protection
+
+			return diskFailure, nil
+		},
+		Recover: recoverEphemeralContainer,
+	})
+}
+
+// NewDiskFailureExperiment creates a new disk failure experiment
+func NewDiskFailureExperiment(clientset kubernetes.Interface, executor PodExecutor, config DiskFailureConfig) *DiskFailureExperiment {
+	if config.Mode == "" {
+		config.Mode = DiskFill
+	}
+	if config.Workers <= 0 {
+		config.Workers = 1
+	}
+	if config.Image == "" {
+		config.Image = DefaultStressImage
+	}
+
+	return &DiskFailureExperiment{
+		injector: &ephemeralInjector{
+			clientset: clientset,
+			executor:  executor,
+			namespace: config.Namespace,
+		},
+		config:   config,
+		fillFile: path.Join(config.Path, ".chaos-disk-fill-"+uuid.New().String()[:8]),
+	}
+}
+
+// script builds the shell script that degrades the disk and undoes it on exit
+func (e *DiskFailureExperiment) script() string {
+	if e.config.Mode == DiskIOStress {
+		return strings.Join([]string{
+			fmt.Sprintf("trap 'kill $PID 2>/dev/null; wait $PID; rm -rf %s/tmp-stress-ng-*; exit 0' TERM INT", e.config.Path),
+			fmt.Sprintf("stress-ng --hdd %d --temp-path %s --timeout %ds &", e.config.Workers, e.config.Path, e.config.Duration),
+			"PID=$!",
+			"wait $PID",
+		}, "\n")
+	}
+
+	return strings.Join([]string{
+		fmt.Sprintf("trap 'kill $PID 2>/dev/null; rm -f %s; exit 0' TERM INT", e.fillFile),
+		fmt.Sprintf("set -- $(df -Pk %s | tail -1)", e.config.Path),
+		fmt.Sprintf("NEED=$(( $2 * %d / 100 - $3 ))", e.config.Percentage),
+		"if [ $NEED -gt 0 ]; then",
+		fmt.Sprintf("  (fallocate -l ${NEED}k %s 2>/dev/null || dd if=/dev/zero of=%s bs=1024 count=$NEED 2>/dev/null) &", e.fillFile, e.fillFile),
+		"  PID=$!",
+		"  wait $PID",
+		"fi",
+		fmt.Sprintf("sleep %d &", e.config.Duration),
+		"PID=$!",
+		"wait $PID",
+		fmt.Sprintf("rm -f %s", e.fillFile),
+	}, "\n")
+}
+
+// targetContainer returns the container whose volume holds the path
+func (e *DiskFailureExperiment) targetContainer(pod *corev1.Pod) (*corev1.Container, error) {
+	for idx := range pod.Spec.Containers {
+		container := &pod.Spec.Containers[idx]
+		if e.config.Container == "" || container.Name == e.config.Container {
+			return container, nil
+		}
+	}
+	return nil, fmt.Errorf("container %s not found in pod %s", e.config.Container, pod.Name)
+}
+
+// volumeMount finds the deepest volume mount of the container that contains the path
+func (e *DiskFailureExperiment) volumeMount(container *corev1.Container) (*corev1.VolumeMount, error) {
+	var match *corev1.VolumeMount
+	for idx := range container.VolumeMounts {
+		mount := &container.VolumeMounts[idx]
+		mountPath := strings.TrimSuffix(mount.MountPath, "/")
+		if e.config.Path != mountPath && !strings.HasPrefix(e.config.Path, mountPath+"/") {
+			continue
+		}
+		if match == nil || len(mount.MountPath) > len(match.MountPath) {
+			match = mount
+		}
+	}
+
+	if match == nil {
+		return nil, fmt.Errorf("path %s is not on a volume of container %s", e.config.Path, container.Name)
+	}
+	if match.ReadOnly {
+		return nil, fmt.Errorf("volume %s is mounted read-only in container %s", match.Name, container.Name)
+	}
+
+	return match, nil
+}
+
+// podVolume returns the container of the pod and its volume mount that hold the path
+func (e *DiskFailureExperiment) podVolume(pod *corev1.Pod) (*corev1.Container, *corev1.VolumeMount, error) {
+	container, err := e.targetContainer(pod)
+	if err != nil {
+		return nil, nil, err
+	}
+	mount, err := e.volumeMount(container)
+	if err != nil {
+		return nil, nil, err
+	}
+	return container, mount, nil
+}
+
+// validate checks the configuration before anything is injected
+func (e *DiskFailureExperiment) validate() error {
+	if e.config.Mode != DiskFill && e.config.Mode != DiskIOStress {
+		return fmt.Errorf("invalid mode: %s, must be %s or %s", e.config.Mode, DiskFill, DiskIOStress)
+	}
+	if !path.IsAbs(e.config.Path) {
+		return fmt.Errorf("invalid path: %q, must be absolute", e.config.Path)
+	}
+	if err := validateShellValue("path", e.config.Path); err != nil {
+		return err
+	}
+	if e.config.Mode == DiskFill && (e.config.Percentage < 1 || e.config.Percentage > 100) {
+		return fmt.Errorf("invalid percentage: %d, must be between 1 and 100", e.config.Percentage)
+	}
+	return nil
+}
+
+// removeFillFile deletes the filler file through the target container as a last resort
+func (e *DiskFailureExperiment) removeFillFile(inj *injection, container string) error {
+	ctx, cancel := context.WithTimeout(context.Background(), cleanupTimeout)
+	defer cancel()
+
+	_, err := e.injector.executor.ExecInContainer(ctx, e.config.Namespace, inj.pod, container, []string{"rm", "-f", e.fillFile})
+	return err
+}
+
+// Plan reports the pods whose volume the disk failure would degrade
+func (e *DiskFailureExperiment) Plan(ctx context.Context) (*Plan, error) {
+	if err := e.validate(); err != nil {
+		return nil, err
+	}
+
+	pods, left, err := e.injector.listPods(ctx, e.config.Selector)
+	if err != nil {
+		return nil, err
+	}
+
+	fault := fmt.Sprintf("run %d I/O workers on %s", e.config.Workers, e.config.Path)
+	if e.config.Mode == DiskFill {
+		fault = fmt.Sprintf("fill the volume holding %s to %d%%", e.config.Path, e.config.Percentage)
+	}
+
+	plan := newPlan("disk-failure")
+	plan.leaveOut(left)
+	for idx := range pods {
+		pod := &pods[idx]
+		if _, mount, err := e.podVolume(pod); err != nil {
+			plan.addAction("Skip pod %s: %v", pod.Name, err)
+		} else {
+			plan.AffectedResources = append(plan.AffectedResources, pod.Name)
+			plan.addAction("Attach a chaos-disk container running %s with volume %s to pod %s to %s for %ds", e.config.Image, mount.Name, pod.Name, fault, e.config.Duration)
+		}
+	}
+	return plan, nil
+}
+
+// Run executes the disk failure experiment
+func (e *DiskFailureExperiment) Run(ctx context.Context) (*ExperimentResult, error) {
+	// Create a result object
+	result := &ExperimentResult{
+		ExperimentType: "disk-failure",
+		StartTime:      time.Now(),
+		Success:        false,
+		Metrics:        map[string]float64{},
+	}
+
+	result.Logf("Starting disk failure experiment (%s) in namespace %s with selector %s", e.config.Mode, e.config.Namespace, e.config.Selector)
+	if e.config.Mode == DiskFill {
+		result.Metrics["fill_percent"] = float64(e.config.Percentage)
+	} else {
+		result.Metrics["io_workers"] = float64(e.config.Workers)
+	}
+
+	if err := e.validate(); err != nil {
+		result.Error = err.Error()
+		return result, err
+	}
+
+	pods, left, err := e.injector.listPods(ctx, e.config.Selector)
+	if err != nil {
+		result.Error = err.Error()
+		return result, err
+	}
+	result.leaveOut(left)
+
+	// Mount the volume holding the path into the chaos container of each pod
+	injections := []*injection{}
+	targetContainers := map[string]string{}
+	for idx := range pods {
+		pod := &pods[idx]
+		container, mount, err := e.podVolume(pod)
+		if err != nil {
+			result.Logf("Skipping pod %s: %v", pod.Name, err)
+			continue
+		}
+
+		inj, err := e.injector.injectWithMounts(ctx, pod, "disk", e.config.Image, e.script(), nil, []corev1.VolumeMount{
+			{Name: mount.Name, MountPath: mount.MountPath, SubPath: mount.SubPath},
+		})
+		if err != nil {
+			result.Logf("Failed to inject disk failure into pod %s: %v", pod.Name, err)
+			continue
+		}
+		injections = append(injections, inj)
+		targetContainers[inj.pod] = container.Name
+	}
+	result.AffectedResources = injectedPods(injections)
+
+	if len(injections) == 0 {
+		result.Error = "Failed to inject disk failure into any pod"
+		return result, errors.New(result.Error)
+	}
+
+	// Hold the fault for the experiment duration, then undo it even if cancelled
+	waitErr := waitForDuration(ctx, e.config.Duration)
+
+	var failures []string
+	for _, inj := range injections {
+		stopErr := e.injector.stop(inj)
+		if e.config.Mode == DiskFill {
+			// Always make sure the filler file is gone, even if the script was stopped
+			if err := e.removeFillFile(inj, targetContainers[inj.pod]); err != nil && stopErr != nil {
+				stopErr = fmt.Errorf("%v; failed to remove %s: %v", stopErr, e.fillFile, err)
+			} else if err == nil {
+				stopErr = nil
+			}
+		}
+		if stopErr != nil {
+			result.Logf("Cleanup failed: %v", stopErr)
+			failures = append(failures, stopErr.Error())
+		}
+	}
+
+	result.EndTime = time.Now()
+
+	if waitErr != nil {
+		result.Error = "Experiment cancelled"
+		return result, waitErr
+	}
+
+	if len(failures) > 0 {
+		result.Error = fmt.Sprintf("Failed to clean up disk failure: %s", strings.Join(failures, "; "))
+		return result, errors.New(result.Error)
+	}
+
+	result.Success = true
+	return result, nil
+}
@@ -0,0 +1,377 @@
+package experiments
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+)
+
+const (
+	// containerStartTimeout is how long to wait for an injected container to start
+	containerStartTimeout = 60 * time.Second
+
+	// cleanupTimeout bounds teardown work that runs after the experiment context is done
+	cleanupTimeout = 30 * time.Second
+)
+
+// shellSafePattern matches values that can be placed in an injected script unquoted
+var shellSafePattern = regexp.MustCompile(`^[A-Za-z0-9._,/@:-]+$`)
+
+// validateShellValue rejects parameter values that could alter an injected script
+func validateShellValue(name, value string) error {
+	if !shellSafePattern.MatchString(value) {
+		return fmt.Errorf("invalid %s: %q", name, value)
+	}
+	return nil
+}
+
+// PodExecutor runs commands inside pod containers. It is implemented by k8s.Client.
+type PodExecutor interface {
+	ExecInContainer(ctx context.Context, namespace, pod, container string, command []string) (string, error)
+}
+
+// injection records a chaos container attached to a target pod
+type injection struct {
+	pod       string
+	container string
+	change    Change
+}
+
+// ephemeralInjector attaches short-lived chaos containers to target pods.
+//
+// Ephemeral containers cannot be removed from a pod once added, so every
+// injected script must undo its own fault when it finishes or when it
+// receives SIGTERM. Stopping an injection early sends SIGTERM to the
+// script through exec; if that fails the script still cleans up once its
+// own timer runs out.
+type ephemeralInjector struct {
+	clientset kubernetes.Interface
+	executor  PodExecutor
+	namespace string
+	journal   Journal
+
+	blastRadius *BlastRadius
+	protection  *Protection
+}
+
+// listPods returns the running pods matching the selector that may be
+// targeted and the blast-radius policy allows, and the pods it left out. A
+// selector matching a protected pod is rejected.
+func (i *ephemeralInjector) listPods(ctx context.Context, selector string) ([]corev1.Pod, leftOut, error) {
+	pods, err := i.clientset.CoreV1().Pods(i.namespace).List(ctx, metav1.ListOptions{
+		LabelSelector: selector,
+	})
+	if err != nil {
+		return nil, leftOut{}, fmt.Errorf("failed to list pods: %w", err)
+	}
+
+	running := []corev1.Pod{}
+	for _, pod := range pods.Items {
+		if pod.Status.Phase == corev1.PodRunning && pod.DeletionTimestamp == nil {
+			running = append(running, pod)
+		}
+	}
+
+	if len(running) == 0 {
+		return nil, leftOut{}, fmt.Errorf("no running pods found matching the selector")
+	}
+
+	var left leftOut
+	running, left.excluded, err = i.protection.filterPods(ctx, i.clientset, running)
+	if err != nil {
+		return nil, leftOut{}, err
+	}
+	if len(running) == 0 {
+		return nil, left, fmt.Errorf("no running pods matching the selector opted in to chaos")
+	}
+
+	running, left.blastRadius, err = i.blastRadius.limit(ctx, i.clientset, running, nil)
+	return running, left, err
+}
+
+// inject attaches a container running script to the pod and waits for it to start
+func (i *ephemeralInjector) inject(ctx context.Context, pod *corev1.Pod, prefix, image, script string, securityContext *corev1.SecurityContext) (*injection, error) {
+	return i.injectWithMounts(ctx, pod, prefix, image, script, securityContext, nil)
+}
+
+// injectWithMounts is like inject but also mounts existing pod volumes into the container
+func (i *ephemeralInjector) injectWithMounts(ctx context.Context, pod *corev1.Pod, prefix, image, script string, securityContext *corev1.SecurityContext, volumeMounts []corev1.VolumeMount) (*injection, error) {
+	name := fmt.Sprintf("chaos-%s-%s", prefix, uuid.New().String()[:8])
+
+	change := newChange(ChangeEphemeralContainer, i.namespace, pod.Name, map[string]string{"container": name})
+	if err := recordChange(i.journal, change); err != nil {
+		return nil, err
+	}
+
+	err := i.attach(ctx, pod.Name, corev1.EphemeralContainerCommon{
+		Name:            name,
+		Image:           image,
+		Command:         []string{"/bin/sh", "-c", script},
+		SecurityContext: securityContext,
+		VolumeMounts:    volumeMounts,
+	})
+	if err != nil {
+		resolveChange(i.journal, change)
+		return nil, err
+	}
+
+	// A container that did not start may still be starting, so its change stays
+	// in the journal for the recover phase
+	if err := i.waitForStart(ctx, pod.Name, name); err != nil {
+		return nil, err
+	}
+
+	return &injection{pod: pod.Name, container: name, change: change}, nil
+}
+
+// attach adds an ephemeral container to the pod
+func (i *ephemeralInjector) attach(ctx context.Context, podName string, container corev1.EphemeralContainerCommon) error {
+	// Fetch the latest version so the update does not conflict
+	current, err := i.clientset.CoreV1().Pods(i.namespace).Get(ctx, podName, metav1.GetOptions{})
+	if err != nil {
+		return fmt.Errorf("failed to get pod %s: %w", podName, err)
+	}
+
+	current.Spec.EphemeralContainers = append(current.Spec.EphemeralContainers, corev1.EphemeralContainer{
+		EphemeralContainerCommon: container,
+	})
+
+	if _, err := i.clientset.CoreV1().Pods(i.namespace).UpdateEphemeralContainers(ctx, podName, current, metav1.UpdateOptions{}); err != nil {
+		return fmt.Errorf("failed to attach chaos container to pod %s: %w", podName, err)
+	}
+	return nil
+}
+
+// runToCompletion attaches a container running script to the pod and waits
+// until it exits, returning an error unless it exited cleanly. Nothing is
+// journaled, so the script must not leave a fault behind that needs undoing.
+func (i *ephemeralInjector) runToCompletion(ctx context.Context, pod *corev1.Pod, prefix, image, script string, securityContext *corev1.SecurityContext) error {
+	name := fmt.Sprintf("chaos-%s-%s", prefix, uuid.New().String()[:8])
+
+	err := i.attach(ctx, pod.Name, corev1.EphemeralContainerCommon{
+		Name:            name,
+		Image:           image,
+		Command:         []string{"/bin/sh", "-c", script},
+		SecurityContext: securityContext,
+
+		// The last lines the script wrote to stderr explain a failure
+		TerminationMessagePolicy: corev1.TerminationMessageFallbackToLogsOnError,
+	})
+	if err != nil {
+		return err
+	}
+
+	return i.waitForExit(ctx, pod.Name, name)
+}
+
+// waitForExit polls the pod until the named ephemeral container has exited
+func (i *ephemeralInjector) waitForExit(ctx context.Context, podName, containerName string) error {
+	ctx, cancel := context.WithTimeout(ctx, containerStartTimeout)
+	defer cancel()
+
+	ticker := time.NewTicker(time.Second)
+	defer ticker.Stop()
+
+	for {
+		pod, err := i.clientset.CoreV1().Pods(i.namespace).Get(ctx, podName, metav1.GetOptions{})
+		if err != nil {
+			return fmt.Errorf("failed to get pod %s: %w", podName, err)
+		}
+
+		// A container without a status has not been started by the kubelet yet
+		exited := false
+		for _, status := range pod.Status.EphemeralContainerStatuses {
+			if status.Name != containerName || status.State.Terminated == nil {
+				continue
+			}
+			if terminated := status.State.Terminated; terminated.ExitCode != 0 {
+				return fmt.Errorf("chaos container %s in pod %s failed with exit code %d: %s", containerName, podName, terminated.ExitCode, strings.TrimSpace(terminated.Message))
+			}
+			exited = true
+		}
+		if exited {
+			return nil
+		}
+
+		select {
+		case <-ctx.Done():
+			return fmt.Errorf("timed out waiting for chaos container %s in pod %s to finish", containerName, podName)
+		case <-ticker.C:
+		}
+	}
+}
+
+// waitForStart polls the pod until the named ephemeral container has started
+func (i *ephemeralInjector) waitForStart(ctx context.Context, podName, containerName string) error {
+	ctx, cancel := context.WithTimeout(ctx, containerStartTimeout)
+	defer cancel()
+
+	ticker := time.NewTicker(time.Second)
+	defer ticker.Stop()
+
+	for {
+		pod, err := i.clientset.CoreV1().Pods(i.namespace).Get(ctx, podName, metav1.GetOptions{})
+		if err != nil {
+			return fmt.Errorf("failed to get pod %s: %w", podName, err)
+		}
+
+		// A container without a status has not been started by the kubelet yet
+		started := false
+		for _, status := range pod.Status.EphemeralContainerStatuses {
+			if status.Name != containerName {
+				continue
+			}
+			if status.State.Running != nil {
+				started = true
+			} else if status.State.Terminated != nil {
+				return fmt.Errorf("chaos container %s in pod %s exited: %s", containerName, podName, status.State.Terminated.Reason)
+			}
+		}
+		if started {
+			return nil
+		}
+
+		select {
+		case <-ctx.Done():
+			return fmt.Errorf("timed out waiting for chaos container %s in pod %s", containerName, podName)
+		case <-ticker.C:
+		}
+	}
+}
+
+// stop asks the injected script to undo its fault and exit
+func (i *ephemeralInjector) stop(inj *injection) error {
+	ctx, cancel := context.WithTimeout(context.Background(), cleanupTimeout)
+	defer cancel()
+
+	if err := i.terminate(ctx, inj); err != nil {
+		return err
+	}
+
+	resolveChange(i.journal, inj.change)
+	return nil
+}
+
+// terminate sends SIGTERM to the injected script unless it has already exited
+func (i *ephemeralInjector) terminate(ctx context.Context, inj *injection) error {
+	output, err := i.executor.ExecInContainer(ctx, i.namespace, inj.pod, inj.container, []string{"/bin/sh", "-c", "kill -TERM 1"})
+	if err != nil {
+		// A container that already finished has cleaned up after itself
+		if i.hasTerminated(ctx, inj) {
+			return nil
+		}
+		return fmt.Errorf("failed to stop chaos container %s in pod %s: %w (%s)", inj.container, inj.pod, err, strings.TrimSpace(output))
+	}
+
+	return nil
+}
+
+// stopAll stops every injection and logs the ones that could not be stopped
+func (i *ephemeralInjector) stopAll(result *ExperimentResult, injections []*injection) []string {
+	var failures []string
+	for _, inj := range injections {
+		if err := i.stop(inj); err != nil {
+			result.Logf("Cleanup failed: %v", err)
+			failures = append(failures, err.Error())
+		}
+	}
+	return failures
+}
+
+// hasTerminated reports whether the injected container is known to have exited
+func (i *ephemeralInjector) hasTerminated(ctx context.Context, inj *injection) bool {
+	pod, err := i.clientset.CoreV1().Pods(i.namespace).Get(ctx, inj.pod, metav1.GetOptions{})
+	if err != nil {
+		// A pod that no longer exists has nothing left to clean up
+		return true
+	}
+
+	for _, status := range pod.Status.EphemeralContainerStatuses {
+		if status.Name == inj.container && status.State.Terminated != nil {
+			return true
+		}
+	}
+
+	return false
+}
+
+// recoverEphemeralContainer stops a journaled chaos container that may still be running
+func recoverEphemeralContainer(ctx context.Context, deps Dependencies, change Change) error {
+	injector := &ephemeralInjector{
+		clientset: deps.Clientset,
+		executor:  deps.Executor,
+		namespace: change.Namespace,
+	}
+	inj := &injection{pod: change.Name, container: change.Data["container"]}
+
+	pod, err := deps.Clientset.CoreV1().Pods(change.Namespace).Get(ctx, change.Name, metav1.GetOptions{})
+	if err != nil {
+		return ignoreNotFound(err)
+	}
+
+	// The container was recorded but never attached
+	attached := false
+	for _, container := range pod.Spec.EphemeralContainers {
+		if container.Name == inj.container {
+			attached = true
+		}
+	}
+	if !attached || injector.hasTerminated(ctx, inj) {
+		return nil
+	}
+
+	if deps.Executor == nil {
+		return fmt.Errorf("recovering chaos container %s in pod %s requires a pod executor", inj.container, inj.pod)
+	}
+
+	return injector.terminate(ctx, inj)
+}
+
+// waitForDuration blocks until the duration elapses or the context is done
+func waitForDuration(ctx context.Context, duration int) error {
+	select {
+	case <-time.After(time.Duration(duration) * time.Second):
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// injectAll attaches the same chaos script to every pod, skipping pods where it fails
+func (i *ephemeralInjector) injectAll(ctx context.Context, result *ExperimentResult, pods []corev1.Pod, prefix, image, script string, securityContext *corev1.SecurityContext) []*injection {
+	injections := []*injection{}
+	for idx := range pods {
+		inj, err := i.inject(ctx, &pods[idx], prefix, image, script, securityContext)
+		if err != nil {
+			result.Logf("Failed to inject %s into pod %s: %v", prefix, pods[idx].Name, err)
+			continue
+		}
+		result.Logf("Injected %s into pod %s (container %s)", prefix, inj.pod, inj.container)
+		injections = append(injections, inj)
+	}
+	return injections
+}
+
+// planAll adds the injection of a chaos container into every pod to the plan
+func planAll(plan *Plan, pods []corev1.Pod, prefix, image, fault string, duration int) {
+	for _, pod := range pods {
+		plan.AffectedResources = append(plan.AffectedResources, pod.Name)
+		plan.addAction("Attach a chaos-%s container running %s to pod %s to %s for %ds", prefix, image, pod.Name, fault, duration)
+	}
+}
+
+// injectedPods returns the names of the pods that received an injection
+func injectedPods(injections []*injection) []string {
+	names := make([]string, 0, len(injections))
+	for _, inj := range injections {
+		names = append(names, inj.pod)
+	}
+	return names
+}
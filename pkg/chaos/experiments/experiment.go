@@ -0,0 +1,21 @@
+package experiments
+
+import (
+	"context"
+)
+
+// Experiment is a chaos experiment that can be run against a target.
+//
+// Running an experiment is its inject phase: Run records each change in the
+// journal before applying it, holds the fault for the experiment duration,
+// undoes it and resolves the change. The recover phase is the RecoverFunc of
+// the experiment type, which undoes changes Run could not, either right after
+// Run returns or when the platform restarts after a crash.
+type Experiment interface {
+	// Run injects the fault, holds it for the experiment duration and removes it
+	Run(ctx context.Context) (*ExperimentResult, error)
+
+	// Plan resolves the targets the way Run would and reports what it would
+	// do to them, without changing anything in the cluster
+	Plan(ctx context.Context) (*Plan, error)
+}
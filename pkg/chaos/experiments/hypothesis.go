@@ -0,0 +1,66 @@
+package experiments
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/flack/chaos-engineering-as-a-platform/pkg/chaos/probes"
+)
+
+// validateProbes checks the steady-state probes and abort conditions of a spec
+func validateProbes(spec Spec) error {
+	for i, probe := range spec.Probes {
+		if err := probe.Validate(); err != nil {
+			return fmt.Errorf("invalid probe %d: %w", i, err)
+		}
+	}
+	if spec.Abort != nil {
+		if err := spec.Abort.Validate(); err != nil {
+			return fmt.Errorf("invalid abort conditions: %w", err)
+		}
+	}
+	return nil
+}
+
+// steadyStateFailure reports a run that never injected anything because the
+// steady state did not hold beforehand
+func steadyStateFailure(experimentType string, report *probes.Report) (*ExperimentResult, error) {
+	now := time.Now()
+	result := &ExperimentResult{
+		ExperimentType: experimentType,
+		StartTime:      now,
+		EndTime:        now,
+		Hypothesis:     report,
+		Error:          "Steady state did not hold before injection: " + strings.Join(report.Failed(), ", "),
+	}
+	return result, errors.New(result.Error)
+}
+
+// concludeHypothesis samples the probes once the fault has been removed and
+// stores the verdict in the result. A run that succeeded fails when the
+// steady state did not hold.
+func concludeHypothesis(runner *probes.Runner, result *ExperimentResult, err error) error {
+	runner.Recover(context.Background())
+	if result == nil {
+		return err
+	}
+
+	result.Hypothesis = runner.Report()
+	if result.Hypothesis.Verdict == probes.VerdictPassed {
+		result.Logf("Steady-state hypothesis held")
+		return err
+	}
+
+	failed := strings.Join(result.Hypothesis.Failed(), ", ")
+	result.Logf("Steady-state hypothesis failed for probes: %s", failed)
+	if err != nil {
+		return err
+	}
+
+	result.Success = false
+	result.Error = "Steady-state hypothesis failed: " + failed
+	return errors.New(result.Error)
+}
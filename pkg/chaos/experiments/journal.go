@@ -0,0 +1,215 @@
+package experiments
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"sync"
+
+	"github.com/google/uuid"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+
+	"github.com/flack/chaos-engineering-as-a-platform/pkg/chaos/probes"
+)
+
+// Kinds of changes experiments record in the journal
+const (
+	ChangeEphemeralContainer = "ephemeral-container"
+	ChangeServiceSelector    = "service-selector"
+	ChangeNetworkPolicy      = "network-policy"
+	ChangeNodeCordon         = "node-cordon"
+	ChangeReplicas           = "replicas"
+)
+
+// Change records a single modification an experiment applies to the cluster
+type Change struct {
+	ID        string            `json:"id"`
+	Kind      string            `json:"kind"`
+	Namespace string            `json:"namespace,omitempty"`
+	Name      string            `json:"name"`
+	Data      map[string]string `json:"data,omitempty"`
+}
+
+// newChange creates a change with a fresh ID
+func newChange(kind, namespace, name string, data map[string]string) Change {
+	return Change{
+		ID:        uuid.New().String(),
+		Kind:      kind,
+		Namespace: namespace,
+		Name:      name,
+		Data:      data,
+	}
+}
+
+// Journal persists the changes an experiment applies so they can be undone
+// even if the process running the experiment dies.
+//
+// Experiments record a change before applying it and resolve it once it has
+// been undone. A recorded change may therefore never have been applied, so
+// recovering it must be harmless in that case.
+type Journal interface {
+	Record(change Change) error
+	Resolve(change Change) error
+}
+
+// RecoverFunc undoes a journaled change. It must be idempotent and succeed
+// when the change was never applied or has already been undone.
+type RecoverFunc func(ctx context.Context, deps Dependencies, change Change) error
+
+// recordChange writes a change to the journal, if there is one
+func recordChange(journal Journal, change Change) error {
+	if journal == nil {
+		return nil
+	}
+	if err := journal.Record(change); err != nil {
+		return fmt.Errorf("failed to record %s change for %s: %w", change.Kind, change.Name, err)
+	}
+	return nil
+}
+
+// resolveChange marks a change as undone in the journal, if there is one
+func resolveChange(journal Journal, change Change) {
+	if journal == nil {
+		return
+	}
+	if err := journal.Resolve(change); err != nil {
+		log.Printf("Failed to resolve %s change for %s: %v", change.Kind, change.Name, err)
+	}
+}
+
+// ignoreNotFound treats a missing object as already recovered
+func ignoreNotFound(err error) error {
+	if apierrors.IsNotFound(err) {
+		return nil
+	}
+	return err
+}
+
+// runJournal tracks the changes of a single run that have not been resolved yet
+type runJournal struct {
+	journal Journal
+	mu      sync.Mutex
+	pending map[string]Change
+	order   []string
+}
+
+// Record implements Journal
+func (j *runJournal) Record(change Change) error {
+	if err := recordChange(j.journal, change); err != nil {
+		return err
+	}
+
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	j.pending[change.ID] = change
+	j.order = append(j.order, change.ID)
+	return nil
+}
+
+// Resolve implements Journal
+func (j *runJournal) Resolve(change Change) error {
+	resolveChange(j.journal, change)
+
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	delete(j.pending, change.ID)
+	return nil
+}
+
+// unresolved returns the changes that are still pending, most recent first
+func (j *runJournal) unresolved() []Change {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	changes := []Change{}
+	for i := len(j.order) - 1; i >= 0; i-- {
+		if change, exists := j.pending[j.order[i]]; exists {
+			changes = append(changes, change)
+		}
+	}
+	return changes
+}
+
+// Execute validates the spec against the definition, builds the experiment
+// and runs it in two phases. The inject phase is the experiment's own Run,
+// which records every change in the journal of deps before applying it. The
+// recover phase then undoes any change the run left unresolved, whether it
+// succeeded, failed or was cancelled. Changes that cannot be recovered stay
+// in the journal for RecoverChange to retry later.
+//
+// When the spec has probes, the steady state must hold before anything is
+// injected. The probes are sampled throughout the inject phase and again
+// after the recover phase, and the verdict is stored in the result. When an
+// abort condition of the spec trips, the inject phase is cancelled, the
+// recover phase runs as usual and an *AbortError is returned.
+func Execute(ctx context.Context, definition Definition, deps Dependencies, spec Spec) (*ExperimentResult, error) {
+	if err := definition.Validate(spec); err != nil {
+		return nil, err
+	}
+	if err := validateProbes(spec); err != nil {
+		return nil, err
+	}
+	if err := checkTargetNamespace(ctx, definition, deps, spec); err != nil {
+		return nil, err
+	}
+
+	journal := &runJournal{journal: deps.Journal, pending: map[string]Change{}}
+	deps.Journal = journal
+
+	experiment, err := definition.New(deps, spec)
+	if err != nil {
+		return nil, err
+	}
+
+	var runner *probes.Runner
+	if len(spec.Probes) > 0 || spec.Abort != nil {
+		runner = probes.NewRunner(deps.Clientset, spec.Probes)
+	}
+	if len(spec.Probes) > 0 && !runner.Evaluate(ctx, probes.PhaseBefore) {
+		return steadyStateFailure(definition.Type, runner.Report())
+	}
+
+	var guard *probes.Guard
+	if spec.Abort != nil {
+		guard = probes.NewGuard(runner, *spec.Abort)
+	}
+
+	result, abort, err := runWatched(ctx, experiment, runner, guard)
+
+	recoverCtx, cancel := context.WithTimeout(context.Background(), cleanupTimeout)
+	defer cancel()
+
+	for _, change := range journal.unresolved() {
+		logf := log.Printf
+		if result != nil {
+			logf = result.Logf
+		}
+		if recoverErr := RecoverChange(recoverCtx, definition, deps, change); recoverErr != nil {
+			logf("Failed to recover %s change for %s: %v", change.Kind, change.Name, recoverErr)
+			continue
+		}
+		logf("Recovered %s change for %s", change.Kind, change.Name)
+	}
+
+	if len(spec.Probes) > 0 {
+		err = concludeHypothesis(runner, result, err)
+	}
+	if abort != nil {
+		err = concludeAbort(abort, result)
+	}
+
+	return result, err
+}
+
+// RecoverChange undoes a journaled change with the recover phase of its
+// experiment type and resolves it in the journal of deps
+func RecoverChange(ctx context.Context, definition Definition, deps Dependencies, change Change) error {
+	if definition.Recover != nil {
+		if err := definition.Recover(ctx, deps, change); err != nil {
+			return err
+		}
+	}
+
+	resolveChange(deps.Journal, change)
+	return nil
+}
@@ -0,0 +1,267 @@
+package experiments
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+)
+
+// maxRampSteps limits how many stress-ng processes a ramp-up starts
+const maxRampSteps = 10
+
+// Outcomes reported for pods targeted by a memory stress experiment
+const (
+	OutcomeSurvived  = "survived"
+	OutcomeOOMKilled = "oom-killed"
+	OutcomeEvicted   = "evicted"
+	OutcomeDeleted   = "deleted"
+)
+
+// MemoryStressConfig holds the settings for a memory stress experiment
+type MemoryStressConfig struct {
+	Namespace string
+	Selector  string
+	Duration  int
+	Size      int    // Memory to allocate in MB
+	RampRate  int    // Allocation rate in MB per second, 0 allocates everything at once
+	Image     string // Image providing stress-ng, defaults to DefaultStressImage
+}
+
+// MemoryStressExperiment allocates memory alongside the containers of matching pods
+type MemoryStressExperiment struct {
+	injector *ephemeralInjector
+	config   MemoryStressConfig
+}
+
+func init() {
+	Register(Definition{
+		Type:        "memory-stress",
+		Description: "Allocate memory inside pods with stress-ng",
+		Params: withPodParams(
+			Param{Name: "size", Description: "Memory to allocate", Type: ParamInt, Unit: "MB", Default: "256", Min: limit(1)},
+			Param{Name: "ramp_rate", Description: "Memory added per second, 0 allocates everything at once", Type: ParamInt, Unit: "MB", Default: "0", Min: limit(0)},
+			Param{Name: "image", Description: "Image providing stress-ng", Type: ParamString, Default: DefaultStressImage},
+		),
+		New: func(deps Dependencies, spec Spec) (Experiment, error) {
+			if err := spec.requirePods(); err != nil {
+				return nil, err
+			}
+
+			memoryStress := NewMemoryStressExperiment(deps.Clientset, deps.Executor, MemoryStressConfig{
+				Namespace: spec.Namespace,
+				Selector:  spec.Selector,
+				Duration:  spec.Duration,
+				Size:      spec.Int("size", 256),
+				RampRate:  spec.Int("ramp_rate", 0),
+				Image:     spec.Params["image"],
+			})
+			memoryStress.injector.journal = deps.Journal
+			memoryStress.injector.blastRadius = deps.BlastRadius
+			memoryStress.injector.protection = deps.Protection
+
+			return memoryStress, nil
+		},
+		Recover: recoverEphemeralContainer,
+	})
+}
+
+// NewMemoryStressExperiment creates a new memory stress experiment
+func NewMemoryStressExperiment(clientset kubernetes.Interface, executor PodExecutor, config MemoryStressConfig) *MemoryStressExperiment {
+	if config.Image == "" {
+		config.Image = DefaultStressImage
+	}
+
+	return &MemoryStressExperiment{
+		injector: &ephemeralInjector{
+			clientset: clientset,
+			executor:  executor,
+			namespace: config.Namespace,
+		},
+		config: config,
+	}
+}
+
+// script builds the shell script that allocates memory, ramping up if configured
+func (e *MemoryStressExperiment) script() string {
+	lines := []string{
+		"PIDS=\"\"",
+		"trap 'kill $PIDS 2>/dev/null; exit 0' TERM INT",
+	}
+
+	// Split the allocation into steps started at a fixed interval
+	steps, interval := 1, 0
+	if e.config.RampRate > 0 && e.config.RampRate < e.config.Size {
+		steps = (e.config.Size + e.config.RampRate - 1) / e.config.RampRate
+		if steps > maxRampSteps {
+			steps = maxRampSteps
+		}
+		chunk := (e.config.Size + steps - 1) / steps
+		interval = (chunk + e.config.RampRate - 1) / e.config.RampRate
+	}
+
+	allocated, elapsed := 0, 0
+	for step := 0; step < steps && elapsed < e.config.Duration; step++ {
+		chunk := (e.config.Size - allocated) / (steps - step)
+		allocated += chunk
+		lines = append(lines,
+			fmt.Sprintf("stress-ng --vm 1 --vm-bytes %dM --vm-keep --timeout %ds &", chunk, e.config.Duration-elapsed),
+			"PIDS=\"$PIDS $!\"",
+		)
+		if step < steps-1 && interval > 0 {
+			lines = append(lines, fmt.Sprintf("sleep %d", interval))
+			elapsed += interval
+		}
+	}
+
+	return strings.Join(append(lines, "wait"), "\n")
+}
+
+// containerRestarts returns the restart count of every regular container in a pod
+func containerRestarts(pod *corev1.Pod) map[string]int32 {
+	restarts := make(map[string]int32, len(pod.Status.ContainerStatuses))
+	for _, status := range pod.Status.ContainerStatuses {
+		restarts[status.Name] = status.RestartCount
+	}
+	return restarts
+}
+
+// podOutcome inspects the pod status to see how it fared under memory
+// pressure. The kernel may pick the injected container itself as the OOM
+// victim, which is reported like a kill of the pod's own containers.
+func (e *MemoryStressExperiment) podOutcome(ctx context.Context, inj *injection, restarts map[string]int32) string {
+	pod, err := e.injector.clientset.CoreV1().Pods(e.config.Namespace).Get(ctx, inj.pod, metav1.GetOptions{})
+	if err != nil {
+		return OutcomeDeleted
+	}
+
+	if pod.Status.Reason == "Evicted" {
+		return OutcomeEvicted
+	}
+
+	// Ephemeral containers never restart, so only the current state counts
+	for _, status := range pod.Status.EphemeralContainerStatuses {
+		if status.Name == inj.container && status.State.Terminated != nil && status.State.Terminated.Reason == "OOMKilled" {
+			return OutcomeOOMKilled
+		}
+	}
+
+	for _, status := range pod.Status.ContainerStatuses {
+		if status.State.Terminated != nil && status.State.Terminated.Reason == "OOMKilled" {
+			return OutcomeOOMKilled
+		}
+		if status.RestartCount > restarts[status.Name] &&
+			status.LastTerminationState.Terminated != nil &&
+			status.LastTerminationState.Terminated.Reason == "OOMKilled" {
+			return OutcomeOOMKilled
+		}
+	}
+
+	return OutcomeSurvived
+}
+
+// validate checks the configuration before anything is injected
+func (e *MemoryStressExperiment) validate() error {
+	if e.config.Size < 1 {
+		return fmt.Errorf("Invalid size: %d, must be greater than 0", e.config.Size)
+	}
+	return nil
+}
+
+// Plan reports the pods the memory stress would be injected into
+func (e *MemoryStressExperiment) Plan(ctx context.Context) (*Plan, error) {
+	if err := e.validate(); err != nil {
+		return nil, err
+	}
+
+	pods, left, err := e.injector.listPods(ctx, e.config.Selector)
+	if err != nil {
+		return nil, err
+	}
+
+	plan := newPlan("memory-stress")
+	plan.leaveOut(left)
+	planAll(plan, pods, "memory", e.config.Image, fmt.Sprintf("allocate %dMB", e.config.Size), e.config.Duration)
+	return plan, nil
+}
+
+// Run executes the memory stress experiment
+func (e *MemoryStressExperiment) Run(ctx context.Context) (*ExperimentResult, error) {
+	// Create a result object
+	result := &ExperimentResult{
+		ExperimentType: "memory-stress",
+		StartTime:      time.Now(),
+		Success:        false,
+		Metrics: map[string]float64{
+			"size_mb":      float64(e.config.Size),
+			"ramp_rate_mb": float64(e.config.RampRate),
+		},
+	}
+
+	result.Logf("Starting memory stress experiment in namespace %s with selector %s", e.config.Namespace, e.config.Selector)
+
+	if err := e.validate(); err != nil {
+		result.Error = err.Error()
+		return result, err
+	}
+
+	pods, left, err := e.injector.listPods(ctx, e.config.Selector)
+	if err != nil {
+		result.Error = err.Error()
+		return result, err
+	}
+	result.leaveOut(left)
+
+	// Remember restart counts so only OOM kills during the window are reported
+	restarts := make(map[string]map[string]int32, len(pods))
+	for idx := range pods {
+		restarts[pods[idx].Name] = containerRestarts(&pods[idx])
+	}
+
+	injections := e.injector.injectAll(ctx, result, pods, "memory", e.config.Image, e.script(), nil)
+	result.AffectedResources = injectedPods(injections)
+
+	if len(injections) == 0 {
+		result.Error = "Failed to inject memory stress into any pod"
+		return result, errors.New(result.Error)
+	}
+
+	// Hold the pressure for the experiment duration, then stop it even if cancelled
+	waitErr := waitForDuration(ctx, e.config.Duration)
+
+	// Read the outcome before stopping so a recovering pod is still reported
+	statusCtx, cancel := context.WithTimeout(context.Background(), cleanupTimeout)
+	defer cancel()
+
+	result.Outcomes = make(map[string]string, len(injections))
+	for _, inj := range injections {
+		outcome := e.podOutcome(statusCtx, inj, restarts[inj.pod])
+		result.Outcomes[inj.pod] = outcome
+		if outcome != OutcomeSurvived {
+			result.Logf("Pod %s was %s during memory stress", inj.pod, outcome)
+			result.Metrics[strings.ReplaceAll(outcome, "-", "_")+"_pods"]++
+		}
+	}
+
+	failures := e.injector.stopAll(result, injections)
+
+	result.EndTime = time.Now()
+
+	if waitErr != nil {
+		result.Error = "Experiment cancelled"
+		return result, waitErr
+	}
+
+	if len(failures) > 0 {
+		result.Error = fmt.Sprintf("Failed to stop memory stress: %s", strings.Join(failures, "; "))
+		return result, errors.New(result.Error)
+	}
+
+	result.Success = true
+	return result, nil
+}
@@ -0,0 +1,181 @@
+package experiments
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/client-go/kubernetes"
+)
+
+// DefaultNetemImage is the image used to run tc/netem when none is configured
+const DefaultNetemImage = "nicolaka/netshoot:latest"
+
+// NetworkDelayConfig holds the settings for a network delay experiment
+type NetworkDelayConfig struct {
+	Namespace   string
+	Selector    string
+	Duration    int
+	Delay       int    // Added latency in milliseconds
+	Jitter      int    // Random variation of the latency in milliseconds
+	Correlation int    // Correlation of the jitter with the previous packet in percent
+	Interface   string // Network interface inside the pod, defaults to eth0
+	Image       string // Image providing tc, defaults to DefaultNetemImage
+}
+
+// NetworkDelayExperiment adds latency to the network interface of matching pods
+type NetworkDelayExperiment struct {
+	injector *ephemeralInjector
+	config   NetworkDelayConfig
+}
+
+func init() {
+	Register(Definition{
+		Type:        "network-delay",
+		Description: "Add latency to pod network traffic with tc netem",
+		Params: withPodParams(
+			Param{Name: "delay", Description: "Added latency", Type: ParamInt, Unit: "ms", Default: "100", Min: limit(1), Max: limit(60000)},
+			Param{Name: "jitter", Description: "Latency variation", Type: ParamInt, Unit: "ms", Default: "0", Min: limit(0), Max: limit(60000)},
+			Param{Name: "correlation", Description: "Correlation between successive delays", Type: ParamInt, Unit: "%", Default: "0", Min: limit(0), Max: limit(100)},
+			Param{Name: "interface", Description: "Network interface to delay", Type: ParamString, Default: "eth0"},
+			Param{Name: "image", Description: "Image providing tc", Type: ParamString, Default: DefaultNetemImage},
+		),
+		New: func(deps Dependencies, spec Spec) (Experiment, error) {
+			if err := spec.requirePods(); err != nil {
+				return nil, err
+			}
+
+			networkDelay := NewNetworkDelayExperiment(deps.Clientset, deps.Executor, NetworkDelayConfig{
+				Namespace:   spec.Namespace,
+				Selector:    spec.Selector,
+				Duration:    spec.Duration,
+				Delay:       spec.Int("delay", 100),
+				Jitter:      spec.Int("jitter", 0),
+				Correlation: spec.Int("correlation", 0),
+				Interface:   spec.Params["interface"],
+				Image:       spec.Params["image"],
+			})
+			networkDelay.injector.journal = deps.Journal
+			networkDelay.injector.blastRadius = deps.BlastRadius
+			networkDelay.injector.protection = deps.Protection
+
+			return networkDelay, nil
+		},
+		Recover: recoverEphemeralContainer,
+	})
+}
+
+// NewNetworkDelayExperiment creates a new network delay experiment
+func NewNetworkDelayExperiment(clientset kubernetes.Interface, executor PodExecutor, config NetworkDelayConfig) *NetworkDelayExperiment {
+	if config.Interface == "" {
+		config.Interface = "eth0"
+	}
+	if config.Image == "" {
+		config.Image = DefaultNetemImage
+	}
+
+	return &NetworkDelayExperiment{
+		injector: &ephemeralInjector{
+			clientset: clientset,
+			executor:  executor,
+			namespace: config.Namespace,
+		},
+		config: config,
+	}
+}
+
+// script builds the shell script that applies netem and removes it on exit
+func (e *NetworkDelayExperiment) script() string {
+	iface := e.config.Interface
+	return strings.Join([]string{
+		fmt.Sprintf("trap 'tc qdisc del dev %s root 2>/dev/null; exit 0' TERM INT", iface),
+		fmt.Sprintf("tc qdisc replace dev %s root netem delay %dms %dms %d%% || exit 1", iface, e.config.Delay, e.config.Jitter, e.config.Correlation),
+		fmt.Sprintf("sleep %d &", e.config.Duration),
+		"wait $!",
+		fmt.Sprintf("tc qdisc del dev %s root", iface),
+	}, "\n")
+}
+
+// Plan reports the pods the network delay would be injected into
+func (e *NetworkDelayExperiment) Plan(ctx context.Context) (*Plan, error) {
+	if err := validateShellValue("interface", e.config.Interface); err != nil {
+		return nil, err
+	}
+
+	pods, left, err := e.injector.listPods(ctx, e.config.Selector)
+	if err != nil {
+		return nil, err
+	}
+
+	plan := newPlan("network-delay")
+	plan.leaveOut(left)
+	fault := fmt.Sprintf("delay traffic on %s by %dms", e.config.Interface, e.config.Delay)
+	planAll(plan, pods, "netem", e.config.Image, fault, e.config.Duration)
+	return plan, nil
+}
+
+// Run executes the network delay experiment
+func (e *NetworkDelayExperiment) Run(ctx context.Context) (*ExperimentResult, error) {
+	// Create a result object
+	result := &ExperimentResult{
+		ExperimentType: "network-delay",
+		StartTime:      time.Now(),
+		Success:        false,
+		Metrics: map[string]float64{
+			"delay_ms":    float64(e.config.Delay),
+			"jitter_ms":   float64(e.config.Jitter),
+			"correlation": float64(e.config.Correlation),
+		},
+	}
+
+	result.Logf("Starting network delay experiment in namespace %s with selector %s", e.config.Namespace, e.config.Selector)
+
+	if err := validateShellValue("interface", e.config.Interface); err != nil {
+		result.Error = err.Error()
+		return result, err
+	}
+
+	pods, left, err := e.injector.listPods(ctx, e.config.Selector)
+	if err != nil {
+		result.Error = err.Error()
+		return result, err
+	}
+	result.leaveOut(left)
+
+	// tc needs NET_ADMIN in the pod network namespace
+	securityContext := &corev1.SecurityContext{
+		Capabilities: &corev1.Capabilities{
+			Add: []corev1.Capability{"NET_ADMIN"},
+		},
+	}
+
+	injections := e.injector.injectAll(ctx, result, pods, "netem", e.config.Image, e.script(), securityContext)
+	result.AffectedResources = injectedPods(injections)
+
+	if len(injections) == 0 {
+		result.Error = "Failed to inject network delay into any pod"
+		return result, errors.New(result.Error)
+	}
+
+	// Hold the delay for the experiment duration, then remove it even if cancelled
+	waitErr := waitForDuration(ctx, e.config.Duration)
+	failures := e.injector.stopAll(result, injections)
+
+	result.EndTime = time.Now()
+
+	if waitErr != nil {
+		result.Error = "Experiment cancelled"
+		return result, waitErr
+	}
+
+	if len(failures) > 0 {
+		result.Error = fmt.Sprintf("Failed to remove network delay: %s", strings.Join(failures, "; "))
+		return result, errors.New(result.Error)
+	}
+
+	result.Success = true
+	return result, nil
+}
@@ -0,0 +1,521 @@
+package experiments
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	corev1 "k8s.io/api/core/v1"
+	networkingv1 "k8s.io/api/networking/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/client-go/kubernetes"
+)
+
+// PartitionDirection defines which traffic a network partition blocks
+type PartitionDirection string
+
+const (
+	PartitionIngress PartitionDirection = "ingress"
+	PartitionEgress  PartitionDirection = "egress"
+	PartitionBoth    PartitionDirection = "both"
+)
+
+const (
+	// ChaosExperimentLabel marks Kubernetes objects created by chaos experiments
+	ChaosExperimentLabel = "chaos.platform/experiment"
+
+	// PartitionLabel carries the token of the partition a NetworkPolicy belongs to
+	PartitionLabel = "chaos.platform/partition"
+)
+
+// NetworkPartitionConfig holds the settings for a network partition experiment
+type NetworkPartitionConfig struct {
+	Namespace string
+	Selector  string // Label selector for the pods to isolate
+	Duration  int
+	Direction PartitionDirection // Traffic to block, defaults to both
+	Peers     string             // Optional label selector; when set only traffic with these pods is blocked
+}
+
+// NetworkPartitionExperiment isolates pods by creating a deny NetworkPolicy for
+// them. NetworkPolicies are additive, so traffic another policy already allows
+// between the pods and the cut peers cannot be denied; such a partition is refused.
+type NetworkPartitionExperiment struct {
+	clientset kubernetes.Interface
+	config    NetworkPartitionConfig
+	token     string
+	journal   Journal
+
+	blastRadius *BlastRadius
+	protection  *Protection
+}
+
+func init() {
+	Register(Definition{
+		Type:        "network-partition",
+		Description: "Isolate pods with a deny NetworkPolicy",
+		Params: withPodParams(
+			Param{Name: "direction", Description: "ingress, egress or both", Type: ParamString, Default: string(PartitionBoth), Enum: []string{string(PartitionIngress), string(PartitionEgress), string(PartitionBoth)}},
+			Param{Name: "peers", Description: "Label selector for the pods to cut off, all traffic when empty", Type: ParamString},
+		),
+		New: func(deps Dependencies, spec Spec) (Experiment, error) {
+			if err := spec.requirePods(); err != nil {
+				return nil, err
+			}
+
+			networkPartition := NewNetworkPartitionExperiment(deps.Clientset, NetworkPartitionConfig{
+				Namespace: spec.Namespace,
+				Selector:  spec.Selector,
+				Duration:  spec.Duration,
+				Direction: PartitionDirection(spec.Params["direction"]),
+				Peers:     spec.Params["peers"],
+			})
+			networkPartition.journal = deps.Journal
+			networkPartition.blastRadius = deps.BlastRadius
+			networkPartition.protection = deps.Protection
+
+			return networkPartition, nil
+		},
+		Recover: recoverNetworkPolicy,
+	})
+}
+
+// NewNetworkPartitionExperiment creates a new network partition experiment
+func NewNetworkPartitionExperiment(clientset kubernetes.Interface, config NetworkPartitionConfig) *NetworkPartitionExperiment {
+	if config.Direction == "" {
+		config.Direction = PartitionBoth
+	}
+
+	return &NetworkPartitionExperiment{
+		clientset: clientset,
+		config:    config,
+		token:     uuid.New().String()[:8],
+	}
+}
+
+// PolicyName returns the name of the NetworkPolicy created by the experiment
+func (e *NetworkPartitionExperiment) PolicyName() string {
+	return "chaos-partition-" + e.token
+}
+
+// negatePeers builds peers that match every pod except those selected by
+// the selector. NetworkPolicies can only allow traffic, so blocking a set of
+// peers means allowing everything outside it. Since NOT (A AND B) is
+// NOT A OR NOT B, each requirement becomes its own peer.
+func negatePeers(selector string) ([]networkingv1.NetworkPolicyPeer, error) {
+	labelSelector, err := metav1.ParseToLabelSelector(selector)
+	if err != nil {
+		return nil, fmt.Errorf("invalid peers selector: %w", err)
+	}
+
+	requirements := labelSelector.MatchExpressions
+	for key, value := range labelSelector.MatchLabels {
+		requirements = append(requirements, metav1.LabelSelectorRequirement{
+			Key:      key,
+			Operator: metav1.LabelSelectorOpIn,
+			Values:   []string{value},
+		})
+	}
+
+	if len(requirements) == 0 {
+		return nil, fmt.Errorf("invalid peers selector: %q selects every pod", selector)
+	}
+
+	negations := map[metav1.LabelSelectorOperator]metav1.LabelSelectorOperator{
+		metav1.LabelSelectorOpIn:           metav1.LabelSelectorOpNotIn,
+		metav1.LabelSelectorOpNotIn:        metav1.LabelSelectorOpIn,
+		metav1.LabelSelectorOpExists:       metav1.LabelSelectorOpDoesNotExist,
+		metav1.LabelSelectorOpDoesNotExist: metav1.LabelSelectorOpExists,
+	}
+
+	peers := make([]networkingv1.NetworkPolicyPeer, 0, len(requirements))
+	for _, requirement := range requirements {
+		peers = append(peers, networkingv1.NetworkPolicyPeer{
+			// An empty namespace selector matches pods in every namespace
+			NamespaceSelector: &metav1.LabelSelector{},
+			PodSelector: &metav1.LabelSelector{
+				MatchExpressions: []metav1.LabelSelectorRequirement{{
+					Key:      requirement.Key,
+					Operator: negations[requirement.Operator],
+					Values:   requirement.Values,
+				}},
+			},
+		})
+	}
+
+	return peers, nil
+}
+
+// policy builds the NetworkPolicy that partitions the selected pods
+func (e *NetworkPartitionExperiment) policy() (*networkingv1.NetworkPolicy, error) {
+	podSelector, err := metav1.ParseToLabelSelector(e.config.Selector)
+	if err != nil {
+		return nil, fmt.Errorf("invalid selector: %w", err)
+	}
+
+	policy := &networkingv1.NetworkPolicy{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      e.PolicyName(),
+			Namespace: e.config.Namespace,
+			Labels: map[string]string{
+				ChaosExperimentLabel: "network-partition",
+				PartitionLabel:       e.token,
+			},
+		},
+		Spec: networkingv1.NetworkPolicySpec{
+			PodSelector: *podSelector,
+		},
+	}
+
+	// Without peers the policy has no rules, which denies all traffic in its directions
+	var peers []networkingv1.NetworkPolicyPeer
+	if e.config.Peers != "" {
+		if peers, err = negatePeers(e.config.Peers); err != nil {
+			return nil, err
+		}
+	}
+
+	switch e.config.Direction {
+	case PartitionIngress, PartitionBoth:
+		policy.Spec.PolicyTypes = append(policy.Spec.PolicyTypes, networkingv1.PolicyTypeIngress)
+		if peers != nil {
+			policy.Spec.Ingress = []networkingv1.NetworkPolicyIngressRule{{From: peers}}
+		}
+	}
+	switch e.config.Direction {
+	case PartitionEgress, PartitionBoth:
+		policy.Spec.PolicyTypes = append(policy.Spec.PolicyTypes, networkingv1.PolicyTypeEgress)
+		if peers != nil {
+			policy.Spec.Egress = []networkingv1.NetworkPolicyEgressRule{{To: peers}}
+		}
+	}
+
+	if len(policy.Spec.PolicyTypes) == 0 {
+		return nil, fmt.Errorf("invalid direction: %s, must be %s, %s or %s", e.config.Direction, PartitionIngress, PartitionEgress, PartitionBoth)
+	}
+
+	return policy, nil
+}
+
+// recoverNetworkPolicy deletes a journaled partition policy
+func recoverNetworkPolicy(ctx context.Context, deps Dependencies, change Change) error {
+	err := deps.Clientset.NetworkingV1().NetworkPolicies(change.Namespace).Delete(ctx, change.Name, metav1.DeleteOptions{})
+	return ignoreNotFound(err)
+}
+
+// checkTargets rejects a partition of pods that may not be targeted or that
+// the blast-radius policy does not allow. The network policy selects every
+// matching pod, so it cannot leave some of them out.
+func (e *NetworkPartitionExperiment) checkTargets(ctx context.Context, pods []corev1.Pod) error {
+	_, excluded, err := e.protection.filterPods(ctx, e.clientset, pods)
+	if err != nil {
+		return err
+	}
+	if len(excluded) > 0 {
+		return fmt.Errorf("the selector matches pods that did not opt in to chaos: %s", strings.Join(excluded, ", "))
+	}
+	return e.blastRadius.check(ctx, e.clientset, pods)
+}
+
+// blocks reports whether the partition blocks traffic in the direction
+func (e *NetworkPartitionExperiment) blocks(direction networkingv1.PolicyType) bool {
+	switch direction {
+	case networkingv1.PolicyTypeIngress:
+		return e.config.Direction == PartitionIngress || e.config.Direction == PartitionBoth
+	case networkingv1.PolicyTypeEgress:
+		return e.config.Direction == PartitionEgress || e.config.Direction == PartitionBoth
+	}
+	return false
+}
+
+// allowedPeers returns the peers of every rule with which a policy allows
+// traffic to or from the pods it selects in the direction
+func allowedPeers(policy *networkingv1.NetworkPolicy, direction networkingv1.PolicyType) [][]networkingv1.NetworkPolicyPeer {
+	applies := false
+	for _, policyType := range policy.Spec.PolicyTypes {
+		applies = applies || policyType == direction
+	}
+
+	rules := [][]networkingv1.NetworkPolicyPeer{}
+	switch direction {
+	case networkingv1.PolicyTypeIngress:
+		if applies || len(policy.Spec.PolicyTypes) == 0 {
+			for _, rule := range policy.Spec.Ingress {
+				rules = append(rules, rule.From)
+			}
+		}
+	case networkingv1.PolicyTypeEgress:
+		if applies || len(policy.Spec.PolicyTypes) == 0 {
+			for _, rule := range policy.Spec.Egress {
+				rules = append(rules, rule.To)
+			}
+		}
+	}
+	return rules
+}
+
+// namespaceLabels returns the labels of a namespace, including the name
+// label the API server adds to every namespace
+func (e *NetworkPartitionExperiment) namespaceLabels(ctx context.Context, name string, cache map[string]labels.Set) (labels.Set, error) {
+	if set, exists := cache[name]; exists {
+		return set, nil
+	}
+
+	set := labels.Set{"kubernetes.io/metadata.name": name}
+	namespace, err := e.clientset.CoreV1().Namespaces().Get(ctx, name, metav1.GetOptions{})
+	if err != nil && !apierrors.IsNotFound(err) {
+		return nil, fmt.Errorf("failed to get namespace %s: %w", name, err)
+	}
+	if err == nil {
+		for key, value := range namespace.Labels {
+			set[key] = value
+		}
+	}
+	cache[name] = set
+	return set, nil
+}
+
+// overlaps reports whether a rule of a policy in the namespace lets through
+// traffic with any of the peers the partition cuts. Without peers the
+// partition cuts all traffic, which every rule overlaps.
+func (e *NetworkPartitionExperiment) overlaps(ctx context.Context, namespace string, rule []networkingv1.NetworkPolicyPeer, cut []corev1.Pod, cache map[string]labels.Set) (bool, error) {
+	if e.config.Peers == "" || len(rule) == 0 {
+		return true, nil
+	}
+
+	for _, peer := range rule {
+		// IP blocks are outside the pods the partition is about
+		if peer.IPBlock != nil {
+			continue
+		}
+
+		podSelector := labels.Everything()
+		if peer.PodSelector != nil {
+			selector, err := metav1.LabelSelectorAsSelector(peer.PodSelector)
+			if err != nil {
+				return false, fmt.Errorf("invalid pod selector in network policy peer: %w", err)
+			}
+			podSelector = selector
+		}
+		var namespaceSelector labels.Selector
+		if peer.NamespaceSelector != nil {
+			selector, err := metav1.LabelSelectorAsSelector(peer.NamespaceSelector)
+			if err != nil {
+				return false, fmt.Errorf("invalid namespace selector in network policy peer: %w", err)
+			}
+			namespaceSelector = selector
+		}
+
+		for _, pod := range cut {
+			if !podSelector.Matches(labels.Set(pod.Labels)) {
+				continue
+			}
+			if namespaceSelector == nil {
+				// A peer without a namespace selector only matches pods in the namespace of the policy
+				if pod.Namespace == namespace {
+					return true, nil
+				}
+				continue
+			}
+			set, err := e.namespaceLabels(ctx, pod.Namespace, cache)
+			if err != nil {
+				return false, err
+			}
+			if namespaceSelector.Matches(set) {
+				return true, nil
+			}
+		}
+	}
+	return false, nil
+}
+
+// checkPolicies rejects a partition of pods that existing policies allow
+// traffic to or from with the peers the partition cuts, in a direction it
+// blocks. The partition would not change anything for that traffic, since
+// policies only ever add to what is allowed.
+func (e *NetworkPartitionExperiment) checkPolicies(ctx context.Context, pods []corev1.Pod) error {
+	policies, err := e.clientset.NetworkingV1().NetworkPolicies(e.config.Namespace).List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return fmt.Errorf("failed to list network policies: %w", err)
+	}
+
+	// The partition cuts the pods matching the peers in every namespace
+	var cut []corev1.Pod
+	if e.config.Peers != "" {
+		peers, err := e.clientset.CoreV1().Pods(metav1.NamespaceAll).List(ctx, metav1.ListOptions{
+			LabelSelector: e.config.Peers,
+		})
+		if err != nil {
+			return fmt.Errorf("failed to list peer pods: %w", err)
+		}
+		cut = peers.Items
+	}
+
+	conflicts := []string{}
+	namespaces := map[string]labels.Set{}
+	for idx := range policies.Items {
+		policy := &policies.Items[idx]
+		selector, err := metav1.LabelSelectorAsSelector(&policy.Spec.PodSelector)
+		if err != nil {
+			return fmt.Errorf("invalid pod selector in network policy %s: %w", policy.Name, err)
+		}
+
+		selectsTarget := false
+		for _, pod := range pods {
+			if selector.Matches(labels.Set(pod.Labels)) {
+				selectsTarget = true
+				break
+			}
+		}
+		if !selectsTarget {
+			continue
+		}
+
+		for _, direction := range []networkingv1.PolicyType{networkingv1.PolicyTypeIngress, networkingv1.PolicyTypeEgress} {
+			if !e.blocks(direction) {
+				continue
+			}
+			for _, rule := range allowedPeers(policy, direction) {
+				overlap, err := e.overlaps(ctx, policy.Namespace, rule, cut, namespaces)
+				if err != nil {
+					return err
+				}
+				if overlap {
+					conflicts = append(conflicts, fmt.Sprintf("%s (%s)", policy.Name, strings.ToLower(string(direction))))
+					break
+				}
+			}
+		}
+	}
+
+	if len(conflicts) > 0 {
+		return fmt.Errorf("network policies already allow traffic the partition would block, which it cannot override: %s", strings.Join(conflicts, ", "))
+	}
+	return nil
+}
+
+// Plan reports the pods the network partition would isolate
+func (e *NetworkPartitionExperiment) Plan(ctx context.Context) (*Plan, error) {
+	policy, err := e.policy()
+	if err != nil {
+		return nil, err
+	}
+
+	pods, err := e.clientset.CoreV1().Pods(e.config.Namespace).List(ctx, metav1.ListOptions{
+		LabelSelector: e.config.Selector,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list pods: %w", err)
+	}
+	if len(pods.Items) == 0 {
+		return nil, errors.New("No pods found matching the selector")
+	}
+
+	if err := e.checkTargets(ctx, pods.Items); err != nil {
+		return nil, err
+	}
+	if err := e.checkPolicies(ctx, pods.Items); err != nil {
+		return nil, err
+	}
+
+	peers := "all pods"
+	if e.config.Peers != "" {
+		peers = "pods matching " + e.config.Peers
+	}
+
+	plan := newPlan("network-partition")
+	for _, pod := range pods.Items {
+		plan.AffectedResources = append(plan.AffectedResources, pod.Name)
+	}
+	plan.addAction("Create network policy %s blocking %s traffic between the selected pods and %s for %ds", policy.Name, e.config.Direction, peers, e.config.Duration)
+	return plan, nil
+}
+
+// Run executes the network partition experiment
+func (e *NetworkPartitionExperiment) Run(ctx context.Context) (*ExperimentResult, error) {
+	// Create a result object
+	result := &ExperimentResult{
+		ExperimentType: "network-partition",
+		StartTime:      time.Now(),
+		Success:        false,
+	}
+
+	result.Logf("Starting network partition experiment in namespace %s with selector %s", e.config.Namespace, e.config.Selector)
+
+	policy, err := e.policy()
+	if err != nil {
+		result.Error = err.Error()
+		return result, err
+	}
+
+	// Record the pods that end up isolated
+	pods, err := e.clientset.CoreV1().Pods(e.config.Namespace).List(ctx, metav1.ListOptions{
+		LabelSelector: e.config.Selector,
+	})
+	if err != nil {
+		result.Error = fmt.Sprintf("Failed to list pods: %v", err)
+		return result, err
+	}
+	if len(pods.Items) == 0 {
+		result.Error = "No pods found matching the selector"
+		return result, errors.New(result.Error)
+	}
+
+	if err := e.checkTargets(ctx, pods.Items); err != nil {
+		result.Error = err.Error()
+		return result, err
+	}
+	if err := e.checkPolicies(ctx, pods.Items); err != nil {
+		result.Error = err.Error()
+		return result, err
+	}
+
+	change := newChange(ChangeNetworkPolicy, e.config.Namespace, policy.Name, nil)
+	if err := recordChange(e.journal, change); err != nil {
+		result.Error = err.Error()
+		return result, err
+	}
+
+	if _, err := e.clientset.NetworkingV1().NetworkPolicies(e.config.Namespace).Create(ctx, policy, metav1.CreateOptions{}); err != nil {
+		resolveChange(e.journal, change)
+		result.Error = fmt.Sprintf("Failed to create network policy: %v", err)
+		return result, err
+	}
+	result.Logf("Created network policy %s", policy.Name)
+
+	for _, pod := range pods.Items {
+		result.AffectedResources = append(result.AffectedResources, pod.Name)
+	}
+
+	// Keep the partition for the experiment duration, then remove it even if cancelled
+	waitErr := waitForDuration(ctx, e.config.Duration)
+
+	deleteCtx, cancel := context.WithTimeout(context.Background(), cleanupTimeout)
+	defer cancel()
+	deleteErr := e.clientset.NetworkingV1().NetworkPolicies(e.config.Namespace).Delete(deleteCtx, policy.Name, metav1.DeleteOptions{})
+	if deleteErr != nil {
+		result.Logf("Failed to delete network policy %s: %v", policy.Name, deleteErr)
+	} else {
+		resolveChange(e.journal, change)
+	}
+
+	result.EndTime = time.Now()
+
+	if waitErr != nil {
+		result.Error = "Experiment cancelled"
+		return result, waitErr
+	}
+
+	if deleteErr != nil {
+		result.Error = fmt.Sprintf("Failed to delete network policy %s: %v", policy.Name, deleteErr)
+		return result, deleteErr
+	}
+
+	result.Success = true
+	return result, nil
+}
@@ -0,0 +1,497 @@
+package experiments
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	corev1 "k8s.io/api/core/v1"
+	policyv1 "k8s.io/api/policy/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/fields"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/util/retry"
+)
+
+const (
+	// CordonedByAnnotation marks nodes cordoned by a chaos experiment so only those are uncordoned
+	CordonedByAnnotation = "chaos.platform/cordoned-by"
+
+	// defaultDrainTimeout bounds how long evictions blocked by disruption budgets are retried
+	defaultDrainTimeout = 2 * time.Minute
+
+	// evictionRetryInterval is the pause between eviction attempts blocked by a disruption budget
+	evictionRetryInterval = 5 * time.Second
+
+	// mirrorPodAnnotation marks static pods, which cannot be evicted through the API
+	mirrorPodAnnotation = "kubernetes.io/config.mirror"
+)
+
+// Outcomes reported for pods on a drained node
+const (
+	OutcomePDBBlocked     = "blocked-by-pdb"
+	OutcomeEvictionFailed = "eviction-failed"
+)
+
+// NodeFailureConfig holds the settings for a node failure experiment
+type NodeFailureConfig struct {
+	NodeName     string // Name of the node to take out of service
+	Selector     string // Label selector for candidate nodes, used when NodeName is empty
+	Count        int    // Number of nodes to take out when using a selector, defaults to 1
+	Duration     int
+	DrainTimeout int // Seconds to keep retrying evictions blocked by disruption budgets, 0 uses the default
+}
+
+// NodeFailureExperiment cordons and drains nodes, then puts them back in service
+type NodeFailureExperiment struct {
+	clientset kubernetes.Interface
+	config    NodeFailureConfig
+	token     string
+	journal   Journal
+
+	blastRadius *BlastRadius
+	protection  *Protection
+}
+
+func init() {
+	Register(Definition{
+		Type:        "node-failure",
+		Description: "Cordon and drain nodes, then put them back in service",
+		TargetType:  "node",
+		Params: []Param{
+			{Name: "node", Description: "Name of a single node to take out", Type: ParamString},
+			{Name: "selector", Description: "Label selector for candidate nodes, used when node is empty", Type: ParamString},
+			{Name: "count", Description: "Number of nodes to take out when using a selector", Type: ParamInt, Default: "1", Min: limit(1)},
+			{Name: "drain_timeout", Description: "Time to retry evictions blocked by disruption budgets", Type: ParamInt, Unit: "s", Default: "120", Min: limit(1)},
+		},
+		New: func(deps Dependencies, spec Spec) (Experiment, error) {
+			config := NodeFailureConfig{
+				Selector:     spec.Selector,
+				Count:        spec.Int("count", 1),
+				Duration:     spec.Duration,
+				DrainTimeout: spec.Int("drain_timeout", 0),
+			}
+			if !spec.Targeted {
+				config.NodeName = spec.Params["node"]
+			}
+
+			if config.NodeName == "" && config.Selector == "" {
+				return nil, fmt.Errorf("missing required parameter: node or selector")
+			}
+
+			nodeFailure := NewNodeFailureExperiment(deps.Clientset, config)
+			nodeFailure.journal = deps.Journal
+			nodeFailure.blastRadius = deps.BlastRadius
+			nodeFailure.protection = deps.Protection
+
+			return nodeFailure, nil
+		},
+		Recover: recoverNode,
+	})
+}
+
+// NewNodeFailureExperiment creates a new node failure experiment
+func NewNodeFailureExperiment(clientset kubernetes.Interface, config NodeFailureConfig) *NodeFailureExperiment {
+	if config.Count <= 0 {
+		config.Count = 1
+	}
+
+	return &NodeFailureExperiment{
+		clientset: clientset,
+		config:    config,
+		token:     uuid.New().String()[:8],
+	}
+}
+
+// nodes returns the names of the nodes to take out of service
+func (e *NodeFailureExperiment) nodes(ctx context.Context) ([]string, error) {
+	if e.config.NodeName != "" {
+		return []string{e.config.NodeName}, nil
+	}
+
+	if e.config.Selector == "" {
+		return nil, fmt.Errorf("either a node name or a selector is required")
+	}
+
+	nodes, err := e.clientset.CoreV1().Nodes().List(ctx, metav1.ListOptions{
+		LabelSelector: e.config.Selector,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list nodes: %w", err)
+	}
+
+	// Only schedulable nodes are candidates, and a stable order keeps runs predictable
+	names := []string{}
+	for _, node := range nodes.Items {
+		if !node.Spec.Unschedulable {
+			names = append(names, node.Name)
+		}
+	}
+	sort.Strings(names)
+
+	if len(names) == 0 {
+		return nil, fmt.Errorf("no schedulable nodes found matching the selector")
+	}
+	if len(names) > e.config.Count {
+		names = names[:e.config.Count]
+	}
+
+	return names, nil
+}
+
+// checkProtected rejects the experiment if any of the nodes is opted out of chaos
+func (e *NodeFailureExperiment) checkProtected(ctx context.Context, names []string) error {
+	metas := []*metav1.ObjectMeta{}
+	for _, name := range names {
+		node, err := e.clientset.CoreV1().Nodes().Get(ctx, name, metav1.GetOptions{})
+		if err != nil {
+			if ignoreNotFound(err) == nil {
+				continue
+			}
+			return fmt.Errorf("failed to get node %s: %w", name, err)
+		}
+		metas = append(metas, &node.ObjectMeta)
+	}
+	return e.protection.checkObjects("node", metas)
+}
+
+// cordon marks the node unschedulable and records that this experiment did it
+func (e *NodeFailureExperiment) cordon(ctx context.Context, name string) error {
+	return retry.RetryOnConflict(retry.DefaultRetry, func() error {
+		node, err := e.clientset.CoreV1().Nodes().Get(ctx, name, metav1.GetOptions{})
+		if err != nil {
+			return fmt.Errorf("failed to get node %s: %w", name, err)
+		}
+
+		// A node that is already cordoned belongs to someone else and must stay that way
+		if node.Spec.Unschedulable {
+			return fmt.Errorf("node %s is already cordoned", name)
+		}
+
+		if node.Annotations == nil {
+			node.Annotations = map[string]string{}
+		}
+		node.Annotations[CordonedByAnnotation] = e.token
+		node.Spec.Unschedulable = true
+
+		_, err = e.clientset.CoreV1().Nodes().Update(ctx, node, metav1.UpdateOptions{})
+		return err
+	})
+}
+
+// UncordonNode makes a node schedulable again if it is still cordoned by the experiment holding the token
+func UncordonNode(ctx context.Context, clientset kubernetes.Interface, name, token string) error {
+	return retry.RetryOnConflict(retry.DefaultRetry, func() error {
+		node, err := clientset.CoreV1().Nodes().Get(ctx, name, metav1.GetOptions{})
+		if err != nil {
+			return fmt.Errorf("failed to get node %s: %w", name, err)
+		}
+
+		if node.Annotations[CordonedByAnnotation] != token {
+			// Cordoned by someone else or already uncordoned, nothing to undo
+			return nil
+		}
+
+		delete(node.Annotations, CordonedByAnnotation)
+		node.Spec.Unschedulable = false
+
+		_, err = clientset.CoreV1().Nodes().Update(ctx, node, metav1.UpdateOptions{})
+		return err
+	})
+}
+
+// recoverNode uncordons a journaled node if it is still cordoned by the experiment that recorded it
+func recoverNode(ctx context.Context, deps Dependencies, change Change) error {
+	return ignoreNotFound(UncordonNode(ctx, deps.Clientset, change.Name, change.Data["token"]))
+}
+
+// evictable reports whether a pod on the node should be evicted during the drain
+func evictable(pod *corev1.Pod) bool {
+	if pod.Status.Phase == corev1.PodSucceeded || pod.Status.Phase == corev1.PodFailed {
+		return false
+	}
+	if _, mirror := pod.Annotations[mirrorPodAnnotation]; mirror {
+		return false
+	}
+	for _, owner := range pod.OwnerReferences {
+		// DaemonSet pods would be recreated on the same node right away
+		if owner.Kind == "DaemonSet" {
+			return false
+		}
+	}
+	return true
+}
+
+// evictablePods returns the pods on the node that a drain evicts, and the
+// ones it excluded for not opting in to chaos. Protected and excluded pods
+// are left running on the cordoned node.
+func (e *NodeFailureExperiment) evictablePods(ctx context.Context, name string) ([]corev1.Pod, []string, error) {
+	pods, err := e.clientset.CoreV1().Pods(metav1.NamespaceAll).List(ctx, metav1.ListOptions{
+		FieldSelector: fields.OneTermEqualSelector("spec.nodeName", name).String(),
+	})
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to list pods on node %s: %w", name, err)
+	}
+	protected, err := e.protection.protectedPods(ctx, e.clientset, pods.Items)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	evictablePods := []corev1.Pod{}
+	for _, pod := range pods.Items {
+		if _, isProtected := protected[pod.Namespace+"/"+pod.Name]; !isProtected && evictable(&pod) {
+			evictablePods = append(evictablePods, pod)
+		}
+	}
+	return e.protection.optedInPods(ctx, e.clientset, evictablePods)
+}
+
+// drainTimeout returns how long evictions blocked by a disruption budget are
+// retried. The drain also ends with the experiment duration, which counts
+// from the moment the nodes are cordoned.
+func (e *NodeFailureExperiment) drainTimeout() time.Duration {
+	if e.config.DrainTimeout > 0 {
+		return time.Duration(e.config.DrainTimeout) * time.Second
+	}
+	return defaultDrainTimeout
+}
+
+// drain evicts the pods on the node, retrying evictions refused by a disruption budget
+func (e *NodeFailureExperiment) drain(ctx context.Context, name string, result *ExperimentResult) error {
+	pending, excluded, err := e.evictablePods(ctx, name)
+	if err != nil {
+		return err
+	}
+	result.leaveOut(leftOut{excluded: excluded})
+	outcomes := result.Outcomes
+
+	ctx, cancel := context.WithTimeout(ctx, e.drainTimeout())
+	defer cancel()
+
+	for len(pending) > 0 {
+		blocked := []corev1.Pod{}
+		for _, pod := range pending {
+			key := pod.Namespace + "/" + pod.Name
+			err := e.clientset.PolicyV1().Evictions(pod.Namespace).Evict(ctx, &policyv1.Eviction{
+				ObjectMeta: metav1.ObjectMeta{Name: pod.Name, Namespace: pod.Namespace},
+			})
+			switch {
+			case err == nil || apierrors.IsNotFound(err):
+				log.Printf("Evicted pod %s from node %s", key, name)
+				outcomes[key] = OutcomeEvicted
+			case apierrors.IsTooManyRequests(err):
+				// The disruption budget does not allow this eviction yet
+				outcomes[key] = OutcomePDBBlocked
+				blocked = append(blocked, pod)
+			default:
+				log.Printf("Failed to evict pod %s: %v", key, err)
+				outcomes[key] = OutcomeEvictionFailed
+			}
+		}
+
+		pending = blocked
+		if len(pending) == 0 {
+			break
+		}
+
+		select {
+		case <-ctx.Done():
+			log.Printf("%d pods on node %s are still protected by disruption budgets", len(pending), name)
+			return nil
+		case <-time.After(evictionRetryInterval):
+		}
+	}
+
+	return nil
+}
+
+// withinBlastRadius returns the nodes, in order, whose pods the blast-radius
+// policy allows to be evicted together, and a reason for every node it leaves out
+func (e *NodeFailureExperiment) withinBlastRadius(ctx context.Context, names []string) ([]string, []string, error) {
+	if !e.blastRadius.enforced() {
+		return names, nil, nil
+	}
+
+	allowed := []string{}
+	skipped := []string{}
+	evicted := []corev1.Pod{}
+	for _, name := range names {
+		pods, _, err := e.evictablePods(ctx, name)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		candidates := append(append([]corev1.Pod{}, evicted...), pods...)
+		if err := e.blastRadius.check(ctx, e.clientset, candidates); err != nil {
+			var policyErr *BlastRadiusError
+			if !errors.As(err, &policyErr) {
+				return nil, nil, err
+			}
+			skipped = append(skipped, fmt.Sprintf("node %s left out, draining it would exceed the blast radius: %s", name, strings.Join(policyErr.Reasons, "; ")))
+			continue
+		}
+		evicted = candidates
+		allowed = append(allowed, name)
+	}
+
+	if len(allowed) == 0 {
+		return nil, skipped, &BlastRadiusError{Reasons: skipped}
+	}
+	return allowed, skipped, nil
+}
+
+// Plan reports the nodes that would be cordoned and the pods the drain would evict
+func (e *NodeFailureExperiment) Plan(ctx context.Context) (*Plan, error) {
+	names, err := e.nodes(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if err := e.checkProtected(ctx, names); err != nil {
+		return nil, err
+	}
+	names, skipped, err := e.withinBlastRadius(ctx, names)
+	if err != nil {
+		return nil, err
+	}
+
+	plan := newPlan("node-failure")
+	plan.BlastRadius = skipped
+	plan.AffectedResources = nodeResources(names)
+	evicted := []string{}
+	for _, name := range names {
+		pods, excluded, err := e.evictablePods(ctx, name)
+		if err != nil {
+			return nil, err
+		}
+		plan.leaveOut(leftOut{excluded: excluded})
+		plan.addAction("Cordon node %s and evict its %d pods, retrying evictions blocked by disruption budgets for up to %s", name, len(pods), e.drainTimeout())
+		for _, pod := range pods {
+			evicted = append(evicted, pod.Namespace+"/"+pod.Name)
+		}
+	}
+	sort.Strings(evicted)
+	plan.AffectedResources = append(plan.AffectedResources, evicted...)
+	plan.addAction("Uncordon the nodes %ds after cordoning them", e.config.Duration)
+
+	return plan, nil
+}
+
+// Run executes the node failure experiment
+func (e *NodeFailureExperiment) Run(ctx context.Context) (*ExperimentResult, error) {
+	// Create a result object
+	result := &ExperimentResult{
+		ExperimentType: "node-failure",
+		StartTime:      time.Now(),
+		Success:        false,
+		Outcomes:       map[string]string{},
+	}
+
+	result.Logf("Starting node failure experiment")
+
+	names, err := e.nodes(ctx)
+	if err != nil {
+		result.Error = err.Error()
+		return result, err
+	}
+	if err := e.checkProtected(ctx, names); err != nil {
+		result.Error = err.Error()
+		return result, err
+	}
+	names, skipped, err := e.withinBlastRadius(ctx, names)
+	if err != nil {
+		result.Error = err.Error()
+		return result, err
+	}
+	result.limitedBy(skipped)
+
+	// Cordon every node first so drained pods are not rescheduled onto the next victim
+	cordoned := []string{}
+	changes := map[string]Change{}
+	for _, name := range names {
+		change := newChange(ChangeNodeCordon, "", name, map[string]string{"token": e.token})
+		if err := recordChange(e.journal, change); err != nil {
+			result.Logf("Skipping node %s: %v", name, err)
+			continue
+		}
+		if err := e.cordon(ctx, name); err != nil {
+			result.Logf("Failed to cordon node %s: %v", name, err)
+			resolveChange(e.journal, change)
+			continue
+		}
+		result.Logf("Cordoned node %s", name)
+		cordoned = append(cordoned, name)
+		changes[name] = change
+	}
+
+	if len(cordoned) == 0 {
+		result.Error = "Failed to cordon any node"
+		return result, errors.New(result.Error)
+	}
+
+	// The nodes stay out of service for the experiment duration, including
+	// the drain, so the run ends within the deadline it was given
+	holdCtx, cancelHold := context.WithTimeout(ctx, time.Duration(e.config.Duration)*time.Second)
+	defer cancelHold()
+
+	for _, name := range cordoned {
+		if err := e.drain(holdCtx, name, result); err != nil {
+			result.Logf("Failed to drain node %s: %v", name, err)
+		}
+	}
+
+	// Report the displaced pods and the nodes taken out of service
+	for key, outcome := range result.Outcomes {
+		if outcome == OutcomeEvicted {
+			result.AffectedResources = append(result.AffectedResources, key)
+		}
+	}
+	sort.Strings(result.AffectedResources)
+	result.AffectedResources = append(nodeResources(cordoned), result.AffectedResources...)
+
+	// Keep the nodes out of service for the rest of the duration, then uncordon them even if cancelled
+	<-holdCtx.Done()
+	waitErr := ctx.Err()
+
+	uncordonCtx, cancel := context.WithTimeout(context.Background(), cleanupTimeout)
+	defer cancel()
+
+	var failures []string
+	for _, name := range cordoned {
+		if err := UncordonNode(uncordonCtx, e.clientset, name, e.token); err != nil {
+			result.Logf("Failed to uncordon node %s: %v", name, err)
+			failures = append(failures, err.Error())
+			continue
+		}
+		resolveChange(e.journal, changes[name])
+	}
+
+	result.EndTime = time.Now()
+
+	if waitErr != nil {
+		result.Error = "Experiment cancelled"
+		return result, waitErr
+	}
+
+	if len(failures) > 0 {
+		result.Error = fmt.Sprintf("Failed to uncordon nodes: %s", strings.Join(failures, "; "))
+		return result, errors.New(result.Error)
+	}
+
+	result.Success = true
+	return result, nil
+}
+
+// nodeResources formats node names as affected resources
+func nodeResources(names []string) []string {
+	resources := make([]string, 0, len(names))
+	for _, name := range names {
+		resources = append(resources, "node/"+name)
+	}
+	return resources
+}
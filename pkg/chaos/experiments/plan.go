@@ -0,0 +1,66 @@
+package experiments
+
+import (
+	"context"
+	"fmt"
+)
+
+// Plan describes what a run of an experiment would do
+type Plan struct {
+	ExperimentID      string   `json:"experiment_id,omitempty"`
+	ExperimentType    string   `json:"experiment_type"`
+	AffectedResources []string `json:"affected_resources"`
+	Actions           []string `json:"actions"`
+	Strategy          string   `json:"strategy,omitempty"`     // Victim selection strategy
+	Seed              int64    `json:"seed,omitempty"`         // Seed that reproduces the planned victim selection
+	BlastRadius       []string `json:"blast_radius,omitempty"` // What the blast-radius policy would leave out and why
+	Excluded          []string `json:"excluded,omitempty"`     // Targets left out for not opting in to chaos
+}
+
+// leftOut lists the targets an experiment leaves out
+type leftOut struct {
+	blastRadius []string // What the blast-radius policy does not allow and why
+	excluded    []string // Targets that did not opt in to chaos
+}
+
+// newPlan creates an empty plan for an experiment type
+func newPlan(experimentType string) *Plan {
+	return &Plan{
+		ExperimentType:    experimentType,
+		AffectedResources: []string{},
+		Actions:           []string{},
+	}
+}
+
+// leaveOut keeps the targets the run would leave out in the plan
+func (p *Plan) leaveOut(left leftOut) {
+	p.BlastRadius = append(p.BlastRadius, left.blastRadius...)
+	p.Excluded = append(p.Excluded, left.excluded...)
+}
+
+// addAction appends a planned action
+func (p *Plan) addAction(format string, args ...interface{}) {
+	p.Actions = append(p.Actions, fmt.Sprintf(format, args...))
+}
+
+// DryRun validates the spec, builds the experiment and plans a run of it.
+// Nothing is applied, so nothing is recorded in the journal of deps.
+func DryRun(ctx context.Context, definition Definition, deps Dependencies, spec Spec) (*Plan, error) {
+	if err := definition.Validate(spec); err != nil {
+		return nil, err
+	}
+	if err := validateProbes(spec); err != nil {
+		return nil, err
+	}
+	if err := checkTargetNamespace(ctx, definition, deps, spec); err != nil {
+		return nil, err
+	}
+
+	deps.Journal = nil
+	experiment, err := definition.New(deps, spec)
+	if err != nil {
+		return nil, err
+	}
+
+	return experiment.Plan(ctx)
+}
@@ -2,14 +2,59 @@ package experiments
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"log"
+	"math/rand"
+	"strings"
 	"time"
 
+	corev1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/client-go/kubernetes"
 )
 
+// PodFailureMode defines how a victim pod is failed
+type PodFailureMode string
+
+const (
+	// PodDelete deletes the whole pod
+	PodDelete PodFailureMode = "delete"
+
+	// ContainerKill signals the main process of a single container, leaving the pod in place
+	ContainerKill PodFailureMode = "container-kill"
+)
+
+// DefaultKillImage is the image used to signal containers when none is configured
+const DefaultKillImage = "busybox:1.36"
+
+// containerRestartTimeout is how long a killed container has to restart
+const containerRestartTimeout = 60 * time.Second
+
+// PodFailureConfig holds the settings for a pod failure experiment
+type PodFailureConfig struct {
+	Namespace   string
+	Selector    string
+	Duration    int
+	Percentage  int
+	Strategy    SelectionStrategy // How victims are picked, defaults to random
+	Seed        *int64            // Seed for random selection, generated when nil
+	Mode        PodFailureMode    // How victims are failed, defaults to PodDelete
+	GracePeriod *int64            // Deletion grace period override in seconds, 0 kills immediately
+	Container   string            // Container to kill in container-kill mode
+	Signal      string            // Signal sent in container-kill mode, defaults to KILL
+	Image       string            // Image sending the signal in container-kill mode, defaults to DefaultKillImage
+	Interval    int               // Seconds between kill rounds, 0 kills once
+}
+
+// PodKill records a single pod or container kill
+type PodKill struct {
+	Time      time.Time `json:"time"`
+	Pod       string    `json:"pod"`
+	Container string    `json:"container,omitempty"`
+	Error     string    `json:"error,omitempty"`
+}
+
 // PodFailureExperiment represents a pod failure chaos experiment
 type PodFailureExperiment struct {
 	clientset  kubernetes.Interface
@@ -17,91 +62,457 @@ type PodFailureExperiment struct {
 	selector   string
 	duration   int
 	percentage int
+	strategy   SelectionStrategy
+	seed       *int64
+	mode       PodFailureMode
+	grace      *int64
+	container  string
+	signal     string
+	image      string
+	interval   int
+
+	blastRadius *BlastRadius
+	protection  *Protection
+}
+
+func init() {
+	Register(Definition{
+		Type:        "pod-failure",
+		Description: "Delete pods or kill their containers",
+		Params: withPodParams(
+			Param{Name: "percentage", Description: "Share of matching pods to kill, 0 kills a single pod", Type: ParamInt, Unit: "%", Default: "100", Min: limit(0), Max: limit(100)},
+			Param{Name: "strategy", Description: "How victims are picked", Type: ParamString, Default: string(SelectRandom), Enum: []string{string(SelectRandom), string(SelectOldest), string(SelectNewest), string(SelectSpreadNodes), string(SelectSpreadZones)}},
+			Param{Name: "seed", Description: "Seed for random selection, to replay a run", Type: ParamInt},
+			Param{Name: "mode", Description: "delete or container-kill", Type: ParamString, Default: string(PodDelete), Enum: []string{string(PodDelete), string(ContainerKill)}},
+			Param{Name: "grace_period", Description: "Deletion grace period, 0 kills immediately", Type: ParamInt, Unit: "s", Min: limit(0)},
+			Param{Name: "container", Description: "Container to kill in container-kill mode", Type: ParamString},
+			Param{Name: "signal", Description: "Signal sent in container-kill mode", Type: ParamString, Default: "KILL"},
+			Param{Name: "image", Description: "Image providing sh, grep, awk and kill, used in container-kill mode", Type: ParamString, Default: DefaultKillImage},
+			Param{Name: "interval", Description: "Time between kill rounds, 0 kills once", Type: ParamInt, Unit: "s", Default: "0", Min: limit(0)},
+		),
+		New: func(deps Dependencies, spec Spec) (Experiment, error) {
+			if err := spec.requirePods(); err != nil {
+				return nil, err
+			}
+
+			strategy := SelectionStrategy(spec.Params["strategy"])
+			if strategy != "" && !ValidSelectionStrategy(strategy) {
+				return nil, fmt.Errorf("invalid strategy parameter: %s", strategy)
+			}
+
+			// Replaying a failure needs the exact seed, so a bad one is an error rather than a default
+			seed, err := spec.OptionalInt64("seed")
+			if err != nil {
+				return nil, err
+			}
+
+			// A grace period of 0 is a hard kill, so it must not fall back to the default either
+			gracePeriod, err := spec.OptionalInt64("grace_period")
+			if err != nil {
+				return nil, err
+			}
+
+			podFailure := NewPodFailureExperiment(deps.Clientset, PodFailureConfig{
+				Namespace:   spec.Namespace,
+				Selector:    spec.Selector,
+				Duration:    spec.Duration,
+				Percentage:  spec.Int("percentage", 100),
+				Strategy:    strategy,
+				Seed:        seed,
+				Mode:        PodFailureMode(spec.Params["mode"]),
+				GracePeriod: gracePeriod,
+				Container:   spec.Params["container"],
+				Signal:      spec.Params["signal"],
+				Image:       spec.Params["image"],
+				Interval:    spec.Int("interval", 0),
+			})
+			podFailure.blastRadius = deps.BlastRadius
+			podFailure.protection = deps.Protection
+
+			return podFailure, nil
+		},
+	})
 }
 
 // NewPodFailureExperiment creates a new pod failure experiment
-func NewPodFailureExperiment(clientset kubernetes.Interface, namespace, selector string, duration, percentage int) *PodFailureExperiment {
+func NewPodFailureExperiment(clientset kubernetes.Interface, config PodFailureConfig) *PodFailureExperiment {
+	if config.Strategy == "" {
+		config.Strategy = SelectRandom
+	}
+	if config.Mode == "" {
+		config.Mode = PodDelete
+	}
+	if config.Signal == "" {
+		config.Signal = "KILL"
+	}
+	if config.Image == "" {
+		config.Image = DefaultKillImage
+	}
+
 	return &PodFailureExperiment{
 		clientset:  clientset,
-		namespace:  namespace,
-		selector:   selector,
-		duration:   duration,
-		percentage: percentage,
+		namespace:  config.Namespace,
+		selector:   config.Selector,
+		duration:   config.Duration,
+		percentage: config.Percentage,
+		strategy:   config.Strategy,
+		seed:       config.Seed,
+		mode:       config.Mode,
+		grace:      config.GracePeriod,
+		container:  config.Container,
+		signal:     config.Signal,
+		image:      config.Image,
+		interval:   config.Interval,
 	}
 }
 
-// Run executes the pod failure experiment
-func (e *PodFailureExperiment) Run(ctx context.Context) (*ExperimentResult, error) {
-	log.Printf("Starting pod failure experiment in namespace %s with selector %s", e.namespace, e.selector)
-	
-	// Create a result object
-	result := &ExperimentResult{
-		ExperimentType: "pod-failure",
-		StartTime:      time.Now(),
-		Success:        false,
+// validate checks the configuration before any pod is touched
+func (e *PodFailureExperiment) validate() error {
+	switch e.mode {
+	case PodDelete:
+	case ContainerKill:
+		if e.container == "" {
+			return fmt.Errorf("container is required in %s mode", ContainerKill)
+		}
+		if err := validateShellValue("signal", e.signal); err != nil {
+			return err
+		}
+	default:
+		return fmt.Errorf("invalid mode: %s, must be %s or %s", e.mode, PodDelete, ContainerKill)
+	}
+
+	if e.grace != nil && *e.grace < 0 {
+		return fmt.Errorf("invalid grace period: %d, must not be negative", *e.grace)
 	}
+	if e.interval < 0 {
+		return fmt.Errorf("invalid interval: %d, must not be negative", e.interval)
+	}
+
+	return nil
+}
+
+// kill fails a single victim according to the mode
+func (e *PodFailureExperiment) kill(ctx context.Context, podName string) PodKill {
+	record := PodKill{Time: time.Now(), Pod: podName}
+
+	var err error
+	if e.mode == ContainerKill {
+		record.Container = e.container
+		log.Printf("Killing container %s in pod %s with SIG%s", e.container, podName, e.signal)
+		err = e.killContainer(ctx, podName)
+	} else {
+		log.Printf("Deleting pod %s", podName)
+		err = e.clientset.CoreV1().Pods(e.namespace).Delete(ctx, podName, metav1.DeleteOptions{
+			GracePeriodSeconds: e.grace,
+		})
+	}
+
+	if err != nil {
+		log.Printf("Failed to kill pod %s: %v", podName, err)
+		record.Error = err.Error()
+	}
+
+	return record
+}
+
+// killScript builds the script that signals the main process of a container
+// from another container in the pod's process namespace. The main process is
+// the one in the container's cgroup whose parent is outside of it.
+func killScript(containerID, signal string) string {
+	return strings.Join([]string{
+		"for DIR in /proc/[0-9]*; do",
+		fmt.Sprintf("  grep -q %s $DIR/cgroup 2>/dev/null || continue", containerID),
+		"  PARENT=$(awk '/^PPid:/ {print $2}' $DIR/status)",
+		fmt.Sprintf("  if [ \"$PARENT\" = 0 ] || ! grep -q %s /proc/$PARENT/cgroup 2>/dev/null; then", containerID),
+		fmt.Sprintf("    exec kill -%s ${DIR#/proc/}", signal),
+		"  fi",
+		"done",
+		fmt.Sprintf("echo 'no process of container %s found' >&2", containerID),
+		"exit 1",
+	}, "\n")
+}
+
+// containerStatus returns the status of the named container, or nil if it has none
+func containerStatus(pod *corev1.Pod, name string) *corev1.ContainerStatus {
+	for idx := range pod.Status.ContainerStatuses {
+		if pod.Status.ContainerStatuses[idx].Name == name {
+			return &pod.Status.ContainerStatuses[idx]
+		}
+	}
+	return nil
+}
+
+// killContainer signals the main process of the container and waits until it
+// has restarted. The kernel drops SIGKILL, and any signal without a handler,
+// sent to PID 1 from inside its own PID namespace, so the signal is sent from a
+// chaos container in the pod's shared process namespace, where the main
+// process of the container has a PID of its own.
+func (e *PodFailureExperiment) killContainer(ctx context.Context, podName string) error {
+	pod, err := e.clientset.CoreV1().Pods(e.namespace).Get(ctx, podName, metav1.GetOptions{})
+	if err != nil {
+		return fmt.Errorf("failed to get pod %s: %w", podName, err)
+	}
+	if pod.Spec.ShareProcessNamespace == nil || !*pod.Spec.ShareProcessNamespace {
+		return fmt.Errorf("pod %s does not share its process namespace, which %s mode requires", podName, ContainerKill)
+	}
+
+	status := containerStatus(pod, e.container)
+	if status == nil || status.State.Running == nil {
+		return fmt.Errorf("container %s is not running in pod %s", e.container, podName)
+	}
+	containerID := status.ContainerID
+	if idx := strings.Index(containerID, "://"); idx >= 0 {
+		containerID = containerID[idx+3:]
+	}
+	if err := validateShellValue("container ID", containerID); err != nil {
+		return err
+	}
+
+	injector := &ephemeralInjector{clientset: e.clientset, namespace: e.namespace}
+	securityContext := &corev1.SecurityContext{
+		Capabilities: &corev1.Capabilities{Add: []corev1.Capability{"KILL"}},
+	}
+	if err := injector.runToCompletion(ctx, pod, "kill", e.image, killScript(containerID, e.signal), securityContext); err != nil {
+		return err
+	}
+
+	return e.waitForRestart(ctx, podName, status.RestartCount)
+}
+
+// waitForRestart polls the pod until the container has restarted more than
+// the given number of times, or has terminated if the pod does not restart it
+func (e *PodFailureExperiment) waitForRestart(ctx context.Context, podName string, restarts int32) error {
+	ctx, cancel := context.WithTimeout(ctx, containerRestartTimeout)
+	defer cancel()
+
+	ticker := time.NewTicker(time.Second)
+	defer ticker.Stop()
+
+	for {
+		pod, err := e.clientset.CoreV1().Pods(e.namespace).Get(ctx, podName, metav1.GetOptions{})
+		if err != nil {
+			return fmt.Errorf("failed to get pod %s: %w", podName, err)
+		}
 
+		status := containerStatus(pod, e.container)
+		if status != nil && (status.RestartCount > restarts || status.State.Terminated != nil) {
+			return nil
+		}
+
+		select {
+		case <-ctx.Done():
+			return fmt.Errorf("container %s in pod %s did not restart after SIG%s", e.container, podName, e.signal)
+		case <-ticker.C:
+		}
+	}
+}
+
+// victims selects the pods to kill among the current pods that may be
+// targeted, within the blast radius left by the pods already killed. It also
+// returns the pods it left out.
+func (e *PodFailureExperiment) victims(ctx context.Context, rng *rand.Rand, killed affectedPods) ([]corev1.Pod, leftOut, error) {
 	// Get pods matching the selector
 	pods, err := e.clientset.CoreV1().Pods(e.namespace).List(ctx, metav1.ListOptions{
 		LabelSelector: e.selector,
 	})
 	if err != nil {
-		result.Error = fmt.Sprintf("Failed to list pods: %v", err)
-		return result, err
+		return nil, leftOut{}, fmt.Errorf("Failed to list pods: %v", err)
+	}
+
+	// Skip pods that are already going away from an earlier round
+	candidates := []corev1.Pod{}
+	for _, pod := range pods.Items {
+		if pod.DeletionTimestamp == nil {
+			candidates = append(candidates, pod)
+		}
+	}
+
+	if len(candidates) == 0 {
+		return nil, leftOut{}, errors.New("No pods found matching the selector")
 	}
 
-	if len(pods.Items) == 0 {
-		result.Error = "No pods found matching the selector"
-		return result, fmt.Errorf(result.Error)
+	var left leftOut
+	candidates, left.excluded, err = e.protection.filterPods(ctx, e.clientset, candidates)
+	if err != nil {
+		return nil, leftOut{}, err
+	}
+	if len(candidates) == 0 {
+		return nil, left, errors.New("No pods matching the selector opted in to chaos")
 	}
 
-	// Calculate how many pods to delete
+	// Calculate how many pods to kill
 	count := 1
 	if e.percentage > 0 {
-		count = (len(pods.Items) * e.percentage) / 100
+		count = (len(candidates) * e.percentage) / 100
 		if count < 1 {
 			count = 1
 		}
-		if count > len(pods.Items) {
-			count = len(pods.Items)
+		if count > len(candidates) {
+			count = len(candidates)
 		}
 	}
 
-	// Delete the pods
-	deletedPods := []string{}
-	for i := 0; i < count; i++ {
-		podName := pods.Items[i].Name
-		log.Printf("Deleting pod %s", podName)
-		
-		err := e.clientset.CoreV1().Pods(e.namespace).Delete(ctx, podName, metav1.DeleteOptions{})
-		if err != nil {
-			log.Printf("Failed to delete pod %s: %v", podName, err)
-			continue
+	victims, err := selectPods(ctx, e.clientset, candidates, count, e.strategy, rng)
+	if err != nil {
+		return nil, leftOut{}, fmt.Errorf("Failed to select pods: %v", err)
+	}
+
+	victims, left.blastRadius, err = e.blastRadius.limit(ctx, e.clientset, victims, killed)
+	return victims, left, err
+}
+
+// killRound selects victims among the current pods and kills them, adding
+// them to the pods killed so far. The pods it left out are kept in the result.
+func (e *PodFailureExperiment) killRound(ctx context.Context, rng *rand.Rand, killed affectedPods, result *ExperimentResult) ([]PodKill, error) {
+	victims, left, err := e.victims(ctx, rng, killed)
+	result.leaveOut(left)
+	if err != nil {
+		return nil, err
+	}
+
+	kills := make([]PodKill, 0, len(victims))
+	for i := range victims {
+		kill := e.kill(ctx, victims[i].Name)
+		if kill.Error == "" {
+			killed.add(&victims[i])
 		}
-		
-		deletedPods = append(deletedPods, podName)
+		kills = append(kills, kill)
 	}
 
-	// Record the affected pods
-	result.AffectedResources = deletedPods
+	return kills, nil
+}
 
-	// Wait for the specified duration
-	select {
-	case <-time.After(time.Duration(e.duration) * time.Second):
-		// Experiment completed successfully
-	case <-ctx.Done():
-		// Experiment was cancelled
-		result.Error = "Experiment cancelled"
-		return result, ctx.Err()
+// selectionSeed returns the configured seed, or a new one
+func (e *PodFailureExperiment) selectionSeed() int64 {
+	if e.seed != nil {
+		return *e.seed
+	}
+	return time.Now().UnixNano()
+}
+
+// Plan reports the pods the first kill round would pick. Without a
+// configured seed a run picks its own, so passing the planned seed is the
+// only way to kill exactly the planned pods.
+func (e *PodFailureExperiment) Plan(ctx context.Context) (*Plan, error) {
+	if err := e.validate(); err != nil {
+		return nil, err
 	}
 
+	plan := newPlan("pod-failure")
+	plan.Strategy = string(e.strategy)
+	plan.Seed = e.selectionSeed()
+
+	victims, left, err := e.victims(ctx, rand.New(rand.NewSource(plan.Seed)), nil)
+	if err != nil {
+		return nil, err
+	}
+	plan.leaveOut(left)
+
+	for _, pod := range victims {
+		plan.AffectedResources = append(plan.AffectedResources, pod.Name)
+		switch {
+		case e.mode == ContainerKill && (pod.Spec.ShareProcessNamespace == nil || !*pod.Spec.ShareProcessNamespace):
+			plan.addAction("Skip pod %s: it does not share its process namespace, which %s mode requires", pod.Name, ContainerKill)
+		case e.mode == ContainerKill:
+			plan.addAction("Attach a chaos-kill container running %s to pod %s to send SIG%s to container %s, and wait for it to restart", e.image, pod.Name, e.signal, e.container)
+		case e.grace != nil:
+			plan.addAction("Delete pod %s with a grace period of %ds", pod.Name, *e.grace)
+		default:
+			plan.addAction("Delete pod %s", pod.Name)
+		}
+	}
+	if e.interval > 0 {
+		plan.addAction("Repeat every %ds for %ds, picking new victims among the pods present at the time", e.interval, e.duration)
+	}
+
+	return plan, nil
+}
+
+// Run executes the pod failure experiment
+func (e *PodFailureExperiment) Run(ctx context.Context) (*ExperimentResult, error) {
+	// Create a result object
+	result := &ExperimentResult{
+		ExperimentType: "pod-failure",
+		StartTime:      time.Now(),
+		Success:        false,
+	}
+
+	result.Logf("Starting pod failure experiment in namespace %s with selector %s", e.namespace, e.selector)
+
+	if err := e.validate(); err != nil {
+		result.Error = err.Error()
+		return result, err
+	}
+
+	// Pick the victims, recording the seed so the selection can be replayed
+	seed := e.selectionSeed()
+	result.Seed = seed
+	result.Strategy = string(e.strategy)
+	rng := rand.New(rand.NewSource(seed))
+
+	// The first round must find pods, later rounds may find none while pods
+	// restart. Every round counts against the blast radius of the whole run.
+	killed := affectedPods{}
+	kills, err := e.killRound(ctx, rng, killed, result)
+	if err != nil {
+		result.Error = err.Error()
+		return result, err
+	}
+	result.Kills = append(result.Kills, kills...)
+
+	// Keep killing every interval, or just wait, for the specified duration
+	deadline := time.After(time.Duration(e.duration) * time.Second)
+	var tick <-chan time.Time
+	if e.interval > 0 {
+		ticker := time.NewTicker(time.Duration(e.interval) * time.Second)
+		defer ticker.Stop()
+		tick = ticker.C
+	}
+
+	for waiting := true; waiting; {
+		select {
+		case <-deadline:
+			// Experiment completed successfully
+			waiting = false
+		case <-tick:
+			kills, err := e.killRound(ctx, rng, killed, result)
+			if err != nil {
+				result.Logf("Kill round skipped: %v", err)
+				continue
+			}
+			result.Kills = append(result.Kills, kills...)
+		case <-ctx.Done():
+			// Experiment was cancelled
+			result.AffectedResources = killedPods(result.Kills)
+			result.Error = "Experiment cancelled"
+			return result, ctx.Err()
+		}
+	}
+
+	// Record the affected pods
+	result.AffectedResources = killedPods(result.Kills)
+
 	// Set end time
 	result.EndTime = time.Now()
 	
-	// Set success if we deleted at least one pod
-	if len(deletedPods) > 0 {
+	// Set success if we killed at least one pod
+	if len(result.AffectedResources) > 0 {
 		result.Success = true
 	}
 
 	return result, nil
-}
\ No newline at end of file
+}
+
+// killedPods returns the distinct pods that were killed successfully, in kill order
+func killedPods(kills []PodKill) []string {
+	seen := map[string]bool{}
+	pods := []string{}
+	for _, kill := range kills {
+		if kill.Error != "" || seen[kill.Pod] {
+			continue
+		}
+		seen[kill.Pod] = true
+		pods = append(pods, kill.Pod)
+	}
+	return pods
+}
@@ -0,0 +1,324 @@
+package experiments
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+
+	appsv1 "k8s.io/api/apps/v1"
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+)
+
+// ProtectedLabel opts a namespace, workload, pod, service or node out of
+// chaos when set to "true" as a label or an annotation
+const ProtectedLabel = "chaos.platform/protected"
+
+// OptInAnnotation makes a namespace or an object in it eligible for chaos
+// when set to "true" and the platform runs in opt-in mode
+const OptInAnnotation = "chaos.platform/enabled"
+
+// Protection lists the namespaces no experiment may touch. Resources opted
+// out with ProtectedLabel are protected as well, whether or not a deny-list
+// is configured. In opt-in mode only the objects that carry OptInAnnotation,
+// themselves or on their namespace, may be targeted.
+type Protection struct {
+	Namespaces []string `json:"namespaces"`
+	OptIn      bool     `json:"opt_in"`
+}
+
+// DefaultProtection is the deny-list used when the platform configures none
+var DefaultProtection = Protection{Namespaces: []string{"kube-system", "kube-public", "kube-node-lease"}}
+
+// ProtectedError reports that an experiment targets protected resources
+type ProtectedError struct {
+	Resources []string
+}
+
+func (e *ProtectedError) Error() string {
+	return "Targets resources protected from chaos: " + strings.Join(e.Resources, ", ")
+}
+
+// isProtected reports whether the object is opted out of chaos
+func isProtected(meta *metav1.ObjectMeta) bool {
+	return meta.Labels[ProtectedLabel] == "true" || meta.Annotations[ProtectedLabel] == "true"
+}
+
+// protectedError returns an error for the protected resources, or nil if there are none
+func protectedError(resources []string) error {
+	if len(resources) == 0 {
+		return nil
+	}
+	sort.Strings(resources)
+	return &ProtectedError{Resources: resources}
+}
+
+// namespaceProtected reports whether the namespace is on the deny-list or opted out
+func (p *Protection) namespaceProtected(ctx context.Context, clientset kubernetes.Interface, namespace string) (bool, error) {
+	if p != nil {
+		for _, denied := range p.Namespaces {
+			if denied == namespace {
+				return true, nil
+			}
+		}
+	}
+
+	object, err := clientset.CoreV1().Namespaces().Get(ctx, namespace, metav1.GetOptions{})
+	if apierrors.IsNotFound(err) {
+		return false, nil
+	}
+	if err != nil {
+		return false, fmt.Errorf("failed to get namespace %s: %w", namespace, err)
+	}
+	return isProtected(&object.ObjectMeta), nil
+}
+
+// checkNamespace rejects an experiment in a protected namespace
+func (p *Protection) checkNamespace(ctx context.Context, clientset kubernetes.Interface, namespace string) error {
+	if namespace == "" {
+		return nil
+	}
+
+	protected, err := p.namespaceProtected(ctx, clientset, namespace)
+	if err != nil {
+		return err
+	}
+	if protected {
+		return protectedError([]string{"namespace " + namespace})
+	}
+	return nil
+}
+
+// checkTargetNamespace rejects an experiment of a type that acts within a
+// namespace if that namespace is protected. The types that act on the pods of
+// every namespace, like node-failure, check the pods instead.
+func checkTargetNamespace(ctx context.Context, definition Definition, deps Dependencies, spec Spec) error {
+	if !definition.namespaced() {
+		return nil
+	}
+	return deps.Protection.checkNamespace(ctx, deps.Clientset, spec.Namespace)
+}
+
+// checkObjects rejects an experiment that acts on opted-out objects of a kind
+func (p *Protection) checkObjects(kind string, objects []*metav1.ObjectMeta) error {
+	resources := []string{}
+	for _, meta := range objects {
+		if isProtected(meta) {
+			resources = append(resources, resourceName(kind, meta))
+		}
+	}
+	return protectedError(resources)
+}
+
+// resourceName formats an object as kind namespace/name, or kind name if it is cluster-scoped
+func resourceName(kind string, meta *metav1.ObjectMeta) string {
+	if meta.Namespace == "" {
+		return kind + " " + meta.Name
+	}
+	return kind + " " + meta.Namespace + "/" + meta.Name
+}
+
+// optedIn reports whether the object or its namespace carries the opt-in
+// annotation, caching the namespaces it looked up
+func optedIn(ctx context.Context, clientset kubernetes.Interface, meta *metav1.ObjectMeta, namespaces map[string]bool) (bool, error) {
+	if meta.Annotations[OptInAnnotation] == "true" {
+		return true, nil
+	}
+	if meta.Namespace == "" {
+		return false, nil
+	}
+
+	namespaceOptedIn, checked := namespaces[meta.Namespace]
+	if !checked {
+		object, err := clientset.CoreV1().Namespaces().Get(ctx, meta.Namespace, metav1.GetOptions{})
+		if err != nil && !apierrors.IsNotFound(err) {
+			return false, fmt.Errorf("failed to get namespace %s: %w", meta.Namespace, err)
+		}
+		namespaceOptedIn = err == nil && object.Annotations[OptInAnnotation] == "true"
+		namespaces[meta.Namespace] = namespaceOptedIn
+	}
+	return namespaceOptedIn, nil
+}
+
+// filterObjects rejects an experiment that acts on protected objects of a
+// kind and, in opt-in mode, leaves out the objects that did not opt in. It
+// returns the names of the objects to act on and the ones it excluded.
+func (p *Protection) filterObjects(ctx context.Context, clientset kubernetes.Interface, kind string, objects []*metav1.ObjectMeta) ([]string, []string, error) {
+	if err := p.checkObjects(kind, objects); err != nil {
+		return nil, nil, err
+	}
+
+	names := []string{}
+	excluded := []string{}
+	namespaces := map[string]bool{}
+	for _, meta := range objects {
+		if p != nil && p.OptIn {
+			eligible, err := optedIn(ctx, clientset, meta, namespaces)
+			if err != nil {
+				return nil, nil, err
+			}
+			if !eligible {
+				excluded = append(excluded, resourceName(kind, meta))
+				continue
+			}
+		}
+		names = append(names, meta.Name)
+	}
+	return names, excluded, nil
+}
+
+// workloadMeta returns the kind and metadata of the workload that controls a
+// pod, following a ReplicaSet up to its Deployment, or nil if it is unknown
+func workloadMeta(ctx context.Context, clientset kubernetes.Interface, namespace string, owner *metav1.OwnerReference) (string, *metav1.ObjectMeta, error) {
+	var meta *metav1.ObjectMeta
+	var err error
+	switch owner.Kind {
+	case "ReplicaSet":
+		var replicaSet *appsv1.ReplicaSet
+		if replicaSet, err = clientset.AppsV1().ReplicaSets(namespace).Get(ctx, owner.Name, metav1.GetOptions{}); err == nil {
+			if parent := metav1.GetControllerOf(replicaSet); parent != nil && parent.Kind == "Deployment" {
+				return workloadMeta(ctx, clientset, namespace, parent)
+			}
+			meta = &replicaSet.ObjectMeta
+		}
+	case "Deployment":
+		var deployment *appsv1.Deployment
+		if deployment, err = clientset.AppsV1().Deployments(namespace).Get(ctx, owner.Name, metav1.GetOptions{}); err == nil {
+			meta = &deployment.ObjectMeta
+		}
+	case "StatefulSet":
+		var statefulSet *appsv1.StatefulSet
+		if statefulSet, err = clientset.AppsV1().StatefulSets(namespace).Get(ctx, owner.Name, metav1.GetOptions{}); err == nil {
+			meta = &statefulSet.ObjectMeta
+		}
+	case "DaemonSet":
+		var daemonSet *appsv1.DaemonSet
+		if daemonSet, err = clientset.AppsV1().DaemonSets(namespace).Get(ctx, owner.Name, metav1.GetOptions{}); err == nil {
+			meta = &daemonSet.ObjectMeta
+		}
+	case "Job":
+		var job *batchv1.Job
+		if job, err = clientset.BatchV1().Jobs(namespace).Get(ctx, owner.Name, metav1.GetOptions{}); err == nil {
+			meta = &job.ObjectMeta
+		}
+	default:
+		return "", nil, nil
+	}
+
+	// A workload that is gone cannot opt out, the pod is checked on its own
+	if apierrors.IsNotFound(err) {
+		return "", nil, nil
+	}
+	if err != nil {
+		return "", nil, fmt.Errorf("failed to get %s %s: %w", strings.ToLower(owner.Kind), owner.Name, err)
+	}
+	return strings.ToLower(owner.Kind), meta, nil
+}
+
+// protectedPods returns the pods that are protected themselves or through
+// their namespace or workload, keyed by namespace/name, with the resource
+// that protects them
+func (p *Protection) protectedPods(ctx context.Context, clientset kubernetes.Interface, pods []corev1.Pod) (map[string]string, error) {
+	protected := map[string]string{}
+	namespaces := map[string]bool{}
+	workloads := map[string]string{} // Owner to the protecting workload, empty if it is not protected
+	for i := range pods {
+		pod := &pods[i]
+		key := pod.Namespace + "/" + pod.Name
+
+		namespaceProtected, checked := namespaces[pod.Namespace]
+		if !checked {
+			var err error
+			if namespaceProtected, err = p.namespaceProtected(ctx, clientset, pod.Namespace); err != nil {
+				return nil, err
+			}
+			namespaces[pod.Namespace] = namespaceProtected
+		}
+		if namespaceProtected {
+			protected[key] = "namespace " + pod.Namespace
+			continue
+		}
+
+		if isProtected(&pod.ObjectMeta) {
+			protected[key] = "pod " + key
+			continue
+		}
+
+		owner := metav1.GetControllerOf(pod)
+		if owner == nil {
+			continue
+		}
+		ownerKey := owner.Kind + "/" + pod.Namespace + "/" + owner.Name
+		resource, checked := workloads[ownerKey]
+		if !checked {
+			kind, meta, err := workloadMeta(ctx, clientset, pod.Namespace, owner)
+			if err != nil {
+				return nil, err
+			}
+			if meta != nil && isProtected(meta) {
+				resource = kind + " " + pod.Namespace + "/" + meta.Name
+			}
+			workloads[ownerKey] = resource
+		}
+		if resource != "" {
+			protected[key] = resource
+		}
+	}
+	return protected, nil
+}
+
+// checkPods rejects an experiment that acts on protected pods
+func (p *Protection) checkPods(ctx context.Context, clientset kubernetes.Interface, pods []corev1.Pod) error {
+	protected, err := p.protectedPods(ctx, clientset, pods)
+	if err != nil {
+		return err
+	}
+
+	seen := map[string]bool{}
+	resources := []string{}
+	for _, resource := range protected {
+		if !seen[resource] {
+			seen[resource] = true
+			resources = append(resources, resource)
+		}
+	}
+	return protectedError(resources)
+}
+
+// filterPods rejects an experiment that acts on protected pods and, in opt-in
+// mode, leaves out the pods that did not opt in. It returns the pods to act on
+// and the ones it excluded.
+func (p *Protection) filterPods(ctx context.Context, clientset kubernetes.Interface, pods []corev1.Pod) ([]corev1.Pod, []string, error) {
+	if err := p.checkPods(ctx, clientset, pods); err != nil {
+		return nil, nil, err
+	}
+	return p.optedInPods(ctx, clientset, pods)
+}
+
+// optedInPods returns the pods that may be targeted in opt-in mode and the
+// ones it excluded. Outside opt-in mode every pod may be targeted.
+func (p *Protection) optedInPods(ctx context.Context, clientset kubernetes.Interface, pods []corev1.Pod) ([]corev1.Pod, []string, error) {
+	if p == nil || !p.OptIn {
+		return pods, nil, nil
+	}
+
+	eligible := []corev1.Pod{}
+	excluded := []string{}
+	namespaces := map[string]bool{}
+	for i := range pods {
+		ok, err := optedIn(ctx, clientset, &pods[i].ObjectMeta, namespaces)
+		if err != nil {
+			return nil, nil, err
+		}
+		if ok {
+			eligible = append(eligible, pods[i])
+		} else {
+			excluded = append(excluded, resourceName("pod", &pods[i].ObjectMeta))
+		}
+	}
+	return eligible, excluded, nil
+}
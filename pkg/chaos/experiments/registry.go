@@ -0,0 +1,303 @@
+package experiments
+
+import (
+	"fmt"
+	"log"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+
+	"k8s.io/client-go/kubernetes"
+
+	"github.com/flack/chaos-engineering-as-a-platform/pkg/chaos/probes"
+)
+
+// Dependencies holds the clients an experiment factory can build on
+type Dependencies struct {
+	Clientset kubernetes.Interface
+	Executor  PodExecutor
+	Journal   Journal // Records applied changes for recovery, may be nil
+
+	// BlastRadius limits what the experiment may affect, may be nil
+	BlastRadius *BlastRadius
+
+	// Protection denies namespaces to the experiment, may be nil. Resources
+	// opted out with ProtectedLabel are protected either way.
+	Protection *Protection
+}
+
+// Spec is the resolved input an experiment is built from
+type Spec struct {
+	Namespace string
+	Selector  string
+	Duration  int
+	Params    map[string]string
+	Probes    []probes.Probe          // Steady-state hypothesis checked before, during and after the run
+	Abort     *probes.AbortConditions // Conditions that halt the run early
+
+	// Targeted reports that Namespace and Selector come from a stored target,
+	// which takes precedence over parameters naming a single object
+	Targeted bool
+}
+
+// Int returns an integer parameter or the default when it is missing or invalid
+func (s Spec) Int(name string, defaultValue int) int {
+	valueStr, ok := s.Params[name]
+	if !ok || valueStr == "" {
+		return defaultValue
+	}
+
+	value, err := strconv.Atoi(valueStr)
+	if err != nil || value < 0 {
+		log.Printf("Invalid %s parameter: %q, using default", name, valueStr)
+		return defaultValue
+	}
+
+	return value
+}
+
+// OptionalInt64 returns an integer parameter, or nil when it is not set
+func (s Spec) OptionalInt64(name string) (*int64, error) {
+	valueStr, ok := s.Params[name]
+	if !ok || valueStr == "" {
+		return nil, nil
+	}
+
+	value, err := strconv.ParseInt(valueStr, 10, 64)
+	if err != nil {
+		return nil, fmt.Errorf("invalid %s parameter: %w", name, err)
+	}
+
+	return &value, nil
+}
+
+// requirePods checks that the spec identifies the pods to act on
+func (s Spec) requirePods() error {
+	if s.Namespace == "" {
+		return fmt.Errorf("missing required parameter: namespace")
+	}
+	if s.Selector == "" {
+		return fmt.Errorf("missing required parameter: selector")
+	}
+	return nil
+}
+
+// value returns a parameter, preferring the resolved namespace and selector
+func (s Spec) value(name string) string {
+	switch {
+	case name == "namespace" && s.Namespace != "":
+		return s.Namespace
+	case name == "selector" && s.Selector != "":
+		return s.Selector
+	}
+	return s.Params[name]
+}
+
+// ParamType is the type of value a parameter takes
+type ParamType string
+
+const (
+	ParamString ParamType = "string"
+	ParamInt    ParamType = "integer"
+)
+
+// Param describes a parameter accepted by an experiment type
+type Param struct {
+	Name        string    `json:"name"`
+	Description string    `json:"description"`
+	Type        ParamType `json:"type"`
+	Unit        string    `json:"unit,omitempty"` // Unit of an integer value, such as ms or MB
+	Default     string    `json:"default,omitempty"`
+	Required    bool      `json:"required,omitempty"`
+	Enum        []string  `json:"enum,omitempty"` // Allowed values, any value when empty
+	Min         *int64    `json:"min,omitempty"`
+	Max         *int64    `json:"max,omitempty"`
+}
+
+// limit returns a bound for Param.Min or Param.Max
+func limit(value int64) *int64 {
+	return &value
+}
+
+// validate checks a single parameter value against the schema
+func (p Param) validate(value string) string {
+	if len(p.Enum) > 0 {
+		for _, allowed := range p.Enum {
+			if value == allowed {
+				return ""
+			}
+		}
+		return fmt.Sprintf("must be one of %s", strings.Join(p.Enum, ", "))
+	}
+
+	if p.Type != ParamInt {
+		return ""
+	}
+
+	number, err := strconv.ParseInt(value, 10, 64)
+	if err != nil {
+		return "must be an integer"
+	}
+	if p.Min != nil && number < *p.Min {
+		return fmt.Sprintf("must be at least %d%s", *p.Min, p.Unit)
+	}
+	if p.Max != nil && number > *p.Max {
+		return fmt.Sprintf("must be at most %d%s", *p.Max, p.Unit)
+	}
+	return ""
+}
+
+// FieldError describes an invalid field of an experiment
+type FieldError struct {
+	Field   string `json:"field"`
+	Message string `json:"message"`
+}
+
+// ValidationError lists every invalid field of an experiment
+type ValidationError []FieldError
+
+// Error implements error
+func (e ValidationError) Error() string {
+	messages := make([]string, len(e))
+	for i, fieldErr := range e {
+		messages[i] = fieldErr.Field + " " + fieldErr.Message
+	}
+	return "invalid parameters: " + strings.Join(messages, "; ")
+}
+
+// Factory builds an experiment from a spec
+type Factory func(deps Dependencies, spec Spec) (Experiment, error)
+
+// Definition describes an experiment type that can be registered
+type Definition struct {
+	Type        string      `json:"type"`
+	Description string      `json:"description"`
+	TargetType  string      `json:"target_type,omitempty"` // Stored target type the targets are resolved from, empty uses parameters only
+	Params      []Param     `json:"params"`
+	New         Factory     `json:"-"`
+	Recover     RecoverFunc `json:"-"` // Undoes journaled changes, nil when the type changes nothing that can be undone
+}
+
+var (
+	registryMu  sync.RWMutex
+	definitions = map[string]Definition{}
+)
+
+// Register makes an experiment type available to the executor and the
+// operator. It is meant to be called from init functions and panics if the
+// definition is incomplete or the type is already registered.
+func Register(definition Definition) {
+	registryMu.Lock()
+	defer registryMu.Unlock()
+
+	if definition.Type == "" {
+		panic("experiments: Register called with an empty type")
+	}
+	if definition.New == nil {
+		panic("experiments: Register called with a nil factory for " + definition.Type)
+	}
+	if _, exists := definitions[definition.Type]; exists {
+		panic("experiments: Register called twice for " + definition.Type)
+	}
+
+	// Parameters without a type take any string
+	params := make([]Param, len(definition.Params))
+	for i, param := range definition.Params {
+		if param.Type == "" {
+			param.Type = ParamString
+		}
+		params[i] = param
+	}
+	definition.Params = params
+
+	definitions[definition.Type] = definition
+}
+
+// Validate checks the parameters of a spec against the schema of the
+// experiment type and returns a ValidationError listing every invalid one.
+// The resolved namespace and selector count as set parameters, so a stored
+// target satisfies them.
+func (d Definition) Validate(spec Spec) error {
+	known := make(map[string]bool, len(d.Params))
+	var errs ValidationError
+
+	for _, param := range d.Params {
+		known[param.Name] = true
+
+		value := spec.value(param.Name)
+		if value == "" {
+			if param.Required {
+				errs = append(errs, FieldError{Field: param.Name, Message: "is required"})
+			}
+			continue
+		}
+
+		if message := param.validate(value); message != "" {
+			errs = append(errs, FieldError{Field: param.Name, Message: message})
+		}
+	}
+
+	// A misspelled parameter would otherwise be silently ignored
+	names := make([]string, 0, len(spec.Params))
+	for name := range spec.Params {
+		if !known[name] {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		errs = append(errs, FieldError{Field: name, Message: "is not a parameter of " + d.Type})
+	}
+
+	if len(errs) > 0 {
+		return errs
+	}
+	return nil
+}
+
+// namespaced reports whether the experiment type acts within a namespace
+func (d Definition) namespaced() bool {
+	for _, param := range d.Params {
+		if param.Name == "namespace" {
+			return true
+		}
+	}
+	return false
+}
+
+// Lookup returns the definition registered for an experiment type
+func Lookup(experimentType string) (Definition, bool) {
+	registryMu.RLock()
+	defer registryMu.RUnlock()
+
+	definition, exists := definitions[experimentType]
+	return definition, exists
+}
+
+// Definitions returns every registered experiment type, sorted by type
+func Definitions() []Definition {
+	registryMu.RLock()
+	defer registryMu.RUnlock()
+
+	result := make([]Definition, 0, len(definitions))
+	for _, definition := range definitions {
+		result = append(result, definition)
+	}
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].Type < result[j].Type
+	})
+
+	return result
+}
+
+// podParams are the parameters shared by experiments that act on pods
+var podParams = []Param{
+	{Name: "namespace", Description: "Namespace of the target pods", Type: ParamString, Required: true},
+	{Name: "selector", Description: "Label selector for the target pods", Type: ParamString, Required: true},
+}
+
+// withPodParams prepends the shared pod parameters to type-specific ones
+func withPodParams(params ...Param) []Param {
+	return append(append([]Param{}, podParams...), params...)
+}
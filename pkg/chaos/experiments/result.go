@@ -1,13 +1,18 @@
 package experiments
 
 import (
+	"fmt"
+	"log"
 	"time"
+
+	"github.com/flack/chaos-engineering-as-a-platform/pkg/chaos/probes"
 )
 
 // ExperimentResult represents the result of a chaos experiment
 type ExperimentResult struct {
 	ID                string    `json:"id"`
 	ExperimentID      string    `json:"experiment_id"`
+	RunID             string    `json:"run_id,omitempty"`
 	ExperimentType    string    `json:"experiment_type"`
 	StartTime         time.Time `json:"start_time"`
 	EndTime           time.Time `json:"end_time"`
@@ -16,6 +21,22 @@ type ExperimentResult struct {
 	Error             string    `json:"error,omitempty"`
 	AffectedResources []string  `json:"affected_resources,omitempty"`
 	Metrics           map[string]float64 `json:"metrics,omitempty"`
+	Outcomes          map[string]string  `json:"outcomes,omitempty"` // Per-resource outcome, e.g. oom-killed or evicted
+	Strategy          string             `json:"strategy,omitempty"` // Victim selection strategy
+	Seed              int64              `json:"seed,omitempty"`     // Seed that reproduces the victim selection
+	Kills             []PodKill          `json:"kills,omitempty"`    // Every pod or container kill in order
+	Logs              []string           `json:"logs,omitempty"`     // Timestamped log lines of the run
+	Hypothesis        *probes.Report     `json:"hypothesis,omitempty"` // Steady-state verdict and probe samples
+	Aborted           *Abort             `json:"aborted,omitempty"`    // Why and when an abort condition halted the run
+	BlastRadius       []string           `json:"blast_radius,omitempty"` // What the blast-radius policy left out and why
+	Excluded          []string           `json:"excluded,omitempty"`     // Targets left out for not opting in to chaos
+}
+
+// Logf logs a message and keeps it in the result's logs
+func (r *ExperimentResult) Logf(format string, args ...interface{}) {
+	message := fmt.Sprintf(format, args...)
+	log.Print(message)
+	r.Logs = append(r.Logs, time.Now().UTC().Format(time.RFC3339)+" "+message)
 }
 
 // CalculateDuration calculates the duration of the experiment
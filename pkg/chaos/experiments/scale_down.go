@@ -0,0 +1,501 @@
+package experiments
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/util/retry"
+)
+
+// WorkloadKind defines the kind of workload a scale-down experiment acts on
+type WorkloadKind string
+
+const (
+	KindDeployment  WorkloadKind = "deployment"
+	KindStatefulSet WorkloadKind = "statefulset"
+)
+
+const (
+	// OriginalReplicasAnnotation stores the replica count of a scaled-down workload so it can be restored
+	OriginalReplicasAnnotation = "chaos.platform/original-replicas"
+
+	// ScaledByAnnotation carries the token of the experiment that scaled a workload down
+	ScaledByAnnotation = "chaos.platform/scaled-by"
+
+	// unsetReplicas is saved as the original replica count of workloads that left it unset
+	unsetReplicas = "unset"
+)
+
+// ScaleDownConfig holds the settings for a scale-down experiment
+type ScaleDownConfig struct {
+	Namespace  string
+	Kind       WorkloadKind // Workload kind, defaults to deployment
+	Name       string       // Name of a single workload to scale down
+	Selector   string       // Label selector for the workloads, used when Name is empty
+	Replicas   *int32       // Replica count to scale down to
+	Percentage int          // Share of replicas to remove when Replicas is nil
+	Duration   int
+}
+
+// ScaleDownExperiment scales workloads down and restores their original replica count
+type ScaleDownExperiment struct {
+	clientset kubernetes.Interface
+	config    ScaleDownConfig
+	token     string
+	journal   Journal
+
+	blastRadius *BlastRadius
+	protection  *Protection
+}
+
+func init() {
+	Register(Definition{
+		Type:        "scale-down",
+		Description: "Scale Deployments or StatefulSets down and restore their replica count",
+		TargetType:  "deployment",
+		Params: []Param{
+			{Name: "namespace", Description: "Namespace of the workloads", Type: ParamString, Required: true},
+			{Name: "kind", Description: "deployment or statefulset", Type: ParamString, Default: string(KindDeployment), Enum: []string{string(KindDeployment), string(KindStatefulSet)}},
+			{Name: "name", Description: "Name of a single workload to scale down", Type: ParamString},
+			{Name: "selector", Description: "Label selector for the workloads, used when name is empty", Type: ParamString},
+			{Name: "replicas", Description: "Replica count to scale down to", Type: ParamInt, Min: limit(0)},
+			{Name: "percentage", Description: "Share of replicas to remove when replicas is not set", Type: ParamInt, Unit: "%", Default: "50", Min: limit(1), Max: limit(100)},
+		},
+		New: func(deps Dependencies, spec Spec) (Experiment, error) {
+			replicas, err := spec.OptionalInt64("replicas")
+			if err != nil {
+				return nil, err
+			}
+
+			config := ScaleDownConfig{
+				Namespace:  spec.Namespace,
+				Kind:       WorkloadKind(spec.Params["kind"]),
+				Selector:   spec.Selector,
+				Percentage: spec.Int("percentage", 50),
+				Duration:   spec.Duration,
+			}
+			if !spec.Targeted {
+				config.Name = spec.Params["name"]
+			}
+			if replicas != nil {
+				count := int32(*replicas)
+				config.Replicas = &count
+			}
+
+			if config.Namespace == "" {
+				return nil, fmt.Errorf("missing required parameter: namespace")
+			}
+			if config.Name == "" && config.Selector == "" {
+				return nil, fmt.Errorf("missing required parameter: name or selector")
+			}
+
+			scaleDown := NewScaleDownExperiment(deps.Clientset, config)
+			scaleDown.journal = deps.Journal
+			scaleDown.blastRadius = deps.BlastRadius
+			scaleDown.protection = deps.Protection
+
+			return scaleDown, nil
+		},
+		Recover: recoverReplicas,
+	})
+}
+
+// NewScaleDownExperiment creates a new scale-down experiment
+func NewScaleDownExperiment(clientset kubernetes.Interface, config ScaleDownConfig) *ScaleDownExperiment {
+	if config.Kind == "" {
+		config.Kind = KindDeployment
+	}
+
+	return &ScaleDownExperiment{
+		clientset: clientset,
+		config:    config,
+		token:     uuid.New().String()[:8],
+	}
+}
+
+// updateReplicas applies mutate to the metadata and replica count of a workload and saves it
+func updateReplicas(ctx context.Context, clientset kubernetes.Interface, namespace string, kind WorkloadKind, name string, mutate func(meta *metav1.ObjectMeta, replicas **int32) error) error {
+	return retry.RetryOnConflict(retry.DefaultRetry, func() error {
+		switch kind {
+		case KindDeployment:
+			deployment, err := clientset.AppsV1().Deployments(namespace).Get(ctx, name, metav1.GetOptions{})
+			if err != nil {
+				return fmt.Errorf("failed to get deployment %s: %w", name, err)
+			}
+			replicas := deployment.Spec.Replicas
+			if err := mutate(&deployment.ObjectMeta, &replicas); err != nil {
+				return err
+			}
+			deployment.Spec.Replicas = replicas
+			_, err = clientset.AppsV1().Deployments(namespace).Update(ctx, deployment, metav1.UpdateOptions{})
+			return err
+		case KindStatefulSet:
+			statefulSet, err := clientset.AppsV1().StatefulSets(namespace).Get(ctx, name, metav1.GetOptions{})
+			if err != nil {
+				return fmt.Errorf("failed to get statefulset %s: %w", name, err)
+			}
+			replicas := statefulSet.Spec.Replicas
+			if err := mutate(&statefulSet.ObjectMeta, &replicas); err != nil {
+				return err
+			}
+			statefulSet.Spec.Replicas = replicas
+			_, err = clientset.AppsV1().StatefulSets(namespace).Update(ctx, statefulSet, metav1.UpdateOptions{})
+			return err
+		default:
+			return fmt.Errorf("unsupported workload kind: %s", kind)
+		}
+	})
+}
+
+// RestoreReplicas scales a workload back to the replica count saved by a scale-down experiment
+func RestoreReplicas(ctx context.Context, clientset kubernetes.Interface, namespace string, kind WorkloadKind, name string) error {
+	return updateReplicas(ctx, clientset, namespace, kind, name, func(meta *metav1.ObjectMeta, replicas **int32) error {
+		original, exists := meta.Annotations[OriginalReplicasAnnotation]
+		if !exists {
+			// Nothing to restore
+			return nil
+		}
+
+		if original == unsetReplicas {
+			*replicas = nil
+		} else {
+			count, err := strconv.ParseInt(original, 10, 32)
+			if err != nil {
+				return fmt.Errorf("failed to parse original replicas of %s %s: %w", kind, name, err)
+			}
+			restored := int32(count)
+			*replicas = &restored
+		}
+		delete(meta.Annotations, OriginalReplicasAnnotation)
+		delete(meta.Annotations, ScaledByAnnotation)
+		return nil
+	})
+}
+
+// getWorkload returns the metadata and replica count of a workload
+func getWorkload(ctx context.Context, clientset kubernetes.Interface, namespace string, kind WorkloadKind, name string) (*metav1.ObjectMeta, int32, error) {
+	var meta *metav1.ObjectMeta
+	var replicas *int32
+	switch kind {
+	case KindDeployment:
+		deployment, err := clientset.AppsV1().Deployments(namespace).Get(ctx, name, metav1.GetOptions{})
+		if err != nil {
+			return nil, 0, err
+		}
+		meta, replicas = &deployment.ObjectMeta, deployment.Spec.Replicas
+	case KindStatefulSet:
+		statefulSet, err := clientset.AppsV1().StatefulSets(namespace).Get(ctx, name, metav1.GetOptions{})
+		if err != nil {
+			return nil, 0, err
+		}
+		meta, replicas = &statefulSet.ObjectMeta, statefulSet.Spec.Replicas
+	default:
+		return nil, 0, fmt.Errorf("unsupported workload kind: %s", kind)
+	}
+
+	// An unset replica count means one replica
+	if replicas == nil {
+		return meta, 1, nil
+	}
+	return meta, *replicas, nil
+}
+
+// recoverReplicas restores a journaled workload if it is still scaled down by the experiment that recorded it
+func recoverReplicas(ctx context.Context, deps Dependencies, change Change) error {
+	kind := WorkloadKind(change.Data["kind"])
+	meta, _, err := getWorkload(ctx, deps.Clientset, change.Namespace, kind, change.Name)
+	if err != nil {
+		return ignoreNotFound(err)
+	}
+
+	if meta.Annotations[ScaledByAnnotation] != change.Data["token"] {
+		return nil
+	}
+
+	return RestoreReplicas(ctx, deps.Clientset, change.Namespace, kind, change.Name)
+}
+
+// workloads returns the names of the workloads targeted by the experiment
+func (e *ScaleDownExperiment) workloads(ctx context.Context) ([]string, error) {
+	if e.config.Name != "" {
+		return []string{e.config.Name}, nil
+	}
+
+	if e.config.Selector == "" {
+		return nil, fmt.Errorf("either a workload name or a selector is required")
+	}
+
+	options := metav1.ListOptions{LabelSelector: e.config.Selector}
+	names := []string{}
+	switch e.config.Kind {
+	case KindDeployment:
+		deployments, err := e.clientset.AppsV1().Deployments(e.config.Namespace).List(ctx, options)
+		if err != nil {
+			return nil, fmt.Errorf("failed to list deployments: %w", err)
+		}
+		for _, deployment := range deployments.Items {
+			names = append(names, deployment.Name)
+		}
+	case KindStatefulSet:
+		statefulSets, err := e.clientset.AppsV1().StatefulSets(e.config.Namespace).List(ctx, options)
+		if err != nil {
+			return nil, fmt.Errorf("failed to list statefulsets: %w", err)
+		}
+		for _, statefulSet := range statefulSets.Items {
+			names = append(names, statefulSet.Name)
+		}
+	default:
+		return nil, fmt.Errorf("unsupported workload kind: %s", e.config.Kind)
+	}
+
+	if len(names) == 0 {
+		return nil, fmt.Errorf("no %ss found matching the selector", e.config.Kind)
+	}
+
+	return names, nil
+}
+
+// eligible returns the workloads that may be targeted and the ones excluded
+// for not opting in to chaos. A protected workload rejects the experiment.
+func (e *ScaleDownExperiment) eligible(ctx context.Context, names []string) ([]string, []string, error) {
+	metas := []*metav1.ObjectMeta{}
+	for _, name := range names {
+		meta, _, err := getWorkload(ctx, e.clientset, e.config.Namespace, e.config.Kind, name)
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to get %s %s: %w", e.config.Kind, name, err)
+		}
+		metas = append(metas, meta)
+	}
+
+	names, excluded, err := e.protection.filterObjects(ctx, e.clientset, string(e.config.Kind), metas)
+	if err != nil {
+		return nil, nil, err
+	}
+	if len(names) == 0 {
+		return nil, excluded, fmt.Errorf("no %ss to scale down opted in to chaos", e.config.Kind)
+	}
+	return names, excluded, nil
+}
+
+// reducedReplicas computes the replica count to scale a workload down to
+func (e *ScaleDownExperiment) reducedReplicas(original int32) int32 {
+	if e.config.Replicas != nil {
+		if *e.config.Replicas < original {
+			return *e.config.Replicas
+		}
+		return original
+	}
+
+	removed := original * int32(e.config.Percentage) / 100
+	if removed < 1 && e.config.Percentage > 0 && original > 0 {
+		removed = 1
+	}
+	return original - removed
+}
+
+// validate checks the configuration before any workload is touched
+func (e *ScaleDownExperiment) validate() error {
+	if e.config.Kind != KindDeployment && e.config.Kind != KindStatefulSet {
+		return fmt.Errorf("invalid kind: %s, must be %s or %s", e.config.Kind, KindDeployment, KindStatefulSet)
+	}
+	if e.config.Replicas != nil && *e.config.Replicas < 0 {
+		return fmt.Errorf("invalid replicas: %d, must not be negative", *e.config.Replicas)
+	}
+	if e.config.Replicas == nil && (e.config.Percentage < 1 || e.config.Percentage > 100) {
+		return fmt.Errorf("invalid percentage: %d, must be between 1 and 100", e.config.Percentage)
+	}
+	return nil
+}
+
+// Plan reports the workloads that would be scaled down and their replica counts
+func (e *ScaleDownExperiment) Plan(ctx context.Context) (*Plan, error) {
+	if err := e.validate(); err != nil {
+		return nil, err
+	}
+
+	names, err := e.workloads(ctx)
+	if err != nil {
+		return nil, err
+	}
+	names, excluded, err := e.eligible(ctx, names)
+	if err != nil {
+		return nil, err
+	}
+
+	plan := newPlan("scale-down")
+	plan.leaveOut(leftOut{excluded: excluded})
+	removed := 0
+	for _, name := range names {
+		meta, original, err := getWorkload(ctx, e.clientset, e.config.Namespace, e.config.Kind, name)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get %s %s: %w", e.config.Kind, name, err)
+		}
+		if _, exists := meta.Annotations[OriginalReplicasAnnotation]; exists {
+			plan.addAction("Skip %s %s: already scaled down by another experiment", e.config.Kind, name)
+			continue
+		}
+
+		resource := fmt.Sprintf("%s/%s", e.config.Kind, name)
+		reduced, limited := e.blastRadius.limitReplicas(resource, original, e.reducedReplicas(original), removed)
+		if limited != "" {
+			plan.BlastRadius = append(plan.BlastRadius, limited)
+			if reduced == original {
+				plan.addAction("Skip %s %s: the blast-radius policy allows no replicas to be removed", e.config.Kind, name)
+				continue
+			}
+		}
+		removed += int(original - reduced)
+
+		plan.AffectedResources = append(plan.AffectedResources, resource)
+		plan.addAction("Scale %s %s from %d to %d replicas for %ds", e.config.Kind, name, original, reduced, e.config.Duration)
+	}
+
+	if len(plan.AffectedResources) == 0 && len(plan.BlastRadius) > 0 {
+		return nil, &BlastRadiusError{Reasons: plan.BlastRadius}
+	}
+	return plan, nil
+}
+
+// Run executes the scale-down experiment
+func (e *ScaleDownExperiment) Run(ctx context.Context) (*ExperimentResult, error) {
+	// Create a result object
+	result := &ExperimentResult{
+		ExperimentType: "scale-down",
+		StartTime:      time.Now(),
+		Success:        false,
+		Metrics:        map[string]float64{},
+	}
+
+	result.Logf("Starting scale-down experiment in namespace %s", e.config.Namespace)
+
+	if err := e.validate(); err != nil {
+		result.Error = err.Error()
+		return result, err
+	}
+
+	names, err := e.workloads(ctx)
+	if err != nil {
+		result.Error = err.Error()
+		return result, err
+	}
+	names, excluded, err := e.eligible(ctx, names)
+	if err != nil {
+		result.Error = err.Error()
+		return result, err
+	}
+	result.leaveOut(leftOut{excluded: excluded})
+
+	// Scale every workload down, saving the original count on the object itself
+	scaled := []string{}
+	changes := map[string]Change{}
+	removed := 0
+	for _, name := range names {
+		resource := fmt.Sprintf("%s/%s", e.config.Kind, name)
+		change := newChange(ChangeReplicas, e.config.Namespace, name, map[string]string{
+			"kind":  string(e.config.Kind),
+			"token": e.token,
+		})
+		if err := recordChange(e.journal, change); err != nil {
+			result.Logf("Skipping %s %s: %v", e.config.Kind, name, err)
+			continue
+		}
+
+		var original, reduced int32
+		var limited string
+		err := updateReplicas(ctx, e.clientset, e.config.Namespace, e.config.Kind, name, func(meta *metav1.ObjectMeta, replicas **int32) error {
+			if _, exists := meta.Annotations[OriginalReplicasAnnotation]; exists {
+				return fmt.Errorf("%s %s is already scaled down by another experiment", e.config.Kind, name)
+			}
+			// An unset replica count means one replica, and is restored unset
+			saved := unsetReplicas
+			original = 1
+			if *replicas != nil {
+				original = **replicas
+				saved = strconv.Itoa(int(original))
+			}
+			reduced, limited = e.blastRadius.limitReplicas(resource, original, e.reducedReplicas(original), removed)
+			if limited != "" && reduced == original {
+				return &BlastRadiusError{Reasons: []string{limited}}
+			}
+			if meta.Annotations == nil {
+				meta.Annotations = map[string]string{}
+			}
+			meta.Annotations[OriginalReplicasAnnotation] = saved
+			meta.Annotations[ScaledByAnnotation] = e.token
+			*replicas = &reduced
+			return nil
+		})
+		var policyErr *BlastRadiusError
+		if errors.As(err, &policyErr) {
+			result.limitedBy(policyErr.Reasons)
+			resolveChange(e.journal, change)
+			continue
+		}
+		if err != nil {
+			result.Logf("Failed to scale down %s %s: %v", e.config.Kind, name, err)
+			resolveChange(e.journal, change)
+			continue
+		}
+		changes[name] = change
+		if limited != "" {
+			result.limitedBy([]string{limited})
+		}
+		removed += int(original - reduced)
+
+		result.Logf("Scaled %s %s from %d to %d replicas", e.config.Kind, name, original, reduced)
+		scaled = append(scaled, name)
+		result.AffectedResources = append(result.AffectedResources, resource)
+		result.Metrics[resource+".original_replicas"] = float64(original)
+		result.Metrics[resource+".reduced_replicas"] = float64(reduced)
+		result.Metrics["original_replicas"] += float64(original)
+		result.Metrics["reduced_replicas"] += float64(reduced)
+	}
+
+	if len(scaled) == 0 && len(result.BlastRadius) > 0 {
+		err := &BlastRadiusError{Reasons: result.BlastRadius}
+		result.Error = err.Error()
+		return result, err
+	}
+	if len(scaled) == 0 {
+		result.Error = fmt.Sprintf("Failed to scale down any %s", e.config.Kind)
+		return result, errors.New(result.Error)
+	}
+
+	// Keep the workloads scaled down for the experiment duration, then restore them even if cancelled
+	waitErr := waitForDuration(ctx, e.config.Duration)
+
+	restoreCtx, cancel := context.WithTimeout(context.Background(), cleanupTimeout)
+	defer cancel()
+
+	var failures []string
+	for _, name := range scaled {
+		if err := RestoreReplicas(restoreCtx, e.clientset, e.config.Namespace, e.config.Kind, name); err != nil {
+			result.Logf("Failed to restore %s %s: %v", e.config.Kind, name, err)
+			failures = append(failures, err.Error())
+			continue
+		}
+		resolveChange(e.journal, changes[name])
+	}
+
+	result.EndTime = time.Now()
+
+	if waitErr != nil {
+		result.Error = "Experiment cancelled"
+		return result, waitErr
+	}
+
+	if len(failures) > 0 {
+		result.Error = fmt.Sprintf("Failed to restore replicas: %s", strings.Join(failures, "; "))
+		return result, errors.New(result.Error)
+	}
+
+	result.Success = true
+	return result, nil
+}
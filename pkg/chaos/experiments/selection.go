@@ -0,0 +1,142 @@
+package experiments
+
+import (
+	"context"
+	"fmt"
+	"math/rand"
+	"sort"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+)
+
+// SelectionStrategy defines how victims are picked from the matching pods
+type SelectionStrategy string
+
+const (
+	SelectRandom      SelectionStrategy = "random"
+	SelectOldest      SelectionStrategy = "oldest"
+	SelectNewest      SelectionStrategy = "newest"
+	SelectSpreadNodes SelectionStrategy = "spread-nodes"
+	SelectSpreadZones SelectionStrategy = "spread-zones"
+)
+
+// zoneLabel is the well-known node label holding the availability zone
+const zoneLabel = "topology.kubernetes.io/zone"
+
+// ValidSelectionStrategy reports whether the strategy is supported
+func ValidSelectionStrategy(strategy SelectionStrategy) bool {
+	switch strategy {
+	case SelectRandom, SelectOldest, SelectNewest, SelectSpreadNodes, SelectSpreadZones:
+		return true
+	}
+	return false
+}
+
+// selectPods picks count pods using the strategy. The result only depends on
+// the pods, the strategy and the state of rng, so a run can be replayed by
+// seeding rng with the same value.
+func selectPods(ctx context.Context, clientset kubernetes.Interface, pods []corev1.Pod, count int, strategy SelectionStrategy, rng *rand.Rand) ([]corev1.Pod, error) {
+	// Start from a stable order so the API's list order does not matter
+	candidates := make([]corev1.Pod, len(pods))
+	copy(candidates, pods)
+	sort.Slice(candidates, func(i, j int) bool {
+		return candidates[i].Name < candidates[j].Name
+	})
+
+	if count > len(candidates) {
+		count = len(candidates)
+	}
+
+	switch strategy {
+	case SelectRandom, "":
+		rng.Shuffle(len(candidates), func(i, j int) {
+			candidates[i], candidates[j] = candidates[j], candidates[i]
+		})
+	case SelectOldest, SelectNewest:
+		sort.SliceStable(candidates, func(i, j int) bool {
+			ti, tj := candidates[i].CreationTimestamp.Time, candidates[j].CreationTimestamp.Time
+			if strategy == SelectOldest {
+				return ti.Before(tj)
+			}
+			return ti.After(tj)
+		})
+	case SelectSpreadNodes:
+		candidates = spread(candidates, func(pod *corev1.Pod) string {
+			return pod.Spec.NodeName
+		}, rng)
+	case SelectSpreadZones:
+		zones, err := nodeZones(ctx, clientset, candidates)
+		if err != nil {
+			return nil, err
+		}
+		candidates = spread(candidates, func(pod *corev1.Pod) string {
+			return zones[pod.Spec.NodeName]
+		}, rng)
+	default:
+		return nil, fmt.Errorf("unsupported selection strategy: %s", strategy)
+	}
+
+	return candidates[:count], nil
+}
+
+// spread orders pods round-robin across the groups returned by key, shuffling within each group
+func spread(pods []corev1.Pod, key func(*corev1.Pod) string, rng *rand.Rand) []corev1.Pod {
+	groups := map[string][]corev1.Pod{}
+	for idx := range pods {
+		k := key(&pods[idx])
+		groups[k] = append(groups[k], pods[idx])
+	}
+
+	keys := make([]string, 0, len(groups))
+	for k := range groups {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	// Shuffle the group order and the pods within each group
+	rng.Shuffle(len(keys), func(i, j int) {
+		keys[i], keys[j] = keys[j], keys[i]
+	})
+	for _, k := range keys {
+		group := groups[k]
+		rng.Shuffle(len(group), func(i, j int) {
+			group[i], group[j] = group[j], group[i]
+		})
+	}
+
+	ordered := make([]corev1.Pod, 0, len(pods))
+	for len(ordered) < len(pods) {
+		for _, k := range keys {
+			if len(groups[k]) == 0 {
+				continue
+			}
+			ordered = append(ordered, groups[k][0])
+			groups[k] = groups[k][1:]
+		}
+	}
+
+	return ordered
+}
+
+// nodeZones returns the zone of every node hosting one of the pods
+func nodeZones(ctx context.Context, clientset kubernetes.Interface, pods []corev1.Pod) (map[string]string, error) {
+	zones := map[string]string{}
+	for _, pod := range pods {
+		if pod.Spec.NodeName == "" {
+			continue
+		}
+		if _, seen := zones[pod.Spec.NodeName]; seen {
+			continue
+		}
+
+		node, err := clientset.CoreV1().Nodes().Get(ctx, pod.Spec.NodeName, metav1.GetOptions{})
+		if err != nil {
+			return nil, fmt.Errorf("failed to get node %s: %w", pod.Spec.NodeName, err)
+		}
+		zones[pod.Spec.NodeName] = node.Labels[zoneLabel]
+	}
+
+	return zones, nil
+}
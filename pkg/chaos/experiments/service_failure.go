@@ -0,0 +1,356 @@
+package experiments
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/util/retry"
+)
+
+const (
+	// BlackholeSelectorLabel is the selector key a failed service points at, which no pod carries
+	BlackholeSelectorLabel = "chaos.platform/blackhole"
+
+	// OriginalSelectorAnnotation stores the selector of a failed service so it can be restored
+	OriginalSelectorAnnotation = "chaos.platform/original-selector"
+)
+
+// ServiceFailureConfig holds the settings for a service failure experiment
+type ServiceFailureConfig struct {
+	Namespace   string
+	ServiceName string // Name of a single service to fail
+	Selector    string // Label selector for the services to fail, used when ServiceName is empty
+	Duration    int
+}
+
+// ServiceFailureExperiment makes services unavailable by pointing their selector at no pods
+type ServiceFailureExperiment struct {
+	clientset kubernetes.Interface
+	config    ServiceFailureConfig
+	token     string
+	journal   Journal
+
+	protection  *Protection
+	blastRadius *BlastRadius
+}
+
+func init() {
+	Register(Definition{
+		Type:        "service-failure",
+		Description: "Take Services out of rotation by pointing them at no pods",
+		TargetType:  "service",
+		Params: []Param{
+			{Name: "namespace", Description: "Namespace of the services", Type: ParamString, Required: true},
+			{Name: "service", Description: "Name of a single service to disable", Type: ParamString},
+			{Name: "selector", Description: "Label selector for the services, used when service is empty", Type: ParamString},
+		},
+		New: func(deps Dependencies, spec Spec) (Experiment, error) {
+			config := ServiceFailureConfig{
+				Namespace: spec.Namespace,
+				Selector:  spec.Selector,
+				Duration:  spec.Duration,
+			}
+			if !spec.Targeted {
+				config.ServiceName = spec.Params["service"]
+			}
+
+			if config.Namespace == "" {
+				return nil, fmt.Errorf("missing required parameter: namespace")
+			}
+			if config.ServiceName == "" && config.Selector == "" {
+				return nil, fmt.Errorf("missing required parameter: service or selector")
+			}
+
+			serviceFailure := NewServiceFailureExperiment(deps.Clientset, config)
+			serviceFailure.journal = deps.Journal
+			serviceFailure.protection = deps.Protection
+			serviceFailure.blastRadius = deps.BlastRadius
+
+			return serviceFailure, nil
+		},
+		Recover: recoverService,
+	})
+}
+
+// NewServiceFailureExperiment creates a new service failure experiment
+func NewServiceFailureExperiment(clientset kubernetes.Interface, config ServiceFailureConfig) *ServiceFailureExperiment {
+	return &ServiceFailureExperiment{
+		clientset: clientset,
+		config:    config,
+		token:     uuid.New().String()[:8],
+	}
+}
+
+// services returns the names of the services targeted by the experiment
+func (e *ServiceFailureExperiment) services(ctx context.Context) ([]string, error) {
+	if e.config.ServiceName != "" {
+		return []string{e.config.ServiceName}, nil
+	}
+
+	if e.config.Selector == "" {
+		return nil, fmt.Errorf("either a service name or a selector is required")
+	}
+
+	services, err := e.clientset.CoreV1().Services(e.config.Namespace).List(ctx, metav1.ListOptions{
+		LabelSelector: e.config.Selector,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list services: %w", err)
+	}
+
+	names := make([]string, 0, len(services.Items))
+	for _, service := range services.Items {
+		names = append(names, service.Name)
+	}
+
+	if len(names) == 0 {
+		return nil, fmt.Errorf("no services found matching the selector")
+	}
+
+	return names, nil
+}
+
+// eligible returns the services that may be targeted and the ones excluded
+// for not opting in to chaos. A protected service rejects the experiment.
+func (e *ServiceFailureExperiment) eligible(ctx context.Context, names []string) ([]string, []string, error) {
+	metas := []*metav1.ObjectMeta{}
+	for _, name := range names {
+		service, err := e.clientset.CoreV1().Services(e.config.Namespace).Get(ctx, name, metav1.GetOptions{})
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to get service %s: %w", name, err)
+		}
+		metas = append(metas, &service.ObjectMeta)
+	}
+
+	names, excluded, err := e.protection.filterObjects(ctx, e.clientset, "service", metas)
+	if err != nil {
+		return nil, nil, err
+	}
+	if len(names) == 0 {
+		return nil, excluded, fmt.Errorf("no services to disable opted in to chaos")
+	}
+	return names, excluded, nil
+}
+
+// checkBlastRadius rejects failing services whose pods, all taken out of
+// rotation at once, the blast-radius policy does not allow
+func (e *ServiceFailureExperiment) checkBlastRadius(ctx context.Context, names []string) error {
+	if !e.blastRadius.enforced() {
+		return nil
+	}
+
+	pods := []corev1.Pod{}
+	seen := map[string]bool{}
+	for _, name := range names {
+		service, err := e.clientset.CoreV1().Services(e.config.Namespace).Get(ctx, name, metav1.GetOptions{})
+		if err != nil {
+			return fmt.Errorf("failed to get service %s: %w", name, err)
+		}
+		if len(service.Spec.Selector) == 0 {
+			continue
+		}
+
+		backing, err := e.clientset.CoreV1().Pods(e.config.Namespace).List(ctx, metav1.ListOptions{
+			LabelSelector: labels.SelectorFromSet(service.Spec.Selector).String(),
+		})
+		if err != nil {
+			return fmt.Errorf("failed to list pods of service %s: %w", name, err)
+		}
+		for _, pod := range backing.Items {
+			if pod.DeletionTimestamp == nil && !seen[pod.Name] {
+				seen[pod.Name] = true
+				pods = append(pods, pod)
+			}
+		}
+	}
+
+	return e.blastRadius.check(ctx, e.clientset, pods)
+}
+
+// disable saves the selector of the service and replaces it with one that matches nothing
+func (e *ServiceFailureExperiment) disable(ctx context.Context, name string) error {
+	return retry.RetryOnConflict(retry.DefaultRetry, func() error {
+		service, err := e.clientset.CoreV1().Services(e.config.Namespace).Get(ctx, name, metav1.GetOptions{})
+		if err != nil {
+			return fmt.Errorf("failed to get service %s: %w", name, err)
+		}
+
+		if len(service.Spec.Selector) == 0 {
+			return fmt.Errorf("service %s has no selector", name)
+		}
+		if _, exists := service.Annotations[OriginalSelectorAnnotation]; exists {
+			return fmt.Errorf("service %s is already failed by another experiment", name)
+		}
+
+		original, err := json.Marshal(service.Spec.Selector)
+		if err != nil {
+			return fmt.Errorf("failed to marshal selector of service %s: %w", name, err)
+		}
+
+		if service.Annotations == nil {
+			service.Annotations = map[string]string{}
+		}
+		service.Annotations[OriginalSelectorAnnotation] = string(original)
+		service.Spec.Selector = map[string]string{BlackholeSelectorLabel: e.token}
+
+		_, err = e.clientset.CoreV1().Services(e.config.Namespace).Update(ctx, service, metav1.UpdateOptions{})
+		return err
+	})
+}
+
+// RestoreService puts back the selector saved by a service failure experiment
+func RestoreService(ctx context.Context, clientset kubernetes.Interface, namespace, name string) error {
+	return retry.RetryOnConflict(retry.DefaultRetry, func() error {
+		service, err := clientset.CoreV1().Services(namespace).Get(ctx, name, metav1.GetOptions{})
+		if err != nil {
+			return fmt.Errorf("failed to get service %s: %w", name, err)
+		}
+
+		original, exists := service.Annotations[OriginalSelectorAnnotation]
+		if !exists {
+			// Nothing to restore
+			return nil
+		}
+
+		var selector map[string]string
+		if err := json.Unmarshal([]byte(original), &selector); err != nil {
+			return fmt.Errorf("failed to parse original selector of service %s: %w", name, err)
+		}
+
+		service.Spec.Selector = selector
+		delete(service.Annotations, OriginalSelectorAnnotation)
+
+		_, err = clientset.CoreV1().Services(namespace).Update(ctx, service, metav1.UpdateOptions{})
+		return err
+	})
+}
+
+// recoverService restores a journaled service if it is still failed by the experiment that recorded it
+func recoverService(ctx context.Context, deps Dependencies, change Change) error {
+	service, err := deps.Clientset.CoreV1().Services(change.Namespace).Get(ctx, change.Name, metav1.GetOptions{})
+	if err != nil {
+		return ignoreNotFound(err)
+	}
+
+	if service.Spec.Selector[BlackholeSelectorLabel] != change.Data["token"] {
+		return nil
+	}
+
+	return RestoreService(ctx, deps.Clientset, change.Namespace, change.Name)
+}
+
+// Plan reports the services that would be taken out of rotation
+func (e *ServiceFailureExperiment) Plan(ctx context.Context) (*Plan, error) {
+	names, err := e.services(ctx)
+	if err != nil {
+		return nil, err
+	}
+	names, excluded, err := e.eligible(ctx, names)
+	if err != nil {
+		return nil, err
+	}
+	if err := e.checkBlastRadius(ctx, names); err != nil {
+		return nil, err
+	}
+
+	plan := newPlan("service-failure")
+	plan.leaveOut(leftOut{excluded: excluded})
+	for _, name := range names {
+		plan.AffectedResources = append(plan.AffectedResources, name)
+		plan.addAction("Replace the selector of service %s with one matching no pods for %ds", name, e.config.Duration)
+	}
+	return plan, nil
+}
+
+// Run executes the service failure experiment
+func (e *ServiceFailureExperiment) Run(ctx context.Context) (*ExperimentResult, error) {
+	// Create a result object
+	result := &ExperimentResult{
+		ExperimentType: "service-failure",
+		StartTime:      time.Now(),
+		Success:        false,
+	}
+
+	result.Logf("Starting service failure experiment in namespace %s", e.config.Namespace)
+
+	names, err := e.services(ctx)
+	if err != nil {
+		result.Error = err.Error()
+		return result, err
+	}
+	names, excluded, err := e.eligible(ctx, names)
+	if err != nil {
+		result.Error = err.Error()
+		return result, err
+	}
+	if err := e.checkBlastRadius(ctx, names); err != nil {
+		result.Error = err.Error()
+		return result, err
+	}
+	result.leaveOut(leftOut{excluded: excluded})
+
+	// Disable every service, remembering which ones have to be restored
+	disabled := []string{}
+	changes := map[string]Change{}
+	for _, name := range names {
+		change := newChange(ChangeServiceSelector, e.config.Namespace, name, map[string]string{"token": e.token})
+		if err := recordChange(e.journal, change); err != nil {
+			result.Logf("Skipping service %s: %v", name, err)
+			continue
+		}
+		if err := e.disable(ctx, name); err != nil {
+			result.Logf("Failed to disable service %s: %v", name, err)
+			resolveChange(e.journal, change)
+			continue
+		}
+		result.Logf("Disabled service %s", name)
+		disabled = append(disabled, name)
+		changes[name] = change
+	}
+	result.AffectedResources = disabled
+
+	if len(disabled) == 0 {
+		result.Error = "Failed to disable any service"
+		return result, errors.New(result.Error)
+	}
+
+	// Keep the services down for the experiment duration, then restore them even if cancelled
+	waitErr := waitForDuration(ctx, e.config.Duration)
+
+	restoreCtx, cancel := context.WithTimeout(context.Background(), cleanupTimeout)
+	defer cancel()
+
+	var failures []string
+	for _, name := range disabled {
+		if err := RestoreService(restoreCtx, e.clientset, e.config.Namespace, name); err != nil {
+			result.Logf("Failed to restore service %s: %v", name, err)
+			failures = append(failures, err.Error())
+			continue
+		}
+		resolveChange(e.journal, changes[name])
+	}
+
+	result.EndTime = time.Now()
+
+	if waitErr != nil {
+		result.Error = "Experiment cancelled"
+		return result, waitErr
+	}
+
+	if len(failures) > 0 {
+		result.Error = fmt.Sprintf("Failed to restore services: %s", strings.Join(failures, "; "))
+		return result, errors.New(result.Error)
+	}
+
+	result.Success = true
+	return result, nil
+}
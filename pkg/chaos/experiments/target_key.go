@@ -0,0 +1,41 @@
+package experiments
+
+import (
+	"k8s.io/apimachinery/pkg/labels"
+)
+
+// objectParams are the parameters that name a single object instead of
+// selecting targets by label
+var objectParams = []string{"name", "service", "node"}
+
+// TargetKey identifies what an experiment acts on, so that runs against the
+// same target can be kept apart. Runs share a key when they select the same
+// labels in the same namespace or name the same object. Types that act across
+// the cluster, like node-failure, are keyed without a namespace.
+func TargetKey(definition Definition, spec Spec) string {
+	scope := "cluster"
+	if definition.namespaced() {
+		scope = spec.Namespace
+	}
+
+	if !spec.Targeted {
+		for _, name := range objectParams {
+			if object := spec.Params[name]; object != "" {
+				kind := definition.TargetType
+				if spec.Params["kind"] != "" {
+					kind = spec.Params["kind"]
+				} else if kind == "" {
+					kind = name
+				}
+				return scope + "/" + kind + "/" + object
+			}
+		}
+	}
+
+	// Parsing sorts the requirements, so equivalent selectors share a key
+	selector := spec.Selector
+	if parsed, err := labels.Parse(selector); err == nil {
+		selector = parsed.String()
+	}
+	return scope + "/" + selector
+}
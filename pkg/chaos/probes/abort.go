@@ -0,0 +1,154 @@
+package probes
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// AbortConditions halt an experiment early once the system under test
+// degrades further than it may. Any condition that trips aborts the run.
+type AbortConditions struct {
+	Interval         int                     `json:"interval,omitempty"`           // Seconds between checks, defaults to 10
+	MaxErrorRate     *ErrorRateCondition     `json:"max_error_rate,omitempty"`     // Abort when a Prometheus query exceeds a threshold
+	MaxProbeFailures int                     `json:"max_probe_failures,omitempty"` // Abort once a probe has failed this many times during the run, 0 never
+	MinReadyReplicas *ReadyReplicasCondition `json:"min_ready_replicas,omitempty"` // Abort when a workload has fewer ready replicas
+}
+
+// ErrorRateCondition trips when the value of a query is above a threshold
+type ErrorRateCondition struct {
+	URL       string  `json:"url"`   // Base URL of the Prometheus server
+	Query     string  `json:"query"` // Must return a scalar or a single series
+	Threshold float64 `json:"threshold"`
+}
+
+// ReadyReplicasCondition trips when a workload has fewer ready replicas than required
+type ReadyReplicasCondition struct {
+	Kind      string `json:"kind"` // deployment or statefulset
+	Namespace string `json:"namespace"`
+	Name      string `json:"name"`
+	Replicas  int32  `json:"replicas"`
+}
+
+// interval returns the time between checks
+func (a AbortConditions) interval() time.Duration {
+	if a.Interval > 0 {
+		return time.Duration(a.Interval) * time.Second
+	}
+	return defaultInterval
+}
+
+// Validate checks that the abort conditions are complete
+func (a AbortConditions) Validate() error {
+	if a.Interval < 0 || a.MaxProbeFailures < 0 {
+		return fmt.Errorf("interval and max_probe_failures must not be negative")
+	}
+	if a.MaxErrorRate == nil && a.MaxProbeFailures == 0 && a.MinReadyReplicas == nil {
+		return fmt.Errorf("at least one of max_error_rate, max_probe_failures or min_ready_replicas is required")
+	}
+
+	if condition := a.MaxErrorRate; condition != nil {
+		if parsed, err := url.Parse(condition.URL); err != nil || parsed.Scheme == "" || parsed.Host == "" {
+			return fmt.Errorf("max_error_rate.url must be an absolute URL")
+		}
+		if condition.Query == "" {
+			return fmt.Errorf("max_error_rate.query is required")
+		}
+	}
+
+	if condition := a.MinReadyReplicas; condition != nil {
+		if condition.Kind != "deployment" && condition.Kind != "statefulset" {
+			return fmt.Errorf("min_ready_replicas.kind must be deployment or statefulset")
+		}
+		if condition.Namespace == "" || condition.Name == "" {
+			return fmt.Errorf("min_ready_replicas.namespace and min_ready_replicas.name are required")
+		}
+		if condition.Replicas < 1 {
+			return fmt.Errorf("min_ready_replicas.replicas must be at least 1")
+		}
+	}
+
+	return nil
+}
+
+// Guard checks the abort conditions of an experiment while it runs
+type Guard struct {
+	runner     *Runner
+	conditions AbortConditions
+}
+
+// NewGuard creates a guard for the conditions. Probe failures are counted
+// from the samples the runner takes during the experiment.
+func NewGuard(runner *Runner, conditions AbortConditions) *Guard {
+	return &Guard{runner: runner, conditions: conditions}
+}
+
+// Check evaluates the conditions once and returns why the experiment must be
+// aborted, or an empty string. A condition that cannot be evaluated, such as
+// an unreachable Prometheus server, does not abort the experiment.
+func (g *Guard) Check(ctx context.Context) string {
+	if condition := g.conditions.MaxErrorRate; condition != nil {
+		check := &PrometheusCheck{URL: condition.URL, Query: condition.Query, Operator: "<=", Threshold: condition.Threshold}
+		checkCtx, cancel := context.WithTimeout(ctx, defaultTimeout)
+		value, err := g.runner.checkPrometheus(checkCtx, check)
+		cancel()
+		if err != nil && value != nil {
+			return fmt.Sprintf("error rate %g is above %g", *value, condition.Threshold)
+		}
+	}
+
+	if max := g.conditions.MaxProbeFailures; max > 0 {
+		if name, failures := g.runner.failures(PhaseDuring); failures >= max {
+			return fmt.Sprintf("probe %s failed %d times", name, failures)
+		}
+	}
+
+	if condition := g.conditions.MinReadyReplicas; condition != nil {
+		checkCtx, cancel := context.WithTimeout(ctx, defaultTimeout)
+		ready, err := g.readyReplicas(checkCtx, condition)
+		cancel()
+		if err == nil && ready < condition.Replicas {
+			return fmt.Sprintf("%s %s has %d ready replicas, below %d", condition.Kind, condition.Name, ready, condition.Replicas)
+		}
+	}
+
+	return ""
+}
+
+// Watch checks the conditions at their interval until one trips, which it
+// returns the reason for, or the context is done
+func (g *Guard) Watch(ctx context.Context) string {
+	ticker := time.NewTicker(g.conditions.interval())
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return ""
+		case <-ticker.C:
+			if reason := g.Check(ctx); reason != "" {
+				return reason
+			}
+		}
+	}
+}
+
+// readyReplicas returns the ready replicas of the workload of a condition
+func (g *Guard) readyReplicas(ctx context.Context, condition *ReadyReplicasCondition) (int32, error) {
+	apps := g.runner.clientset.AppsV1()
+	if condition.Kind == "statefulset" {
+		statefulSet, err := apps.StatefulSets(condition.Namespace).Get(ctx, condition.Name, metav1.GetOptions{})
+		if err != nil {
+			return 0, err
+		}
+		return statefulSet.Status.ReadyReplicas, nil
+	}
+
+	deployment, err := apps.Deployments(condition.Namespace).Get(ctx, condition.Name, metav1.GetOptions{})
+	if err != nil {
+		return 0, err
+	}
+	return deployment.Status.ReadyReplicas, nil
+}
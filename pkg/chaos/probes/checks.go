@@ -0,0 +1,178 @@
+package probes
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// comparisons are the operators a Prometheus check can compare with
+var comparisons = map[string]func(value, threshold float64) bool{
+	"<":  func(value, threshold float64) bool { return value < threshold },
+	"<=": func(value, threshold float64) bool { return value <= threshold },
+	">":  func(value, threshold float64) bool { return value > threshold },
+	">=": func(value, threshold float64) bool { return value >= threshold },
+	"==": func(value, threshold float64) bool { return value == threshold },
+	"!=": func(value, threshold float64) bool { return value != threshold },
+}
+
+// checkHTTP calls the endpoint and returns its latency in milliseconds
+func (r *Runner) checkHTTP(ctx context.Context, check *HTTPCheck) (*float64, error) {
+	method := check.Method
+	if method == "" {
+		method = http.MethodGet
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, check.URL, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+
+	start := time.Now()
+	resp, err := r.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("request failed: %w", err)
+	}
+	_, _ = io.Copy(io.Discard, resp.Body)
+	resp.Body.Close()
+
+	latency := float64(time.Since(start).Milliseconds())
+
+	if check.ExpectedStatus != 0 && resp.StatusCode != check.ExpectedStatus {
+		return &latency, fmt.Errorf("status %d, expected %d", resp.StatusCode, check.ExpectedStatus)
+	}
+	if check.ExpectedStatus == 0 && (resp.StatusCode < 200 || resp.StatusCode > 299) {
+		return &latency, fmt.Errorf("status %d, expected 2xx", resp.StatusCode)
+	}
+	if check.MaxLatency > 0 && latency > float64(check.MaxLatency) {
+		return &latency, fmt.Errorf("latency %.0fms above %dms", latency, check.MaxLatency)
+	}
+
+	return &latency, nil
+}
+
+// checkKubernetes reads the object and returns its available replicas, if it has any
+func (r *Runner) checkKubernetes(ctx context.Context, check *KubernetesCheck) (*float64, error) {
+	conditions := map[string]string{}
+	var available *float64
+
+	switch check.Kind {
+	case "deployment":
+		deployment, err := r.clientset.AppsV1().Deployments(check.Namespace).Get(ctx, check.Name, metav1.GetOptions{})
+		if err != nil {
+			return nil, fmt.Errorf("failed to get deployment %s: %w", check.Name, err)
+		}
+		for _, condition := range deployment.Status.Conditions {
+			conditions[string(condition.Type)] = string(condition.Status)
+		}
+		replicas := float64(deployment.Status.AvailableReplicas)
+		available = &replicas
+	case "statefulset":
+		statefulSet, err := r.clientset.AppsV1().StatefulSets(check.Namespace).Get(ctx, check.Name, metav1.GetOptions{})
+		if err != nil {
+			return nil, fmt.Errorf("failed to get statefulset %s: %w", check.Name, err)
+		}
+		for _, condition := range statefulSet.Status.Conditions {
+			conditions[string(condition.Type)] = string(condition.Status)
+		}
+		replicas := float64(statefulSet.Status.AvailableReplicas)
+		available = &replicas
+	case "pod":
+		pod, err := r.clientset.CoreV1().Pods(check.Namespace).Get(ctx, check.Name, metav1.GetOptions{})
+		if err != nil {
+			return nil, fmt.Errorf("failed to get pod %s: %w", check.Name, err)
+		}
+		for _, condition := range pod.Status.Conditions {
+			conditions[string(condition.Type)] = string(condition.Status)
+		}
+	default:
+		return nil, fmt.Errorf("unsupported kind: %s", check.Kind)
+	}
+
+	if check.Condition != "" && conditions[check.Condition] != string(corev1.ConditionTrue) {
+		return available, fmt.Errorf("condition %s is not True", check.Condition)
+	}
+	if check.MinAvailableReplicas != nil && *available < float64(*check.MinAvailableReplicas) {
+		return available, fmt.Errorf("%.0f available replicas, expected at least %d", *available, *check.MinAvailableReplicas)
+	}
+
+	return available, nil
+}
+
+// prometheusResponse is the part of a Prometheus instant query response the check reads
+type prometheusResponse struct {
+	Status string `json:"status"`
+	Error  string `json:"error"`
+	Data   struct {
+		ResultType string          `json:"resultType"`
+		Result     json.RawMessage `json:"result"`
+	} `json:"data"`
+}
+
+// checkPrometheus runs the query and returns its value
+func (r *Runner) checkPrometheus(ctx context.Context, check *PrometheusCheck) (*float64, error) {
+	endpoint := check.URL + "/api/v1/query?" + url.Values{"query": {check.Query}}.Encode()
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, endpoint, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+
+	resp, err := r.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("query failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	var body prometheusResponse
+	if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+		return nil, fmt.Errorf("failed to decode query response: %w", err)
+	}
+	if body.Status != "success" {
+		return nil, fmt.Errorf("query failed: %s", body.Error)
+	}
+
+	// A sample is a [timestamp, "value"] pair
+	var sample []interface{}
+	switch body.Data.ResultType {
+	case "scalar":
+		if err := json.Unmarshal(body.Data.Result, &sample); err != nil {
+			return nil, fmt.Errorf("failed to decode scalar: %w", err)
+		}
+	case "vector":
+		var series []struct {
+			Value []interface{} `json:"value"`
+		}
+		if err := json.Unmarshal(body.Data.Result, &series); err != nil {
+			return nil, fmt.Errorf("failed to decode vector: %w", err)
+		}
+		if len(series) != 1 {
+			return nil, fmt.Errorf("query returned %d series, expected 1", len(series))
+		}
+		sample = series[0].Value
+	default:
+		return nil, fmt.Errorf("unsupported result type: %s", body.Data.ResultType)
+	}
+
+	if len(sample) != 2 {
+		return nil, fmt.Errorf("malformed sample in query response")
+	}
+	valueStr, _ := sample[1].(string)
+	value, err := strconv.ParseFloat(valueStr, 64)
+	if err != nil {
+		return nil, fmt.Errorf("invalid sample value %q", valueStr)
+	}
+
+	if !comparisons[check.Operator](value, check.Threshold) {
+		return &value, fmt.Errorf("%g is not %s %g", value, check.Operator, check.Threshold)
+	}
+
+	return &value, nil
+}
@@ -0,0 +1,193 @@
+package probes
+
+import (
+	"fmt"
+	"net/url"
+	"time"
+)
+
+// Type identifies what a probe checks
+type Type string
+
+const (
+	TypeHTTP       Type = "http"
+	TypeKubernetes Type = "kubernetes"
+	TypePrometheus Type = "prometheus"
+)
+
+// Phase is the part of an experiment a sample was taken in
+type Phase string
+
+const (
+	PhaseBefore Phase = "before" // Before the fault is injected
+	PhaseDuring Phase = "during" // While the fault is held
+	PhaseAfter  Phase = "after"  // After the fault has been removed
+)
+
+// Defaults for the timing of a probe
+const (
+	defaultInterval = 10 * time.Second
+	defaultTimeout  = 5 * time.Second
+)
+
+// Probe checks one aspect of the steady state of the system under test. The
+// steady-state hypothesis of an experiment holds if every sample of every
+// probe passes.
+type Probe struct {
+	Name            string           `json:"name"`
+	Type            Type             `json:"type"`
+	Interval        int              `json:"interval,omitempty"`         // Seconds between samples during the experiment, defaults to 10
+	Timeout         int              `json:"timeout,omitempty"`          // Seconds a single sample may take, defaults to 5
+	RecoveryTimeout int              `json:"recovery_timeout,omitempty"` // Seconds the after phase retries a failing probe, 0 samples once
+	HTTP            *HTTPCheck       `json:"http,omitempty"`
+	Kubernetes      *KubernetesCheck `json:"kubernetes,omitempty"`
+	Prometheus      *PrometheusCheck `json:"prometheus,omitempty"`
+}
+
+// HTTPCheck passes when an endpoint answers with the expected status in time
+type HTTPCheck struct {
+	URL            string `json:"url"`
+	Method         string `json:"method,omitempty"`          // Defaults to GET
+	ExpectedStatus int    `json:"expected_status,omitempty"` // Any 2xx status when 0
+	MaxLatency     int    `json:"max_latency,omitempty"`     // Milliseconds, latency is not checked when 0
+}
+
+// KubernetesCheck passes when a workload or pod reports a condition or
+// enough available replicas
+type KubernetesCheck struct {
+	Kind                 string `json:"kind"` // deployment, statefulset or pod
+	Namespace            string `json:"namespace"`
+	Name                 string `json:"name"`
+	Condition            string `json:"condition,omitempty"`              // Status condition that must be True, such as Available or Ready
+	MinAvailableReplicas *int32 `json:"min_available_replicas,omitempty"` // Not supported for pods
+}
+
+// PrometheusCheck passes when the value of a query compares to a threshold
+type PrometheusCheck struct {
+	URL       string  `json:"url"`      // Base URL of the Prometheus server
+	Query     string  `json:"query"`    // Must return a scalar or a single series
+	Operator  string  `json:"operator"` // <, <=, >, >=, == or !=
+	Threshold float64 `json:"threshold"`
+}
+
+// interval returns the time between samples during the experiment
+func (p Probe) interval() time.Duration {
+	if p.Interval > 0 {
+		return time.Duration(p.Interval) * time.Second
+	}
+	return defaultInterval
+}
+
+// timeout returns the time a single sample may take
+func (p Probe) timeout() time.Duration {
+	if p.Timeout > 0 {
+		return time.Duration(p.Timeout) * time.Second
+	}
+	return defaultTimeout
+}
+
+// Validate checks that the probe is complete
+func (p Probe) Validate() error {
+	if p.Name == "" {
+		return fmt.Errorf("name is required")
+	}
+	if p.Interval < 0 || p.Timeout < 0 || p.RecoveryTimeout < 0 {
+		return fmt.Errorf("interval, timeout and recovery_timeout must not be negative")
+	}
+
+	switch p.Type {
+	case TypeHTTP:
+		if p.HTTP == nil {
+			return fmt.Errorf("http settings are required for an http probe")
+		}
+		if parsed, err := url.Parse(p.HTTP.URL); err != nil || parsed.Scheme == "" || parsed.Host == "" {
+			return fmt.Errorf("http.url must be an absolute URL")
+		}
+		if p.HTTP.MaxLatency < 0 {
+			return fmt.Errorf("http.max_latency must not be negative")
+		}
+	case TypeKubernetes:
+		check := p.Kubernetes
+		if check == nil {
+			return fmt.Errorf("kubernetes settings are required for a kubernetes probe")
+		}
+		if check.Namespace == "" || check.Name == "" {
+			return fmt.Errorf("kubernetes.namespace and kubernetes.name are required")
+		}
+		switch check.Kind {
+		case "deployment", "statefulset":
+			if check.Condition == "" && check.MinAvailableReplicas == nil {
+				return fmt.Errorf("kubernetes.condition or kubernetes.min_available_replicas is required")
+			}
+		case "pod":
+			if check.Condition == "" {
+				return fmt.Errorf("kubernetes.condition is required for a pod")
+			}
+			if check.MinAvailableReplicas != nil {
+				return fmt.Errorf("kubernetes.min_available_replicas is not supported for a pod")
+			}
+		default:
+			return fmt.Errorf("kubernetes.kind must be deployment, statefulset or pod")
+		}
+	case TypePrometheus:
+		check := p.Prometheus
+		if check == nil {
+			return fmt.Errorf("prometheus settings are required for a prometheus probe")
+		}
+		if parsed, err := url.Parse(check.URL); err != nil || parsed.Scheme == "" || parsed.Host == "" {
+			return fmt.Errorf("prometheus.url must be an absolute URL")
+		}
+		if check.Query == "" {
+			return fmt.Errorf("prometheus.query is required")
+		}
+		if _, valid := comparisons[check.Operator]; !valid {
+			return fmt.Errorf("prometheus.operator must be one of <, <=, >, >=, == or !=")
+		}
+	default:
+		return fmt.Errorf("type must be %s, %s or %s", TypeHTTP, TypeKubernetes, TypePrometheus)
+	}
+
+	return nil
+}
+
+// Sample is the outcome of evaluating a probe once
+type Sample struct {
+	Time    time.Time `json:"time"`
+	Phase   Phase     `json:"phase"`
+	Passed  bool      `json:"passed"`
+	Value   *float64  `json:"value,omitempty"`   // Latency in ms, available replicas or query result
+	Message string    `json:"message,omitempty"` // Why the sample failed
+}
+
+// ProbeReport holds every sample taken of a probe
+type ProbeReport struct {
+	Name    string   `json:"name"`
+	Type    Type     `json:"type"`
+	Passed  bool     `json:"passed"`
+	Samples []Sample `json:"samples"`
+}
+
+// Verdict is the outcome of a steady-state hypothesis
+type Verdict string
+
+const (
+	VerdictPassed Verdict = "passed"
+	VerdictFailed Verdict = "failed"
+)
+
+// Report is the verdict of a steady-state hypothesis with the samples it is based on
+type Report struct {
+	Verdict Verdict       `json:"verdict"`
+	Probes  []ProbeReport `json:"probes"`
+}
+
+// Failed returns the names of the probes that had a failing sample
+func (r *Report) Failed() []string {
+	names := []string{}
+	for _, probe := range r.Probes {
+		if !probe.Passed {
+			names = append(names, probe.Name)
+		}
+	}
+	return names
+}
@@ -0,0 +1,177 @@
+package probes
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+
+	"k8s.io/client-go/kubernetes"
+)
+
+// Runner evaluates the probes of an experiment and keeps their samples
+type Runner struct {
+	clientset kubernetes.Interface
+	client    *http.Client
+	probes    []Probe
+
+	mu      sync.Mutex
+	reports []ProbeReport
+}
+
+// NewRunner creates a runner for the probes. Kubernetes probes read objects
+// through the clientset.
+func NewRunner(clientset kubernetes.Interface, probes []Probe) *Runner {
+	reports := make([]ProbeReport, len(probes))
+	for i, probe := range probes {
+		reports[i] = ProbeReport{Name: probe.Name, Type: probe.Type, Samples: []Sample{}}
+	}
+
+	return &Runner{
+		clientset: clientset,
+		client:    &http.Client{},
+		probes:    probes,
+		reports:   reports,
+	}
+}
+
+// sample evaluates a probe once and records the outcome
+func (r *Runner) sample(ctx context.Context, idx int, phase Phase) Sample {
+	probe := r.probes[idx]
+	ctx, cancel := context.WithTimeout(ctx, probe.timeout())
+	defer cancel()
+
+	var value *float64
+	var err error
+	switch probe.Type {
+	case TypeHTTP:
+		value, err = r.checkHTTP(ctx, probe.HTTP)
+	case TypeKubernetes:
+		value, err = r.checkKubernetes(ctx, probe.Kubernetes)
+	case TypePrometheus:
+		value, err = r.checkPrometheus(ctx, probe.Prometheus)
+	default:
+		err = fmt.Errorf("unsupported probe type: %s", probe.Type)
+	}
+
+	sample := Sample{Time: time.Now(), Phase: phase, Passed: err == nil, Value: value}
+	if err != nil {
+		sample.Message = err.Error()
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.reports[idx].Samples = append(r.reports[idx].Samples, sample)
+
+	return sample
+}
+
+// Evaluate samples every probe once and reports whether all of them passed
+func (r *Runner) Evaluate(ctx context.Context, phase Phase) bool {
+	passed := true
+	for idx := range r.probes {
+		if !r.sample(ctx, idx, phase).Passed {
+			passed = false
+		}
+	}
+	return passed
+}
+
+// Watch samples every probe at its interval until the context is done
+func (r *Runner) Watch(ctx context.Context) {
+	var wg sync.WaitGroup
+	for idx := range r.probes {
+		wg.Add(1)
+		go func(idx int) {
+			defer wg.Done()
+
+			ticker := time.NewTicker(r.probes[idx].interval())
+			defer ticker.Stop()
+			for {
+				select {
+				case <-ctx.Done():
+					return
+				case <-ticker.C:
+					r.sample(ctx, idx, PhaseDuring)
+				}
+			}
+		}(idx)
+	}
+	wg.Wait()
+}
+
+// Recover samples every probe after the fault has been removed. A failing
+// probe is sampled again at its interval until it passes or its recovery
+// timeout runs out, since the system may take a while to settle.
+func (r *Runner) Recover(ctx context.Context) bool {
+	passed := true
+	for idx, probe := range r.probes {
+		deadline := time.Now().Add(time.Duration(probe.RecoveryTimeout) * time.Second)
+		for !r.sample(ctx, idx, PhaseAfter).Passed {
+			if time.Now().Add(probe.interval()).After(deadline) {
+				passed = false
+				break
+			}
+			select {
+			case <-ctx.Done():
+				return false
+			case <-time.After(probe.interval()):
+			}
+		}
+	}
+	return passed
+}
+
+// failures returns the probe with the most failing samples in a phase and its count
+func (r *Runner) failures(phase Phase) (string, int) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	name, most := "", 0
+	for _, report := range r.reports {
+		count := 0
+		for _, sample := range report.Samples {
+			if sample.Phase == phase && !sample.Passed {
+				count++
+			}
+		}
+		if count > most {
+			name, most = report.Name, count
+		}
+	}
+	return name, most
+}
+
+// Report returns the verdict of the steady-state hypothesis with every sample
+// taken so far. A probe passes if every sample before and during the
+// experiment passed and its last sample after it did.
+func (r *Runner) Report() *Report {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	report := &Report{Verdict: VerdictPassed, Probes: make([]ProbeReport, len(r.reports))}
+	for i, probe := range r.reports {
+		probe.Samples = append([]Sample{}, probe.Samples...)
+		probe.Passed = true
+
+		var after *Sample
+		for idx := range probe.Samples {
+			sample := &probe.Samples[idx]
+			if sample.Phase == PhaseAfter {
+				after = sample
+			} else if !sample.Passed {
+				probe.Passed = false
+			}
+		}
+		if after != nil && !after.Passed {
+			probe.Passed = false
+		}
+
+		report.Probes[i] = probe
+		if !probe.Passed {
+			report.Verdict = VerdictFailed
+		}
+	}
+	return report
+}
@@ -93,8 +93,14 @@ func (s *Scheduler) schedulerLoop() {
 
 // checkSchedules checks for scheduled experiments to run
 func (s *Scheduler) checkSchedules() {
-	s.mutex.RLock()
-	defer s.mutex.RUnlock()
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	// Nothing is started while the kill switch is engaged
+	if err := s.db.CheckKillSwitch(); err != nil {
+		log.Printf("Scheduler paused: %v", err)
+		return
+	}
 
 	now := time.Now()
 	for id, schedule := range s.schedules {
@@ -103,7 +109,8 @@ func (s *Scheduler) checkSchedules() {
 		}
 
 		if schedule.Type == ScheduleOneTime && !schedule.ExecuteAt.IsZero() && now.After(schedule.ExecuteAt) {
-			// Execute one-time schedule
+			// Disable the one-time schedule first, so it does not fire again while the run is going
+			schedule.Enabled = false
 			go s.executeSchedule(id)
 		} else if schedule.Type == ScheduleCron {
 			// In a real implementation, this would check if the cron expression matches the current time
@@ -126,17 +133,10 @@ func (s *Scheduler) executeSchedule(scheduleID string) {
 	log.Printf("Executing scheduled experiment %s", schedule.ExperimentID)
 
 	// Execute the experiment
-	_, err := s.executor.ExecuteExperiment(schedule.ExperimentID)
+	_, err := s.executor.ExecuteExperiment(schedule.ExperimentID, storage.RunTriggerScheduler)
 	if err != nil {
 		log.Printf("Failed to execute scheduled experiment %s: %v", schedule.ExperimentID, err)
 	}
-
-	// If it's a one-time schedule, disable it
-	if schedule.Type == ScheduleOneTime {
-		s.mutex.Lock()
-		schedule.Enabled = false
-		s.mutex.Unlock()
-	}
 }
 
 // AddSchedule adds a new schedule
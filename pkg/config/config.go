@@ -4,6 +4,7 @@ import (
 	"log"
 	"os"
 	"strconv"
+	"strings"
 
 	"github.com/joho/godotenv"
 )
@@ -21,10 +22,24 @@ type Config struct {
 	KubeConfigPath string
 	Namespace      string
 	MockKubernetes bool
+
+	// Name of this operator, telling its runs apart from those of other operators
+	OperatorID string
 	
 	// Monitoring configuration
 	PrometheusEnabled bool
 	GrafanaURL        string
+	
+	// Blast-radius policy, a limit of 0 is not enforced
+	BlastRadiusMaxPods          int
+	BlastRadiusMaxPercentage    int
+	BlastRadiusMinReadyReplicas int
+
+	// Namespaces no experiment may touch
+	ProtectedNamespaces []string
+
+	// Only namespaces and objects annotated as chaos-eligible may be targeted
+	OptInMode bool
 }
 
 // init loads environment variables from .env file if it exists
@@ -49,8 +64,16 @@ func Load() (*Config, error) {
 		KubeConfigPath:    getEnvOrDefault("KUBECONFIG", ""),
 		Namespace:         getEnvOrDefault("NAMESPACE", "default"),
 		MockKubernetes:    getEnvOrDefault("MOCK_KUBERNETES", "false") == "true",
+		OperatorID:        getEnvOrDefault("OPERATOR_ID", ""),
 		PrometheusEnabled: getEnvOrDefault("PROMETHEUS_ENABLED", "true") == "true",
 		GrafanaURL:        getEnvOrDefault("GRAFANA_URL", "http://localhost:3000"),
+		
+		BlastRadiusMaxPods:          getEnvIntOrDefault("BLAST_RADIUS_MAX_PODS", 10),
+		BlastRadiusMaxPercentage:    getEnvIntOrDefault("BLAST_RADIUS_MAX_PERCENTAGE", 50),
+		BlastRadiusMinReadyReplicas: getEnvIntOrDefault("BLAST_RADIUS_MIN_READY_REPLICAS", 1),
+
+		ProtectedNamespaces: getEnvListOrDefault("PROTECTED_NAMESPACES", "kube-system,kube-public,kube-node-lease"),
+		OptInMode:           getEnvOrDefault("OPT_IN_MODE", "false") == "true",
 	}, nil
 }
 
@@ -60,4 +83,31 @@ func getEnvOrDefault(key, defaultValue string) string {
 		return value
 	}
 	return defaultValue
-}
\ No newline at end of file
+}
+
+// getEnvIntOrDefault returns the integer value of the environment variable or
+// a default value when it is missing or not an integer
+func getEnvIntOrDefault(key string, defaultValue int) int {
+	value, exists := os.LookupEnv(key)
+	if !exists {
+		return defaultValue
+	}
+	parsed, err := strconv.Atoi(value)
+	if err != nil {
+		log.Printf("Invalid %s %q, using %d", key, value, defaultValue)
+		return defaultValue
+	}
+	return parsed
+}
+
+// getEnvListOrDefault returns the comma-separated values of the environment
+// variable or of a default value
+func getEnvListOrDefault(key, defaultValue string) []string {
+	values := []string{}
+	for _, value := range strings.Split(getEnvOrDefault(key, defaultValue), ",") {
+		if value = strings.TrimSpace(value); value != "" {
+			values = append(values, value)
+		}
+	}
+	return values
+}
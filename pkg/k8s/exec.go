@@ -0,0 +1,50 @@
+package k8s
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/client-go/rest"
+	"k8s.io/client-go/tools/remotecommand"
+)
+
+// ExecInContainer runs a command inside a container of a pod and returns its combined output
+func (c *Client) ExecInContainer(ctx context.Context, namespace, pod, container string, command []string) (string, error) {
+	// The fake clientset used by the mock client has no REST client to stream through
+	restClient, ok := c.clientset.CoreV1().RESTClient().(*rest.RESTClient)
+	if !ok || restClient == nil {
+		return "", fmt.Errorf("exec is not supported by this Kubernetes client")
+	}
+
+	req := restClient.Post().
+		Resource("pods").
+		Namespace(namespace).
+		Name(pod).
+		SubResource("exec").
+		VersionedParams(&corev1.PodExecOptions{
+			Container: container,
+			Command:   command,
+			Stdout:    true,
+			Stderr:    true,
+		}, scheme.ParameterCodec)
+
+	executor, err := remotecommand.NewSPDYExecutor(c.config, "POST", req.URL())
+	if err != nil {
+		return "", fmt.Errorf("failed to create executor: %w", err)
+	}
+
+	var stdout, stderr bytes.Buffer
+	err = executor.StreamWithContext(ctx, remotecommand.StreamOptions{
+		Stdout: &stdout,
+		Stderr: &stderr,
+	})
+	output := stdout.String() + stderr.String()
+	if err != nil {
+		return output, fmt.Errorf("failed to exec in %s/%s: %w", pod, container, err)
+	}
+
+	return output, nil
+}
@@ -1,5 +1,11 @@
 package operator
 
+import (
+	"context"
+
+	"github.com/flack/chaos-engineering-as-a-platform/pkg/chaos/experiments"
+)
+
 // ExperimentController defines the interface for experiment controllers.
 // All experiment controllers must implement these methods to be compatible
 // with the chaos operator.
@@ -7,9 +13,18 @@ type ExperimentController interface {
 	// Start starts the experiment
 	Start() error
 	
-	// Stop stops the experiment
-	Stop() error
+	// Stop stops the experiment, recording the reason on its run
+	Stop(reason string) error
 	
 	// GetStatus gets the status of the experiment
 	GetStatus() string
+	
+	// RunID returns the ID of the run the controller executes
+	RunID() string
+	
+	// Done returns a channel that is closed once the experiment has finished and been cleaned up
+	Done() <-chan struct{}
+	
+	// Plan reports what the experiment would do without starting it
+	Plan(ctx context.Context) (*experiments.Plan, error)
 }
\ No newline at end of file
@@ -1,16 +1,29 @@
 package operator
 
 import (
+	"context"
+	"errors"
 	"fmt"
 	"log"
 	"sync"
 	"time"
 
+	"github.com/google/uuid"
+
+	"github.com/flack/chaos-engineering-as-a-platform/pkg/chaos/executor"
+	"github.com/flack/chaos-engineering-as-a-platform/pkg/chaos/experiments"
+	"github.com/flack/chaos-engineering-as-a-platform/pkg/chaos/probes"
 	"github.com/flack/chaos-engineering-as-a-platform/pkg/k8s"
 	"github.com/flack/chaos-engineering-as-a-platform/pkg/monitoring"
 	"github.com/flack/chaos-engineering-as-a-platform/pkg/storage"
 )
 
+// experimentGracePeriod is the time an experiment may take beyond its duration to inject and recover faults
+const experimentGracePeriod = 2 * time.Minute
+
+// errStopped reports that a run ended because its controller was stopped
+var errStopped = errors.New("experiment stopped")
+
 // ExperimentConfig holds configuration for an experiment
 type ExperimentConfig struct {
 	ID             string
@@ -18,71 +31,100 @@ type ExperimentConfig struct {
 	Target         string
 	Params         map[string]string
 	Duration       int
+	Probes         []probes.Probe
+	Abort          *probes.AbortConditions
+	Trigger        storage.RunTrigger // What started the run
+	RunID          string             // Run the status and result are recorded on, set by the operator
 }
 
 // K8sExperimentController controls a single chaos experiment in Kubernetes
 type K8sExperimentController struct {
 	id             string
+	runID          string
 	experimentType string
 	target         string
 	params         map[string]string
 	duration       int
+	probes         []probes.Probe
+	abort          *probes.AbortConditions
+	blastRadius    *experiments.BlastRadius
+	protection     *experiments.Protection
 	client         *k8s.Client
+	db             *storage.Database
+	locks          storage.TargetLocks // Nil when targets are not locked
+	owner          string              // Owner of the run, its target lock and journal entries
 	metrics        *monitoring.Metrics
 	status         storage.ExperimentStatus
 	statusMu       sync.RWMutex
 	stopCh         chan struct{}
+	stopReason     string // Why the experiment was stopped, set before stopCh is closed
 	doneCh         chan struct{}
 }
 
-// NewExperimentController creates a new experiment controller
-func NewExperimentController(config *ExperimentConfig, client *k8s.Client, metrics *monitoring.Metrics) (*K8sExperimentController, error) {
+// NewExperimentController creates a new experiment controller. The database
+// holds the recovery journal and may be nil, in which case none is kept.
+func NewExperimentController(config *ExperimentConfig, client *k8s.Client, db *storage.Database, metrics *monitoring.Metrics) (*K8sExperimentController, error) {
 	if config == nil {
 		return nil, fmt.Errorf("experiment config cannot be nil")
 	}
-	
-	return &K8sExperimentController{
+
+	controller := &K8sExperimentController{
 		id:             config.ID,
+		runID:          config.RunID,
 		experimentType: config.Type,
 		target:         config.Target,
 		params:         config.Params,
 		duration:       config.Duration,
+		probes:         config.Probes,
+		abort:          config.Abort,
 		client:         client,
+		db:             db,
+		owner:          storage.JournalOwnerOperator,
 		metrics:        metrics,
 		status:         storage.StatusPending,
 		stopCh:         make(chan struct{}),
 		doneCh:         make(chan struct{}),
-	}, nil
+	}
+	if db != nil {
+		controller.locks = db
+	}
+	return controller, nil
 }
 
-// Start starts the experiment
+// Start starts the experiment. It fails if another run holds the lock of its target.
 func (c *K8sExperimentController) Start() error {
-	c.setStatus(storage.StatusRunning)
+	release, err := c.lockTarget()
+	if err != nil {
+		return err
+	}
+
+	c.setStatus(storage.StatusRunning, "Started")
 
 	go func() {
 		defer close(c.doneCh)
+		defer release()
 
-		// Execute the experiment based on its type
+		// Execute the experiment based on its registered type
 		var err error
-		switch c.experimentType {
-		case "pod-failure":
-			err = c.executePodFailure()
-		case "network-delay":
-			err = c.executeNetworkDelay()
-		case "cpu-stress":
-			err = c.executeCPUStress()
-		case "memory-stress":
-			err = c.executeMemoryStress()
-		default:
+		if definition, exists := experiments.Lookup(c.experimentType); exists {
+			err = c.runExperiment(definition)
+		} else {
 			err = fmt.Errorf("unsupported experiment type: %s", c.experimentType)
 		}
 
-		if err != nil {
+		var abortErr *experiments.AbortError
+		if errors.Is(err, errStopped) {
+			c.setStatus(storage.StatusCancelled, c.stopReason)
+		} else if errors.As(err, &abortErr) {
+			log.Printf("Experiment %s aborted: %s", c.id, abortErr.Abort.Reason)
+			c.setStatus(storage.StatusAborted, err.Error())
+			c.metrics.ExperimentsAborted.Inc()
+		} else if err != nil {
 			log.Printf("Experiment %s failed: %v", c.id, err)
-			c.setStatus(storage.StatusFailed)
+			c.setStatus(storage.StatusFailed, err.Error())
 			c.metrics.ExperimentsFailed.Inc()
 		} else {
-			c.setStatus(storage.StatusCompleted)
+			c.setStatus(storage.StatusCompleted, "Finished")
 			c.metrics.ExperimentsSucceeded.Inc()
 		}
 	}()
@@ -90,21 +132,42 @@ func (c *K8sExperimentController) Start() error {
 	return nil
 }
 
-// Stop stops the experiment
-func (c *K8sExperimentController) Stop() error {
+// lockTarget locks the target of the experiment for the run. It returns the
+// function that releases the lock, or a LockConflictError if another run
+// holds it. Without locks nothing is locked.
+func (c *K8sExperimentController) lockTarget() (func(), error) {
+	definition, exists := experiments.Lookup(c.experimentType)
+	if c.locks == nil || !exists {
+		return func() {}, nil
+	}
+
+	lock := &storage.TargetLock{
+		Target:       experiments.TargetKey(definition, c.spec()),
+		ExperimentID: c.id,
+		Owner:        c.owner,
+		AcquiredAt:   time.Now(),
+	}
+	lock.ExpiresAt = lock.AcquiredAt.Add(time.Duration(c.duration)*time.Second + experimentGracePeriod)
+	if err := c.locks.AcquireTargetLock(lock); err != nil {
+		return nil, err
+	}
+
+	return func() {
+		if err := c.locks.ReleaseTargetLock(lock.Target, c.id); err != nil {
+			log.Printf("Failed to release target lock of experiment %s: %v", c.id, err)
+		}
+	}, nil
+}
+
+// Stop stops the experiment for the reason and waits until its faults have been recovered
+func (c *K8sExperimentController) Stop(reason string) error {
 	// Signal the experiment to stop
+	c.stopReason = reason
 	close(c.stopCh)
 
 	// Wait for the experiment to finish
 	<-c.doneCh
 
-	// Update status if it was running
-	c.statusMu.Lock()
-	if c.status == storage.StatusRunning {
-		c.status = storage.StatusCancelled
-	}
-	c.statusMu.Unlock()
-
 	return nil
 }
 
@@ -115,53 +178,114 @@ func (c *K8sExperimentController) GetStatus() string {
 	return string(c.status)
 }
 
-// setStatus sets the status of the experiment
-func (c *K8sExperimentController) setStatus(status storage.ExperimentStatus) {
+// RunID returns the ID of the run the controller executes
+func (c *K8sExperimentController) RunID() string {
+	return c.runID
+}
+
+// Done returns a channel that is closed once the experiment has finished and its faults have been recovered
+func (c *K8sExperimentController) Done() <-chan struct{} {
+	return c.doneCh
+}
+
+// setStatus sets the status of the experiment and records the change and its reason on its run
+func (c *K8sExperimentController) setStatus(status storage.ExperimentStatus, reason string) {
 	c.statusMu.Lock()
 	c.status = status
 	c.statusMu.Unlock()
+
+	recordRun(c.db, c.runID, status, c.owner, reason)
 }
 
-// executeExperiment is a generic method to execute experiments
-func (c *K8sExperimentController) executeExperiment(experimentType string) error {
-	log.Printf("Executing %s experiment on target %s", experimentType, c.target)
-
-	// Simulate the experiment duration
-	select {
-	case <-time.After(time.Duration(c.duration) * time.Second):
-		log.Printf("%s experiment completed for target %s", experimentType, c.target)
-	case <-c.stopCh:
-		log.Printf("%s experiment cancelled for target %s", experimentType, c.target)
-		return nil
+// runExperiment runs the registered experiment type until it finishes or the controller is stopped
+func (c *K8sExperimentController) runExperiment(definition experiments.Definition) error {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	deps := experiments.Dependencies{
+		Clientset:   c.client.GetClientset(),
+		Executor:    c.client,
+		BlastRadius: c.blastRadius,
+		Protection:  c.protection,
+	}
+	if c.db != nil {
+		deadline := time.Now().Add(time.Duration(c.duration)*time.Second + experimentGracePeriod)
+		deps.Journal = executor.NewJournal(c.db, c.id, c.experimentType, c.owner, deadline)
 	}
 
-	return nil
+	// Cancel the experiment when the controller is stopped
+	go func() {
+		select {
+		case <-c.stopCh:
+			cancel()
+		case <-ctx.Done():
+		}
+	}()
+
+	result, err := experiments.Execute(ctx, definition, deps, c.spec())
+	if result != nil {
+		result.ID = uuid.New().String()
+		result.ExperimentID = c.id
+		result.RunID = c.runID
+		result.CalculateDuration()
+		c.metrics.ExperimentDuration.Observe(result.Duration)
+		c.metrics.TargetsAffected.Add(float64(len(result.AffectedResources)))
+
+		if c.db != nil {
+			if err := executor.SaveResult(c.db, result); err != nil {
+				log.Printf("Failed to save result of experiment %s: %v", c.id, err)
+			}
+		}
+	}
+
+	// A stopped experiment has already been cleaned up by Run
+	if err != nil && ctx.Err() != nil {
+		log.Printf("Experiment %s cancelled", c.id)
+		return errStopped
+	}
+
+	return err
 }
 
-// executePodFailure executes a pod failure experiment
-func (c *K8sExperimentController) executePodFailure() error {
-	// In a real implementation, this would use the Kubernetes API to find and delete pods
-	// based on the target selector
-	return c.executeExperiment("pod failure")
+// Plan reports what the experiment would do without starting it
+func (c *K8sExperimentController) Plan(ctx context.Context) (*experiments.Plan, error) {
+	definition, exists := experiments.Lookup(c.experimentType)
+	if !exists {
+		return nil, fmt.Errorf("unsupported experiment type: %s", c.experimentType)
+	}
+
+	return experiments.DryRun(ctx, definition, experiments.Dependencies{
+		Clientset:   c.client.GetClientset(),
+		Executor:    c.client,
+		BlastRadius: c.blastRadius,
+		Protection:  c.protection,
+	}, c.spec())
 }
 
-// executeNetworkDelay executes a network delay experiment
-func (c *K8sExperimentController) executeNetworkDelay() error {
-	// In a real implementation, this would use network policies or a CNI plugin
-	// to introduce network delays
-	return c.executeExperiment("network delay")
+// spec returns the resolved input the experiment is built from
+func (c *K8sExperimentController) spec() experiments.Spec {
+	return experiments.Spec{
+		Namespace: c.namespace(),
+		Selector:  c.selector(),
+		Duration:  c.duration,
+		Params:    c.params,
+		Probes:    c.probes,
+		Abort:     c.abort,
+	}
 }
 
-// executeCPUStress executes a CPU stress experiment
-func (c *K8sExperimentController) executeCPUStress() error {
-	// In a real implementation, this would deploy a stress testing pod
-	// to consume CPU resources
-	return c.executeExperiment("CPU stress")
+// namespace returns the namespace the experiment targets
+func (c *K8sExperimentController) namespace() string {
+	if namespace := c.params["namespace"]; namespace != "" {
+		return namespace
+	}
+	return c.client.GetNamespace()
 }
 
-// executeMemoryStress executes a memory stress experiment
-func (c *K8sExperimentController) executeMemoryStress() error {
-	// In a real implementation, this would deploy a stress testing pod
-	// to consume memory resources
-	return c.executeExperiment("memory stress")
+// selector returns the label selector for the experiment's targets
+func (c *K8sExperimentController) selector() string {
+	if selector := c.params["selector"]; selector != "" {
+		return selector
+	}
+	return c.target
 }
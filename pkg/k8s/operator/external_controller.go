@@ -1,26 +1,53 @@
 package operator
 
 import (
+	"context"
 	"fmt"
 	"log"
 	"net/http"
 	"time"
 
+	"github.com/flack/chaos-engineering-as-a-platform/pkg/chaos/experiments"
 	"github.com/flack/chaos-engineering-as-a-platform/pkg/monitoring"
+	"github.com/flack/chaos-engineering-as-a-platform/pkg/storage"
 )
 
 // ExternalExperimentController controls chaos experiments on external targets
 type ExternalExperimentController struct {
 	experimentID string
+	runID        string
 	targetURL    string
 	params       map[string]string
 	duration     int
 	client       *http.Client
 	metrics      *monitoring.Metrics
+	db           *storage.Database
+	owner        string // Operator that owns the run
 	stopCh       chan struct{}
+	stopReason   string // Why the experiment was stopped, set before stopCh is closed
+	doneCh       chan struct{}
 	status       string
 }
 
+func init() {
+	// External experiments are run by ExternalExperimentController rather than
+	// built from the registry, the definition only publishes their parameters
+	experiments.Register(experiments.Definition{
+		Type:        "external-target",
+		Description: "Ask an external system to inject a failure through its chaos endpoints",
+		Params: []experiments.Param{
+			{Name: "target_type", Description: "Routes the experiment to the external controller", Type: experiments.ParamString, Required: true, Enum: []string{"external"}},
+			{Name: "endpoint", Description: "Path called on the target URL to inject the failure", Type: experiments.ParamString, Required: true},
+			{Name: "type", Description: "Kind of chaos the external system injects", Type: experiments.ParamString, Required: true},
+			{Name: "auth_token", Description: "Bearer token sent to the external system", Type: experiments.ParamString},
+			{Name: "cleanup_endpoint", Description: "Path called on the target URL to remove the failure", Type: experiments.ParamString, Default: "/cleanup"},
+		},
+		New: func(deps experiments.Dependencies, spec experiments.Spec) (experiments.Experiment, error) {
+			return nil, fmt.Errorf("external-target experiments are run by the chaos operator's external controller")
+		},
+	})
+}
+
 // NewExternalExperimentController creates a new external experiment controller
 func NewExternalExperimentController(experimentID, targetURL string, params map[string]string, duration int, metrics *monitoring.Metrics) (*ExternalExperimentController, error) {
 	return &ExternalExperimentController{
@@ -31,7 +58,9 @@ func NewExternalExperimentController(experimentID, targetURL string, params map[
 		client:       &http.Client{Timeout: 10 * time.Second},
 		metrics:      metrics,
 		stopCh:       make(chan struct{}),
+		doneCh:       make(chan struct{}),
 		status:       "pending",
+		owner:        storage.JournalOwnerOperator,
 	}, nil
 }
 
@@ -41,6 +70,7 @@ func (c *ExternalExperimentController) Start() error {
 	
 	// Update status
 	c.status = "running"
+	recordRun(c.db, c.runID, storage.StatusRunning, c.owner, "Started")
 	
 	// Start the experiment in a goroutine
 	go c.runExperiment()
@@ -48,15 +78,31 @@ func (c *ExternalExperimentController) Start() error {
 	return nil
 }
 
-// Stop stops the external experiment
-func (c *ExternalExperimentController) Stop() error {
+// Plan reports the calls the external experiment would make
+func (c *ExternalExperimentController) Plan(ctx context.Context) (*experiments.Plan, error) {
+	cleanupEndpoint := c.params["cleanup_endpoint"]
+	if cleanupEndpoint == "" {
+		cleanupEndpoint = "/cleanup"
+	}
+
+	return &experiments.Plan{
+		ExperimentType:    "external-target",
+		AffectedResources: []string{c.targetURL},
+		Actions: []string{
+			fmt.Sprintf("POST %s%s to inject %s chaos", c.targetURL, c.params["endpoint"], c.params["type"]),
+			fmt.Sprintf("POST %s%s after %ds to clean up", c.targetURL, cleanupEndpoint, c.duration),
+		},
+	}, nil
+}
+
+// Stop stops the external experiment for the reason and waits until it has been cleaned up
+func (c *ExternalExperimentController) Stop(reason string) error {
 	log.Printf("Stopping external experiment %s", c.experimentID)
 	
-	// Signal the goroutine to stop
+	// Signal the goroutine to stop and wait for the cleanup
+	c.stopReason = reason
 	close(c.stopCh)
-	
-	// Update status
-	c.status = "stopped"
+	<-c.doneCh
 	
 	return nil
 }
@@ -66,8 +112,20 @@ func (c *ExternalExperimentController) GetStatus() string {
 	return c.status
 }
 
+// RunID returns the ID of the run the controller executes
+func (c *ExternalExperimentController) RunID() string {
+	return c.runID
+}
+
+// Done returns a channel that is closed once the external experiment has finished and been cleaned up
+func (c *ExternalExperimentController) Done() <-chan struct{} {
+	return c.doneCh
+}
+
 // runExperiment runs the external experiment
 func (c *ExternalExperimentController) runExperiment() {
+	defer close(c.doneCh)
+	
 	// Create a timer for the experiment duration
 	timer := time.NewTimer(time.Duration(c.duration) * time.Second)
 	defer timer.Stop()
@@ -76,25 +134,31 @@ func (c *ExternalExperimentController) runExperiment() {
 	if err := c.executeExperiment(); err != nil {
 		log.Printf("Error executing external experiment %s: %v", c.experimentID, err)
 		c.status = "failed"
+		recordRun(c.db, c.runID, storage.StatusFailed, c.owner, err.Error())
 		return
 	}
 	
 	// Wait for the experiment to complete or be stopped
+	var status storage.ExperimentStatus
+	var reason string
 	select {
 	case <-timer.C:
 		// Experiment completed successfully
 		log.Printf("External experiment %s completed", c.experimentID)
 		c.status = "completed"
+		status, reason = storage.StatusCompleted, "Finished"
 	case <-c.stopCh:
 		// Experiment was stopped
 		log.Printf("External experiment %s was stopped", c.experimentID)
 		c.status = "stopped"
+		status, reason = storage.StatusCancelled, c.stopReason
 	}
 	
 	// Clean up the experiment
 	if err := c.cleanupExperiment(); err != nil {
 		log.Printf("Error cleaning up external experiment %s: %v", c.experimentID, err)
 	}
+	recordRun(c.db, c.runID, status, c.owner, reason)
 }
 
 // executeExperiment executes the external experiment
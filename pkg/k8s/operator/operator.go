@@ -1,28 +1,48 @@
 package operator
 
 import (
+	"context"
+	"errors"
 	"fmt"
 	"log"
+	"sort"
 	"sync"
 	"time"
 
+	"github.com/google/uuid"
+
+	"github.com/flack/chaos-engineering-as-a-platform/pkg/chaos/executor"
+	"github.com/flack/chaos-engineering-as-a-platform/pkg/chaos/experiments"
 	"github.com/flack/chaos-engineering-as-a-platform/pkg/config"
 	"github.com/flack/chaos-engineering-as-a-platform/pkg/k8s"
 	"github.com/flack/chaos-engineering-as-a-platform/pkg/monitoring"
+	"github.com/flack/chaos-engineering-as-a-platform/pkg/storage"
 )
 
+// ErrNotRunning reports that the operator has no run of an experiment in progress
+var ErrNotRunning = errors.New("experiment is not running")
+
+// recoveryTimeout bounds the recovery of unfinished experiments at startup
+const recoveryTimeout = 5 * time.Minute
+
 // ChaosOperator represents the chaos operator that manages chaos experiments
 type ChaosOperator struct {
 	client       *k8s.Client
+	db           *storage.Database
+	locks        storage.TargetLocks
+	owner        string // Owner of the runs and locks of this operator
 	metrics      *monitoring.Metrics
 	stopCh       chan struct{}
 	wg           sync.WaitGroup
 	experiments  map[string]ExperimentController
 	experimentMu sync.RWMutex
+	blastRadius  experiments.BlastRadius
+	protection   experiments.Protection
 }
 
-// NewChaosOperator creates a new chaos operator
-func NewChaosOperator(cfg *config.Config, metrics *monitoring.Metrics) (*ChaosOperator, error) {
+// NewChaosOperator creates a new chaos operator. The database holds the
+// recovery journal and may be nil, in which case nothing is recovered.
+func NewChaosOperator(cfg *config.Config, db *storage.Database, metrics *monitoring.Metrics) (*ChaosOperator, error) {
 	var client *k8s.Client
 	var err error
 	
@@ -36,18 +56,57 @@ func NewChaosOperator(cfg *config.Config, metrics *monitoring.Metrics) (*ChaosOp
 		}
 	}
 
-	return &ChaosOperator{
+	owner := storage.JournalOwnerOperator
+	if cfg.OperatorID != "" {
+		owner += "/" + cfg.OperatorID
+	}
+
+	chaosOperator := &ChaosOperator{
 		client:      client,
+		db:          db,
+		owner:       owner,
 		metrics:     metrics,
 		stopCh:      make(chan struct{}),
 		experiments: make(map[string]ExperimentController),
-	}, nil
+		blastRadius: experiments.BlastRadius{
+			MaxPods:          cfg.BlastRadiusMaxPods,
+			MaxPercentage:    cfg.BlastRadiusMaxPercentage,
+			MinReadyReplicas: cfg.BlastRadiusMinReadyReplicas,
+		},
+		protection: experiments.Protection{
+			Namespaces: cfg.ProtectedNamespaces,
+			OptIn:      cfg.OptInMode,
+		},
+	}
+	if db != nil {
+		chaosOperator.locks = db
+	}
+	return chaosOperator, nil
+}
+
+// SetTargetLocks sets where the operator locks the targets of its runs, by
+// default the database
+func (o *ChaosOperator) SetTargetLocks(locks storage.TargetLocks) {
+	o.locks = locks
+}
+
+// GetClient returns the Kubernetes client the operator runs experiments with
+func (o *ChaosOperator) GetClient() *k8s.Client {
+	return o.client
 }
 
 // Start starts the chaos operator
 func (o *ChaosOperator) Start() error {
 	log.Println("Starting chaos operator...")
 
+	// Undo faults left behind by experiments that were running when the platform went down
+	if err := o.recoverExperiments(); err != nil {
+		return fmt.Errorf("failed to recover unfinished experiments: %w", err)
+	}
+	if err := o.failInterruptedRuns(); err != nil {
+		return fmt.Errorf("failed to end interrupted runs: %w", err)
+	}
+
 	// Start the controller loop
 	o.wg.Add(1)
 	go o.controllerLoop()
@@ -66,14 +125,102 @@ func (o *ChaosOperator) Stop() error {
 	o.wg.Wait()
 
 	// Stop all running experiments
-	o.experimentMu.Lock()
-	for id, exp := range o.experiments {
-		log.Printf("Stopping experiment %s", id)
-		if err := exp.Stop(); err != nil {
-			log.Printf("Error stopping experiment %s: %v", id, err)
+	o.StopAll("Stopped, the operator is shutting down")
+
+	return nil
+}
+
+// recoverExperiments runs the recover phase for every journaled change that
+// was never undone. Changes recorded by this operator belong to its previous
+// process and are always recovered; changes recorded by other operators or
+// executors are only recovered once their run is past its deadline, since it
+// may still be going.
+func (o *ChaosOperator) recoverExperiments() error {
+	if o.db == nil {
+		return nil
+	}
+
+	entries, err := o.db.ListUnresolvedJournalEntries()
+	if err != nil {
+		return err
+	}
+	if len(entries) == 0 {
+		return nil
+	}
+
+	log.Printf("Recovering %d unresolved changes from unfinished experiments", len(entries))
+
+	ctx, cancel := context.WithTimeout(context.Background(), recoveryTimeout)
+	defer cancel()
+
+	// The runs whose changes were recovered, by experiment and owner
+	type interrupted struct{ experimentID, owner string }
+	recovered := map[interrupted]bool{}
+	for _, entry := range entries {
+		if entry.Owner != o.owner && time.Now().Before(entry.Deadline) {
+			log.Printf("Skipping %s change for %s, experiment %s may still be running", entry.Kind, entry.Name, entry.ExperimentID)
+			continue
+		}
+
+		definition, exists := experiments.Lookup(entry.ExperimentType)
+		if !exists {
+			log.Printf("Cannot recover %s change for %s: unknown experiment type %s", entry.Kind, entry.Name, entry.ExperimentType)
+			continue
+		}
+
+		deps := experiments.Dependencies{
+			Clientset: o.client.GetClientset(),
+			Executor:  o.client,
+			Journal:   executor.NewJournal(o.db, entry.ExperimentID, entry.ExperimentType, entry.Owner, entry.Deadline),
+		}
+		if err := experiments.RecoverChange(ctx, definition, deps, executor.JournaledChange(entry)); err != nil {
+			log.Printf("Failed to recover %s change for %s in experiment %s: %v", entry.Kind, entry.Name, entry.ExperimentID, err)
+			continue
+		}
+
+		log.Printf("Recovered %s change for %s in experiment %s", entry.Kind, entry.Name, entry.ExperimentID)
+		recovered[interrupted{entry.ExperimentID, entry.Owner}] = true
+	}
+
+	// The runs that were interrupted did not complete. Only the runs of the
+	// owner that recorded the changes are failed, other owners may be running
+	// the same experiment.
+	for run := range recovered {
+		runs, err := o.db.ListExperimentRuns(run.experimentID)
+		if err != nil {
+			log.Printf("Failed to list runs of experiment %s: %v", run.experimentID, err)
+			continue
+		}
+		for _, candidate := range runs {
+			if candidate.Status == storage.StatusRunning && candidate.Owner == run.owner {
+				recordRun(o.db, candidate.ID, storage.StatusFailed, o.owner, "Interrupted before it finished, its changes were recovered")
+			}
+		}
+	}
+
+	return nil
+}
+
+// failInterruptedRuns fails every run this operator left pending or running
+// when it went down, and frees the targets they locked. None of them can
+// still be going, since their controllers died with the previous process.
+func (o *ChaosOperator) failInterruptedRuns() error {
+	if o.db == nil {
+		return nil
+	}
+
+	runs, err := o.db.ListUnfinishedRuns(o.owner)
+	if err != nil {
+		return err
+	}
+
+	for _, run := range runs {
+		log.Printf("Failing run %s of experiment %s, interrupted by a restart", run.ID, run.ExperimentID)
+		recordRun(o.db, run.ID, storage.StatusFailed, o.owner, "Interrupted before it finished, its operator restarted")
+		if err := o.db.ReleaseOwnedTargetLocks(run.ExperimentID, o.owner); err != nil {
+			log.Printf("Failed to release target locks of experiment %s: %v", run.ExperimentID, err)
 		}
 	}
-	o.experimentMu.Unlock()
 
 	return nil
 }
@@ -91,12 +238,66 @@ func (o *ChaosOperator) controllerLoop() {
 			log.Println("Controller loop stopping...")
 			return
 		case <-ticker.C:
+			// The kill switch may have been engaged, or runs asked to stop, by another process
+			o.enforceKillSwitch()
+			o.enforceStopRequests()
+
 			// Check for new experiments to run
 			o.reconcileExperiments()
 		}
 	}
 }
 
+// enforceKillSwitch stops every running experiment while the kill switch is engaged
+func (o *ChaosOperator) enforceKillSwitch() {
+	if o.db == nil {
+		return
+	}
+
+	var halted *storage.KillSwitchError
+	if err := o.db.CheckKillSwitch(); errors.As(err, &halted) {
+		if stopped := o.StopAll(halted.Error()); len(stopped) > 0 {
+			log.Printf("Stopped experiments %v: %v", stopped, halted)
+		}
+	}
+}
+
+// enforceStopRequests stops every running experiment whose run another process asked to stop
+func (o *ChaosOperator) enforceStopRequests() {
+	if o.db == nil {
+		return
+	}
+
+	// The requests are looked up without the lock, so new runs are not held up
+	o.experimentMu.RLock()
+	running := map[string]string{}
+	for experimentID, controller := range o.experiments {
+		if controller.GetStatus() == string(storage.StatusRunning) && controller.RunID() != "" {
+			running[experimentID] = controller.RunID()
+		}
+	}
+	o.experimentMu.RUnlock()
+
+	for experimentID, runID := range running {
+		request, err := o.db.GetStopRequest(runID)
+		if err != nil {
+			log.Printf("Failed to check stop request of run %s: %v", runID, err)
+			continue
+		}
+		if request == nil {
+			continue
+		}
+
+		// The run may have ended, or been replaced, since it was looked up
+		err = o.stop(experimentID, func(controller ExperimentController) bool {
+			return controller.RunID() == runID
+		}, request.Reason)
+		if err != nil && !errors.Is(err, ErrNotRunning) {
+			log.Printf("Failed to stop experiment %s: %v", experimentID, err)
+		}
+	}
+}
+
 // reconcileExperiments checks for new experiments and updates existing ones
 func (o *ChaosOperator) reconcileExperiments() {
 	// This would typically involve checking a Custom Resource Definition (CRD)
@@ -110,83 +311,200 @@ func (o *ChaosOperator) reconcileExperiments() {
 	// 4. Stop controllers for deleted experiments
 }
 
-// RunExperiment runs a new chaos experiment
-func (o *ChaosOperator) RunExperiment(config *ExperimentConfig) error {
+// RunExperiment runs a chaos experiment as a new run and returns the run
+func (o *ChaosOperator) RunExperiment(config *ExperimentConfig) (*storage.Run, error) {
 	o.experimentMu.Lock()
 	defer o.experimentMu.Unlock()
 
 	// Validate config
 	if config == nil {
-		return fmt.Errorf("experiment config cannot be nil")
+		return nil, fmt.Errorf("experiment config cannot be nil")
 	}
 
-	// Check if experiment is already running
-	if _, exists := o.experiments[config.ID]; exists {
-		return fmt.Errorf("experiment %s is already running", config.ID)
+	// Nothing runs while the kill switch is engaged
+	if o.db != nil {
+		if err := o.db.CheckKillSwitch(); err != nil {
+			return nil, err
+		}
 	}
 
-	// Determine if this is an external target
-	isExternal := false
-	if config.Params != nil && config.Params["target_type"] == "external" {
-		isExternal = true
+	// Check if experiment is already running. A controller that has finished
+	// is replaced by the new run.
+	if existing, exists := o.experiments[config.ID]; exists && existing.GetStatus() == string(storage.StatusRunning) {
+		return nil, fmt.Errorf("experiment %s is already running", config.ID)
 	}
 
-	var controller ExperimentController
+	// Every execution is a run of its own, the experiment is left as it is
+	run := &storage.Run{
+		ID:           uuid.New().String(),
+		ExperimentID: config.ID,
+		Trigger:      config.Trigger,
+		Status:       storage.StatusPending,
+		CreatedAt:    time.Now(),
+		Owner:        o.owner,
+	}
+	runConfig := *config
+	runConfig.RunID = run.ID
 
-	if isExternal {
-		// Create a new external experiment controller
-		extController, err := NewExternalExperimentController(config.ID, config.Target, config.Params, config.Duration, o.metrics)
-		if err != nil {
-			return fmt.Errorf("failed to create external experiment controller: %w", err)
-		}
-		controller = extController
-	} else {
-		// Create a new Kubernetes experiment controller
-		k8sController, err := NewExperimentController(config, o.client, o.metrics)
-		if err != nil {
-			return fmt.Errorf("failed to create experiment controller: %w", err)
+	controller, err := o.newController(&runConfig)
+	if err != nil {
+		return nil, err
+	}
+
+	if o.db != nil {
+		if err := o.db.CreateRun(run, o.owner); err != nil {
+			return nil, err
 		}
-		controller = k8sController
 	}
 
-	// Start the experiment
+	// Start the experiment. A run that never started is recorded as failed.
 	if err := controller.Start(); err != nil {
-		return fmt.Errorf("failed to start experiment: %w", err)
+		recordRun(o.db, run.ID, storage.StatusFailed, o.owner, err.Error())
+		return nil, fmt.Errorf("failed to start experiment: %w", err)
 	}
+	run.Status = storage.StatusRunning
 
 	// Store the controller
 	o.experiments[config.ID] = controller
 
-	// Update metrics
+	// Update metrics. The experiment is active until its controller is done,
+	// however the run ends.
 	o.metrics.ExperimentsExecuted.Inc()
 	o.metrics.ActiveExperiments.Inc()
+	go func() {
+		<-controller.Done()
+		o.metrics.ActiveExperiments.Dec()
+	}()
 
-	return nil
+	return run, nil
+}
+
+// PlanExperiment reports what running a chaos experiment would do, without running it
+func (o *ChaosOperator) PlanExperiment(ctx context.Context, config *ExperimentConfig) (*experiments.Plan, error) {
+	if config == nil {
+		return nil, fmt.Errorf("experiment config cannot be nil")
+	}
+
+	controller, err := o.newController(config)
+	if err != nil {
+		return nil, err
+	}
+
+	plan, err := controller.Plan(ctx)
+	if err != nil {
+		return nil, err
+	}
+	plan.ExperimentID = config.ID
+
+	return plan, nil
+}
+
+// newController creates the controller that runs an experiment
+func (o *ChaosOperator) newController(config *ExperimentConfig) (ExperimentController, error) {
+	// Determine if this is an external target
+	if config.Params != nil && config.Params["target_type"] == "external" {
+		// Create a new external experiment controller
+		controller, err := NewExternalExperimentController(config.ID, config.Target, config.Params, config.Duration, o.metrics)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create external experiment controller: %w", err)
+		}
+		controller.runID = config.RunID
+		controller.db = o.db
+		controller.owner = o.owner
+		return controller, nil
+	}
+
+	// Create a new Kubernetes experiment controller
+	controller, err := NewExperimentController(config, o.client, o.db, o.metrics)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create experiment controller: %w", err)
+	}
+	controller.blastRadius = &o.blastRadius
+	controller.protection = &o.protection
+	controller.locks = o.locks
+	controller.owner = o.owner
+	return controller, nil
+}
+
+// recordRun moves a run to a new status, made by the operator that owns it for
+// the reason. Without a database or a run nothing is recorded.
+func recordRun(db *storage.Database, runID string, status storage.ExperimentStatus, owner, reason string) {
+	if db == nil || runID == "" {
+		return
+	}
+
+	if err := db.TransitionRun(runID, status, owner, reason); err != nil {
+		log.Printf("Failed to update status of run %s: %v", runID, err)
+	}
 }
 
-// StopExperiment stops a running chaos experiment
+// StopExperiment stops a running chaos experiment and waits until its faults
+// have been recovered. It returns ErrNotRunning if the experiment is not running.
 func (o *ChaosOperator) StopExperiment(experimentID string) error {
+	return o.stop(experimentID, func(ExperimentController) bool { return true }, "Stopped")
+}
+
+// stop stops the running experiment for the reason if its controller matches,
+// and waits until its faults have been recovered. It returns ErrNotRunning if
+// the experiment is not running or its controller does not match.
+func (o *ChaosOperator) stop(experimentID string, match func(ExperimentController) bool, reason string) error {
 	o.experimentMu.Lock()
-	defer o.experimentMu.Unlock()
 
-	// Check if experiment exists
+	// Check if experiment is running
 	controller, exists := o.experiments[experimentID]
-	if !exists {
-		return fmt.Errorf("experiment %s not found", experimentID)
+	if !exists || controller.GetStatus() != string(storage.StatusRunning) || !match(controller) {
+		o.experimentMu.Unlock()
+		return fmt.Errorf("%w: %s", ErrNotRunning, experimentID)
 	}
 
+	// Remove the controller, so other experiments are not held up while this one recovers
+	delete(o.experiments, experimentID)
+	o.experimentMu.Unlock()
+
 	// Stop the experiment
-	if err := controller.Stop(); err != nil {
+	if err := controller.Stop(reason); err != nil {
 		return fmt.Errorf("failed to stop experiment: %w", err)
 	}
 
-	// Remove the controller
-	delete(o.experiments, experimentID)
+	return nil
+}
 
-	// Update metrics
-	o.metrics.ActiveExperiments.Dec()
+// StopAll stops every running experiment for the reason, which is recorded
+// on their runs, and waits until their faults have been recovered. It returns
+// the IDs of the experiments it stopped.
+func (o *ChaosOperator) StopAll(reason string) []string {
+	// The controllers are taken out under the lock, so new runs are not held
+	// up while these recover
+	o.experimentMu.Lock()
+	running := map[string]ExperimentController{}
+	for experimentID, controller := range o.experiments {
+		if controller.GetStatus() == string(storage.StatusRunning) {
+			running[experimentID] = controller
+			delete(o.experiments, experimentID)
+		}
+	}
+	o.experimentMu.Unlock()
 
-	return nil
+	// The experiments are stopped together, each waits for its own recovery
+	var wg sync.WaitGroup
+	for experimentID, controller := range running {
+		wg.Add(1)
+		go func(experimentID string, controller ExperimentController) {
+			defer wg.Done()
+			if err := controller.Stop(reason); err != nil {
+				log.Printf("Failed to stop experiment %s: %v", experimentID, err)
+			}
+		}(experimentID, controller)
+	}
+	wg.Wait()
+
+	stopped := []string{}
+	for experimentID := range running {
+		stopped = append(stopped, experimentID)
+	}
+	sort.Strings(stopped)
+
+	return stopped
 }
 
 // GetExperimentStatus gets the status of a running experiment
@@ -11,6 +11,7 @@ type Metrics struct {
 	ExperimentsExecuted   prometheus.Counter
 	ExperimentsSucceeded  prometheus.Counter
 	ExperimentsFailed     prometheus.Counter
+	ExperimentsAborted    prometheus.Counter
 	ExperimentDuration    prometheus.Histogram
 	ActiveExperiments     prometheus.Gauge
 	TargetsAffected       prometheus.Counter
@@ -37,6 +38,10 @@ func NewMetrics() *Metrics {
 			Name: "chaos_experiments_failed_total",
 			Help: "The total number of chaos experiments that failed",
 		}),
+		ExperimentsAborted: promauto.NewCounter(prometheus.CounterOpts{
+			Name: "chaos_experiments_aborted_total",
+			Help: "The total number of chaos experiments halted by an abort condition",
+		}),
 		ExperimentDuration: promauto.NewHistogram(prometheus.HistogramOpts{
 			Name:    "chaos_experiment_duration_seconds",
 			Help:    "The duration of chaos experiments in seconds",
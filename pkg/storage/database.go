@@ -33,6 +33,11 @@ func NewDatabase(connectionURL string) (*Database, error) {
 	return &Database{db: db}, nil
 }
 
+// NewDatabaseFromDB wraps a database connection that is already open
+func NewDatabaseFromDB(db *sql.DB) *Database {
+	return &Database{db: db}
+}
+
 // Close closes the database connection
 func (d *Database) Close() error {
 	return d.db.Close()
@@ -2,6 +2,7 @@ package storage
 
 import (
 	"database/sql"
+	"encoding/json"
 	"fmt"
 	"time"
 )
@@ -10,13 +11,16 @@ import (
 type ExperimentType string
 
 const (
-	PodFailure     ExperimentType = "pod-failure"
-	NetworkDelay   ExperimentType = "network-delay"
-	CPUStress      ExperimentType = "cpu-stress"
-	MemoryStress   ExperimentType = "memory-stress"
-	DiskFailure    ExperimentType = "disk-failure"
-	ServiceFailure ExperimentType = "service-failure"
-	ExternalTarget ExperimentType = "external-target"
+	PodFailure       ExperimentType = "pod-failure"
+	NetworkDelay     ExperimentType = "network-delay"
+	CPUStress        ExperimentType = "cpu-stress"
+	MemoryStress     ExperimentType = "memory-stress"
+	DiskFailure      ExperimentType = "disk-failure"
+	ServiceFailure   ExperimentType = "service-failure"
+	NetworkPartition ExperimentType = "network-partition"
+	NodeFailure      ExperimentType = "node-failure"
+	ScaleDown        ExperimentType = "scale-down"
+	ExternalTarget   ExperimentType = "external-target"
 )
 
 // ExperimentStatus defines the status of an experiment
@@ -28,6 +32,7 @@ const (
 	StatusCompleted ExperimentStatus = "completed"
 	StatusFailed    ExperimentStatus = "failed"
 	StatusCancelled ExperimentStatus = "cancelled"
+	StatusAborted   ExperimentStatus = "aborted" // Halted early by an abort condition
 )
 
 // Experiment represents a chaos experiment
@@ -36,21 +41,51 @@ type Experiment struct {
 	Name        string           `json:"name"`
 	Description string           `json:"description"`
 	Type        ExperimentType   `json:"type"`
-	Status      ExperimentStatus `json:"status"`
+	Status      ExperimentStatus `json:"status"` // Status of the latest run
 	Target      string           `json:"target"`
 	Parameters  string           `json:"parameters"`
 	CreatedAt   time.Time        `json:"created_at"`
 	UpdatedAt   time.Time        `json:"updated_at"`
-	Duration    int              `json:"duration"` // Duration in seconds
+	Duration    int              `json:"duration"`         // Duration in seconds
+	Probes      json.RawMessage  `json:"probes,omitempty"` // Steady-state hypothesis checked around each run, as JSON
+
+	AbortConditions json.RawMessage `json:"abort_conditions,omitempty"` // Conditions that halt a run early, as JSON
+}
+
+// experimentColumns selects an experiment from experiments e. Its status is
+// the status of its latest run, or the status it was created with if it never ran.
+const experimentColumns = `
+	e.id, e.name, e.description, e.type,
+	COALESCE((SELECT r.status FROM experiment_runs r WHERE r.experiment_id = e.id ORDER BY r.created_at DESC LIMIT 1), e.status),
+	e.target, e.parameters, e.created_at, e.updated_at, e.duration, e.probes, e.abort_conditions
+`
+
+// scanChecks keeps the probes and abort_conditions columns of an experiment
+func scanChecks(experiment *Experiment, probesJSON, abortJSON []byte) {
+	if len(probesJSON) > 0 {
+		experiment.Probes = json.RawMessage(probesJSON)
+	}
+	if len(abortJSON) > 0 {
+		experiment.AbortConditions = json.RawMessage(abortJSON)
+	}
 }
 
 // CreateExperiment creates a new experiment in the database
 func (d *Database) CreateExperiment(experiment *Experiment) error {
 	query := `
-		INSERT INTO experiments (id, name, description, type, status, target, parameters, created_at, updated_at, duration)
-		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10)
+		INSERT INTO experiments (id, name, description, type, status, target, parameters, created_at, updated_at, duration, probes, abort_conditions)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12)
 	`
 	
+	// Experiments without probes or abort conditions keep NULL columns
+	var probesJSON, abortJSON interface{}
+	if len(experiment.Probes) > 0 {
+		probesJSON = string(experiment.Probes)
+	}
+	if len(experiment.AbortConditions) > 0 {
+		abortJSON = string(experiment.AbortConditions)
+	}
+	
 	_, err := d.db.Exec(
 		query,
 		experiment.ID,
@@ -63,6 +98,8 @@ func (d *Database) CreateExperiment(experiment *Experiment) error {
 		experiment.CreatedAt,
 		experiment.UpdatedAt,
 		experiment.Duration,
+		probesJSON,
+		abortJSON,
 	)
 	
 	if err != nil {
@@ -75,12 +112,13 @@ func (d *Database) CreateExperiment(experiment *Experiment) error {
 // GetExperiment retrieves an experiment by ID
 func (d *Database) GetExperiment(id string) (*Experiment, error) {
 	query := `
-		SELECT id, name, description, type, status, target, parameters, created_at, updated_at, duration
-		FROM experiments
-		WHERE id = $1
+		SELECT ` + experimentColumns + `
+		FROM experiments e
+		WHERE e.id = $1
 	`
 	
 	var experiment Experiment
+	var probesJSON, abortJSON []byte
 	err := d.db.QueryRow(query, id).Scan(
 		&experiment.ID,
 		&experiment.Name,
@@ -92,6 +130,8 @@ func (d *Database) GetExperiment(id string) (*Experiment, error) {
 		&experiment.CreatedAt,
 		&experiment.UpdatedAt,
 		&experiment.Duration,
+		&probesJSON,
+		&abortJSON,
 	)
 	
 	if err != nil {
@@ -101,15 +141,17 @@ func (d *Database) GetExperiment(id string) (*Experiment, error) {
 		return nil, fmt.Errorf("failed to get experiment: %w", err)
 	}
 	
+	scanChecks(&experiment, probesJSON, abortJSON)
+	
 	return &experiment, nil
 }
 
 // ListExperiments retrieves all experiments
 func (d *Database) ListExperiments() ([]*Experiment, error) {
 	query := `
-		SELECT id, name, description, type, status, target, parameters, created_at, updated_at, duration
-		FROM experiments
-		ORDER BY created_at DESC
+		SELECT ` + experimentColumns + `
+		FROM experiments e
+		ORDER BY e.created_at DESC
 	`
 	
 	rows, err := d.db.Query(query)
@@ -121,6 +163,7 @@ func (d *Database) ListExperiments() ([]*Experiment, error) {
 	var experiments []*Experiment
 	for rows.Next() {
 		var experiment Experiment
+		var probesJSON, abortJSON []byte
 		err := rows.Scan(
 			&experiment.ID,
 			&experiment.Name,
@@ -132,10 +175,13 @@ func (d *Database) ListExperiments() ([]*Experiment, error) {
 			&experiment.CreatedAt,
 			&experiment.UpdatedAt,
 			&experiment.Duration,
+			&probesJSON,
+			&abortJSON,
 		)
 		if err != nil {
 			return nil, fmt.Errorf("failed to scan experiment: %w", err)
 		}
+		scanChecks(&experiment, probesJSON, abortJSON)
 		experiments = append(experiments, &experiment)
 	}
 	
@@ -146,22 +192,6 @@ func (d *Database) ListExperiments() ([]*Experiment, error) {
 	return experiments, nil
 }
 
-// UpdateExperimentStatus updates the status of an experiment
-func (d *Database) UpdateExperimentStatus(id string, status ExperimentStatus) error {
-	query := `
-		UPDATE experiments
-		SET status = $1, updated_at = $2
-		WHERE id = $3
-	`
-	
-	_, err := d.db.Exec(query, status, time.Now(), id)
-	if err != nil {
-		return fmt.Errorf("failed to update experiment status: %w", err)
-	}
-	
-	return nil
-}
-
 // DeleteExperiment deletes an experiment by ID
 func (d *Database) DeleteExperiment(id string) error {
 	query := `
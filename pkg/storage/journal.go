@@ -0,0 +1,126 @@
+package storage
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+)
+
+// Owners of journal entries, identifying the component that ran the experiment
+const (
+	JournalOwnerExecutor = "executor"
+	JournalOwnerOperator = "operator"
+)
+
+// JournalEntry is a change applied to the cluster by a running experiment
+type JournalEntry struct {
+	ID             string            `json:"id"`
+	ExperimentID   string            `json:"experiment_id"`
+	ExperimentType string            `json:"experiment_type"`
+	Owner          string            `json:"owner"`
+	Kind           string            `json:"kind"`
+	Namespace      string            `json:"namespace"`
+	Name           string            `json:"name"`
+	Data           map[string]string `json:"data,omitempty"`
+	Deadline       time.Time         `json:"deadline"` // Time by which the run that applied the change must have ended
+	CreatedAt      time.Time         `json:"created_at"`
+	ResolvedAt     *time.Time        `json:"resolved_at,omitempty"`
+}
+
+// RecordJournalEntry stores a change before it is applied
+func (d *Database) RecordJournalEntry(entry *JournalEntry) error {
+	query := `
+		INSERT INTO experiment_journal (id, experiment_id, experiment_type, owner, kind, namespace, name, data, deadline, created_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10)
+	`
+
+	data, err := json.Marshal(entry.Data)
+	if err != nil {
+		return fmt.Errorf("failed to marshal journal data: %w", err)
+	}
+
+	_, err = d.db.Exec(
+		query,
+		entry.ID,
+		entry.ExperimentID,
+		entry.ExperimentType,
+		entry.Owner,
+		entry.Kind,
+		entry.Namespace,
+		entry.Name,
+		string(data),
+		entry.Deadline,
+		entry.CreatedAt,
+	)
+
+	if err != nil {
+		return fmt.Errorf("failed to record journal entry: %w", err)
+	}
+
+	return nil
+}
+
+// ResolveJournalEntry marks a change as undone
+func (d *Database) ResolveJournalEntry(id string) error {
+	query := `
+		UPDATE experiment_journal
+		SET resolved_at = $1
+		WHERE id = $2 AND resolved_at IS NULL
+	`
+
+	_, err := d.db.Exec(query, time.Now(), id)
+	if err != nil {
+		return fmt.Errorf("failed to resolve journal entry: %w", err)
+	}
+
+	return nil
+}
+
+// ListUnresolvedJournalEntries retrieves the changes that have not been undone, most recent first
+func (d *Database) ListUnresolvedJournalEntries() ([]*JournalEntry, error) {
+	query := `
+		SELECT id, experiment_id, experiment_type, owner, kind, namespace, name, data, deadline, created_at
+		FROM experiment_journal
+		WHERE resolved_at IS NULL
+		ORDER BY created_at DESC
+	`
+
+	rows, err := d.db.Query(query)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list journal entries: %w", err)
+	}
+	defer rows.Close()
+
+	var entries []*JournalEntry
+	for rows.Next() {
+		var entry JournalEntry
+		var data []byte
+		err := rows.Scan(
+			&entry.ID,
+			&entry.ExperimentID,
+			&entry.ExperimentType,
+			&entry.Owner,
+			&entry.Kind,
+			&entry.Namespace,
+			&entry.Name,
+			&data,
+			&entry.Deadline,
+			&entry.CreatedAt,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan journal entry: %w", err)
+		}
+		if len(data) > 0 {
+			if err := json.Unmarshal(data, &entry.Data); err != nil {
+				return nil, fmt.Errorf("failed to parse journal data: %w", err)
+			}
+		}
+		entries = append(entries, &entry)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating journal entries: %w", err)
+	}
+
+	return entries, nil
+}
@@ -0,0 +1,82 @@
+package storage
+
+import (
+	"database/sql"
+	"fmt"
+	"time"
+)
+
+// KillSwitch is the emergency stop of the platform. While it is engaged no
+// experiment may run.
+type KillSwitch struct {
+	Engaged   bool      `json:"engaged"`
+	Actor     string    `json:"actor,omitempty"` // Who flipped the switch last
+	Reason    string    `json:"reason,omitempty"`
+	ChangedAt time.Time `json:"changed_at,omitempty"`
+}
+
+// KillSwitchError reports that an experiment was refused because the kill switch is engaged
+type KillSwitchError struct {
+	Switch *KillSwitch
+}
+
+func (e *KillSwitchError) Error() string {
+	return fmt.Sprintf("chaos is halted by the kill switch, engaged by %s at %s", e.Switch.Actor, e.Switch.ChangedAt.Format(time.RFC3339))
+}
+
+// SetKillSwitch engages or clears the kill switch. Every flip is kept, the
+// latest one is the state of the switch.
+func (d *Database) SetKillSwitch(state *KillSwitch) error {
+	query := `
+		INSERT INTO kill_switch_changes (engaged, actor, reason, changed_at)
+		VALUES ($1, $2, $3, $4)
+	`
+
+	if _, err := d.db.Exec(query, state.Engaged, state.Actor, state.Reason, state.ChangedAt); err != nil {
+		return fmt.Errorf("failed to set kill switch: %w", err)
+	}
+
+	return nil
+}
+
+// GetKillSwitch retrieves the state of the kill switch. A switch that was
+// never flipped is not engaged.
+func (d *Database) GetKillSwitch() (*KillSwitch, error) {
+	query := `
+		SELECT engaged, actor, reason, changed_at
+		FROM kill_switch_changes
+		ORDER BY id DESC
+		LIMIT 1
+	`
+
+	var state KillSwitch
+	var reason sql.NullString
+	err := d.db.QueryRow(query).Scan(
+		&state.Engaged,
+		&state.Actor,
+		&reason,
+		&state.ChangedAt,
+	)
+
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return &KillSwitch{}, nil
+		}
+		return nil, fmt.Errorf("failed to get kill switch: %w", err)
+	}
+	state.Reason = reason.String
+
+	return &state, nil
+}
+
+// CheckKillSwitch returns a KillSwitchError if the kill switch is engaged
+func (d *Database) CheckKillSwitch() error {
+	state, err := d.GetKillSwitch()
+	if err != nil {
+		return err
+	}
+	if state.Engaged {
+		return &KillSwitchError{Switch: state}
+	}
+	return nil
+}
@@ -0,0 +1,162 @@
+package storage
+
+import (
+	"database/sql"
+	"fmt"
+	"time"
+)
+
+// TargetLock reserves a target for a single run, so that two experiments
+// cannot act on it at the same time
+type TargetLock struct {
+	Target       string    `json:"target"` // Key of the target, see experiments.TargetKey
+	ExperimentID string    `json:"experiment_id"`
+	Owner        string    `json:"owner"` // Executor or operator instance running the experiment
+	AcquiredAt   time.Time `json:"acquired_at"`
+	ExpiresAt    time.Time `json:"expires_at"` // Time after which the lock is free again, even if it was never released
+}
+
+// TargetLocks takes and frees the locks of targets
+type TargetLocks interface {
+	AcquireTargetLock(lock *TargetLock) error
+	ReleaseTargetLock(target, experimentID string) error
+}
+
+// LockConflictError reports that another run holds the lock of a target
+type LockConflictError struct {
+	Lock *TargetLock
+}
+
+func (e *LockConflictError) Error() string {
+	return fmt.Sprintf("target %s is locked by experiment %s until %s", e.Lock.Target, e.Lock.ExperimentID, e.Lock.ExpiresAt.Format(time.RFC3339))
+}
+
+// AcquireTargetLock takes the lock of a target. A lock that has expired is
+// taken over, any other returns a LockConflictError naming its holder.
+func (d *Database) AcquireTargetLock(lock *TargetLock) error {
+	query := `
+		INSERT INTO target_locks (target, experiment_id, owner, acquired_at, expires_at)
+		VALUES ($1, $2, $3, $4, $5)
+		ON CONFLICT (target) DO UPDATE
+		SET experiment_id = EXCLUDED.experiment_id, owner = EXCLUDED.owner, acquired_at = EXCLUDED.acquired_at, expires_at = EXCLUDED.expires_at
+		WHERE target_locks.expires_at < EXCLUDED.acquired_at
+	`
+
+	// The holder may release the lock between the insert and the lookup, in
+	// which case the insert is tried once more
+	for attempt := 0; ; attempt++ {
+		res, err := d.db.Exec(query, lock.Target, lock.ExperimentID, lock.Owner, lock.AcquiredAt, lock.ExpiresAt)
+		if err != nil {
+			return fmt.Errorf("failed to acquire target lock: %w", err)
+		}
+
+		acquired, err := res.RowsAffected()
+		if err != nil {
+			return fmt.Errorf("failed to acquire target lock: %w", err)
+		}
+		if acquired > 0 {
+			return nil
+		}
+
+		holder, err := d.GetTargetLock(lock.Target)
+		if err == nil {
+			return &LockConflictError{Lock: holder}
+		}
+		if attempt > 0 {
+			return err
+		}
+	}
+}
+
+// ReleaseTargetLock frees the lock of a target if the experiment still holds it
+func (d *Database) ReleaseTargetLock(target, experimentID string) error {
+	query := `
+		DELETE FROM target_locks
+		WHERE target = $1 AND experiment_id = $2
+	`
+
+	if _, err := d.db.Exec(query, target, experimentID); err != nil {
+		return fmt.Errorf("failed to release target lock: %w", err)
+	}
+
+	return nil
+}
+
+// ReleaseOwnedTargetLocks frees every lock the owner holds for the experiment
+func (d *Database) ReleaseOwnedTargetLocks(experimentID, owner string) error {
+	query := `
+		DELETE FROM target_locks
+		WHERE experiment_id = $1 AND owner = $2
+	`
+
+	if _, err := d.db.Exec(query, experimentID, owner); err != nil {
+		return fmt.Errorf("failed to release target locks: %w", err)
+	}
+
+	return nil
+}
+
+// GetTargetLock retrieves the lock of a target
+func (d *Database) GetTargetLock(target string) (*TargetLock, error) {
+	query := `
+		SELECT target, experiment_id, owner, acquired_at, expires_at
+		FROM target_locks
+		WHERE target = $1
+	`
+
+	var lock TargetLock
+	err := d.db.QueryRow(query, target).Scan(
+		&lock.Target,
+		&lock.ExperimentID,
+		&lock.Owner,
+		&lock.AcquiredAt,
+		&lock.ExpiresAt,
+	)
+
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, fmt.Errorf("target lock not found: %s", target)
+		}
+		return nil, fmt.Errorf("failed to get target lock: %w", err)
+	}
+
+	return &lock, nil
+}
+
+// ListTargetLocks retrieves the locks that have not expired, oldest first
+func (d *Database) ListTargetLocks() ([]*TargetLock, error) {
+	query := `
+		SELECT target, experiment_id, owner, acquired_at, expires_at
+		FROM target_locks
+		WHERE expires_at > $1
+		ORDER BY acquired_at
+	`
+
+	rows, err := d.db.Query(query, time.Now())
+	if err != nil {
+		return nil, fmt.Errorf("failed to list target locks: %w", err)
+	}
+	defer rows.Close()
+
+	locks := []*TargetLock{}
+	for rows.Next() {
+		var lock TargetLock
+		err := rows.Scan(
+			&lock.Target,
+			&lock.ExperimentID,
+			&lock.Owner,
+			&lock.AcquiredAt,
+			&lock.ExpiresAt,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan target lock: %w", err)
+		}
+		locks = append(locks, &lock)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating target locks: %w", err)
+	}
+
+	return locks, nil
+}
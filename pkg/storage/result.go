@@ -0,0 +1,145 @@
+package storage
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// ExperimentResult is the stored result of an experiment run. The parts of
+// the result that have no column of their own are kept as a JSON document.
+type ExperimentResult struct {
+	ID           string             `json:"id"`
+	ExperimentID string             `json:"experiment_id"`
+	RunID        string             `json:"run_id,omitempty"` // Empty for results saved without a run
+	Status       ExperimentStatus   `json:"status"`
+	StartTime    time.Time          `json:"start_time"`
+	EndTime      time.Time          `json:"end_time"` // Zero for a run that never got as far as finishing
+	Metrics      map[string]float64 `json:"metrics,omitempty"`
+	Logs         []string           `json:"logs,omitempty"`
+	Details      json.RawMessage    `json:"details,omitempty"`
+}
+
+// SaveExperimentResult stores the result of an experiment run
+func (d *Database) SaveExperimentResult(result *ExperimentResult) error {
+	query := `
+		INSERT INTO experiment_results (id, experiment_id, run_id, status, start_time, end_time, metrics, logs, details)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)
+	`
+
+	metrics := result.Metrics
+	if metrics == nil {
+		metrics = map[string]float64{}
+	}
+	metricsJSON, err := json.Marshal(metrics)
+	if err != nil {
+		return fmt.Errorf("failed to marshal result metrics: %w", err)
+	}
+
+	details := result.Details
+	if len(details) == 0 {
+		details = json.RawMessage("{}")
+	}
+
+	// A run that never got as far as finishing has no end time
+	var endTime interface{}
+	if !result.EndTime.IsZero() {
+		endTime = result.EndTime
+	}
+
+	// Results saved without a run keep a NULL run_id
+	var runID interface{}
+	if result.RunID != "" {
+		runID = result.RunID
+	}
+
+	_, err = d.db.Exec(
+		query,
+		result.ID,
+		result.ExperimentID,
+		runID,
+		result.Status,
+		result.StartTime,
+		endTime,
+		string(metricsJSON),
+		strings.Join(result.Logs, "\n"),
+		string(details),
+	)
+
+	if err != nil {
+		return fmt.Errorf("failed to save experiment result: %w", err)
+	}
+
+	return nil
+}
+
+// ListExperimentResults retrieves the results of an experiment, most recent first
+func (d *Database) ListExperimentResults(experimentID string) ([]*ExperimentResult, error) {
+	return d.listResults("experiment_id", experimentID)
+}
+
+// listResults retrieves the results whose column, experiment_id or run_id,
+// matches the value, most recent first
+func (d *Database) listResults(column, value string) ([]*ExperimentResult, error) {
+	query := `
+		SELECT id, experiment_id, run_id, status, start_time, end_time, metrics, logs, details
+		FROM experiment_results
+		WHERE ` + column + ` = $1
+		ORDER BY start_time DESC
+	`
+
+	rows, err := d.db.Query(query, value)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list experiment results: %w", err)
+	}
+	defer rows.Close()
+
+	results := []*ExperimentResult{}
+	for rows.Next() {
+		var result ExperimentResult
+		var runID sql.NullString
+		var endTime sql.NullTime
+		var metricsJSON, detailsJSON []byte
+		var logs *string
+		err := rows.Scan(
+			&result.ID,
+			&result.ExperimentID,
+			&runID,
+			&result.Status,
+			&result.StartTime,
+			&endTime,
+			&metricsJSON,
+			&logs,
+			&detailsJSON,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan experiment result: %w", err)
+		}
+
+		result.RunID = runID.String
+		if endTime.Valid {
+			result.EndTime = endTime.Time
+		}
+		if len(metricsJSON) > 0 {
+			if err := json.Unmarshal(metricsJSON, &result.Metrics); err != nil {
+				return nil, fmt.Errorf("failed to parse result metrics: %w", err)
+			}
+		}
+		if logs != nil && *logs != "" {
+			result.Logs = strings.Split(*logs, "\n")
+		}
+		if len(detailsJSON) > 0 {
+			result.Details = json.RawMessage(detailsJSON)
+		}
+
+		results = append(results, &result)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating experiment results: %w", err)
+	}
+
+	return results, nil
+}
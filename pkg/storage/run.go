@@ -0,0 +1,184 @@
+package storage
+
+import (
+	"database/sql"
+	"fmt"
+	"time"
+)
+
+// RunTrigger names what started a run
+type RunTrigger string
+
+const (
+	RunTriggerAPI       RunTrigger = "api"
+	RunTriggerScheduler RunTrigger = "scheduler"
+)
+
+// Run is a single execution of an experiment. The experiment holds the
+// definition, every execution gets a run of its own.
+type Run struct {
+	ID           string           `json:"id"`
+	ExperimentID string           `json:"experiment_id"`
+	Trigger      RunTrigger       `json:"trigger"`
+	Status       ExperimentStatus `json:"status"`
+	Error        string           `json:"error,omitempty"`
+	CreatedAt    time.Time        `json:"created_at"`
+	StartedAt    *time.Time       `json:"started_at,omitempty"`
+	EndedAt      *time.Time       `json:"ended_at,omitempty"`
+	Owner        string           `json:"owner"` // Executor or operator instance running it
+
+	Result      *ExperimentResult `json:"result,omitempty"`      // Set once the run has saved its result
+	Transitions []Transition      `json:"transitions,omitempty"` // Every status change, oldest first
+}
+
+// CreateRun stores a new run and records its status as the first transition,
+// made by the actor
+func (d *Database) CreateRun(run *Run, actor string) error {
+	query := `
+		INSERT INTO experiment_runs (id, experiment_id, trigger, status, error, created_at, started_at, ended_at, owner)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)
+	`
+
+	tx, err := d.db.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to create run: %w", err)
+	}
+	defer tx.Rollback()
+
+	_, err = tx.Exec(
+		query,
+		run.ID,
+		run.ExperimentID,
+		run.Trigger,
+		run.Status,
+		run.Error,
+		run.CreatedAt,
+		run.StartedAt,
+		run.EndedAt,
+		run.Owner,
+	)
+
+	if err != nil {
+		return fmt.Errorf("failed to create run: %w", err)
+	}
+
+	created := &Transition{To: run.Status, Actor: actor, Reason: "Triggered by " + string(run.Trigger), Time: run.CreatedAt}
+	if err := recordTransition(tx, run.ID, created); err != nil {
+		return err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to create run: %w", err)
+	}
+
+	return nil
+}
+
+// scanRun reads a run from a row of experiment_runs
+func scanRun(row interface{ Scan(...interface{}) error }) (*Run, error) {
+	var run Run
+	var message sql.NullString
+	var startedAt, endedAt sql.NullTime
+	err := row.Scan(
+		&run.ID,
+		&run.ExperimentID,
+		&run.Trigger,
+		&run.Status,
+		&message,
+		&run.CreatedAt,
+		&startedAt,
+		&endedAt,
+		&run.Owner,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	run.Error = message.String
+	if startedAt.Valid {
+		run.StartedAt = &startedAt.Time
+	}
+	if endedAt.Valid {
+		run.EndedAt = &endedAt.Time
+	}
+	return &run, nil
+}
+
+// GetRun retrieves a run by ID together with its result and status changes
+func (d *Database) GetRun(id string) (*Run, error) {
+	query := `
+		SELECT id, experiment_id, trigger, status, error, created_at, started_at, ended_at, owner
+		FROM experiment_runs
+		WHERE id = $1
+	`
+
+	run, err := scanRun(d.db.QueryRow(query, id))
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, fmt.Errorf("run not found: %s", id)
+		}
+		return nil, fmt.Errorf("failed to get run: %w", err)
+	}
+
+	results, err := d.listResults("run_id", id)
+	if err != nil {
+		return nil, err
+	}
+	if len(results) > 0 {
+		run.Result = results[0]
+	}
+
+	if run.Transitions, err = d.ListRunTransitions(id); err != nil {
+		return nil, err
+	}
+
+	return run, nil
+}
+
+// ListExperimentRuns retrieves the runs of an experiment, most recent first
+func (d *Database) ListExperimentRuns(experimentID string) ([]*Run, error) {
+	query := `
+		SELECT id, experiment_id, trigger, status, error, created_at, started_at, ended_at, owner
+		FROM experiment_runs
+		WHERE experiment_id = $1
+		ORDER BY created_at DESC
+	`
+
+	return d.queryRuns(query, experimentID)
+}
+
+// ListUnfinishedRuns retrieves the pending and running runs of an owner, oldest first
+func (d *Database) ListUnfinishedRuns(owner string) ([]*Run, error) {
+	query := `
+		SELECT id, experiment_id, trigger, status, error, created_at, started_at, ended_at, owner
+		FROM experiment_runs
+		WHERE owner = $1 AND status IN ($2, $3)
+		ORDER BY created_at
+	`
+
+	return d.queryRuns(query, owner, StatusPending, StatusRunning)
+}
+
+// queryRuns runs a query selecting the columns scanRun reads
+func (d *Database) queryRuns(query string, args ...interface{}) ([]*Run, error) {
+	rows, err := d.db.Query(query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list runs: %w", err)
+	}
+	defer rows.Close()
+
+	runs := []*Run{}
+	for rows.Next() {
+		run, err := scanRun(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan run: %w", err)
+		}
+		runs = append(runs, run)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating runs: %w", err)
+	}
+
+	return runs, nil
+}
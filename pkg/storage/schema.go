@@ -18,7 +18,9 @@ func (d *Database) InitSchema() error {
 			parameters JSONB,
 			created_at TIMESTAMP NOT NULL,
 			updated_at TIMESTAMP NOT NULL,
-			duration INTEGER NOT NULL
+			duration INTEGER NOT NULL,
+			probes JSONB,
+			abort_conditions JSONB
 		)
 	`
 	
@@ -46,22 +48,151 @@ func (d *Database) InitSchema() error {
 			end_time TIMESTAMP,
 			metrics JSONB,
 			logs TEXT,
+			details JSONB,
+			run_id VARCHAR(36) REFERENCES experiment_runs(id) ON DELETE CASCADE,
 			FOREIGN KEY (experiment_id) REFERENCES experiments(id) ON DELETE CASCADE
 		)
 	`
 	
+	// Create experiment runs table, one row for every execution of an experiment
+	runsTable := `
+		CREATE TABLE IF NOT EXISTS experiment_runs (
+			id VARCHAR(36) PRIMARY KEY,
+			experiment_id VARCHAR(36) NOT NULL,
+			trigger VARCHAR(50) NOT NULL,
+			status VARCHAR(50) NOT NULL,
+			error TEXT,
+			created_at TIMESTAMP NOT NULL,
+			started_at TIMESTAMP,
+			ended_at TIMESTAMP,
+			owner VARCHAR(255) NOT NULL DEFAULT '',
+			FOREIGN KEY (experiment_id) REFERENCES experiments(id) ON DELETE CASCADE
+		)
+	`
+	
+	// Create run transitions table, the history of the status of every run
+	transitionsTable := `
+		CREATE TABLE IF NOT EXISTS run_transitions (
+			id BIGSERIAL PRIMARY KEY,
+			run_id VARCHAR(36) NOT NULL,
+			from_status VARCHAR(50) NOT NULL,
+			to_status VARCHAR(50) NOT NULL,
+			actor VARCHAR(255) NOT NULL,
+			reason TEXT,
+			created_at TIMESTAMP NOT NULL,
+			FOREIGN KEY (run_id) REFERENCES experiment_runs(id) ON DELETE CASCADE
+		)
+	`
+	
+	// Create experiment journal table. It has no foreign key so that changes
+	// still get recovered when their experiment has been deleted.
+	journalTable := `
+		CREATE TABLE IF NOT EXISTS experiment_journal (
+			id VARCHAR(36) PRIMARY KEY,
+			experiment_id VARCHAR(36) NOT NULL,
+			experiment_type VARCHAR(50) NOT NULL,
+			owner VARCHAR(50) NOT NULL,
+			kind VARCHAR(50) NOT NULL,
+			namespace VARCHAR(255) NOT NULL,
+			name VARCHAR(255) NOT NULL,
+			data JSONB,
+			deadline TIMESTAMP NOT NULL,
+			created_at TIMESTAMP NOT NULL,
+			resolved_at TIMESTAMP
+		)
+	`
+	
+	// Create target locks table, keyed by the target so that one run holds it at a time
+	locksTable := `
+		CREATE TABLE IF NOT EXISTS target_locks (
+			target VARCHAR(512) PRIMARY KEY,
+			experiment_id VARCHAR(36) NOT NULL,
+			owner VARCHAR(50) NOT NULL,
+			acquired_at TIMESTAMP NOT NULL,
+			expires_at TIMESTAMP NOT NULL
+		)
+	`
+	
+	// Create kill switch table, every flip of the switch with who made it
+	killSwitchTable := `
+		CREATE TABLE IF NOT EXISTS kill_switch_changes (
+			id BIGSERIAL PRIMARY KEY,
+			engaged BOOLEAN NOT NULL,
+			actor VARCHAR(255) NOT NULL,
+			reason TEXT,
+			changed_at TIMESTAMP NOT NULL
+		)
+	`
+	
+	// Create stop requests table, the runs another process asked their owner to stop
+	stopRequestsTable := `
+		CREATE TABLE IF NOT EXISTS run_stop_requests (
+			run_id VARCHAR(36) PRIMARY KEY,
+			reason TEXT,
+			requested_at TIMESTAMP NOT NULL,
+			FOREIGN KEY (run_id) REFERENCES experiment_runs(id) ON DELETE CASCADE
+		)
+	`
+	
 	// Execute the schema creation
 	if _, err := d.db.Exec(experimentsTable); err != nil {
 		return fmt.Errorf("failed to create experiments table: %w", err)
 	}
 	
+	// Databases created before probes and abort conditions were supported lack their columns
+	if _, err := d.db.Exec(`ALTER TABLE experiments ADD COLUMN IF NOT EXISTS probes JSONB`); err != nil {
+		return fmt.Errorf("failed to add probes column to experiments table: %w", err)
+	}
+	if _, err := d.db.Exec(`ALTER TABLE experiments ADD COLUMN IF NOT EXISTS abort_conditions JSONB`); err != nil {
+		return fmt.Errorf("failed to add abort_conditions column to experiments table: %w", err)
+	}
+	
 	if _, err := d.db.Exec(targetsTable); err != nil {
 		return fmt.Errorf("failed to create targets table: %w", err)
 	}
 	
+	if _, err := d.db.Exec(runsTable); err != nil {
+		return fmt.Errorf("failed to create experiment_runs table: %w", err)
+	}
+	
+	// Databases created before runs had owners lack the owner column
+	if _, err := d.db.Exec(`ALTER TABLE experiment_runs ADD COLUMN IF NOT EXISTS owner VARCHAR(255) NOT NULL DEFAULT ''`); err != nil {
+		return fmt.Errorf("failed to add owner column to experiment_runs table: %w", err)
+	}
+	
+	if _, err := d.db.Exec(transitionsTable); err != nil {
+		return fmt.Errorf("failed to create run_transitions table: %w", err)
+	}
+	
 	if _, err := d.db.Exec(resultsTable); err != nil {
 		return fmt.Errorf("failed to create experiment_results table: %w", err)
 	}
 	
+	// Databases created before results were persisted lack the details column
+	if _, err := d.db.Exec(`ALTER TABLE experiment_results ADD COLUMN IF NOT EXISTS details JSONB`); err != nil {
+		return fmt.Errorf("failed to add details column to experiment_results table: %w", err)
+	}
+	
+	// Databases created before runs were tracked lack the run_id column
+	if _, err := d.db.Exec(`ALTER TABLE experiment_results ADD COLUMN IF NOT EXISTS run_id VARCHAR(36) REFERENCES experiment_runs(id) ON DELETE CASCADE`); err != nil {
+		return fmt.Errorf("failed to add run_id column to experiment_results table: %w", err)
+	}
+	
+	if _, err := d.db.Exec(journalTable); err != nil {
+		return fmt.Errorf("failed to create experiment_journal table: %w", err)
+	}
+	
+	if _, err := d.db.Exec(locksTable); err != nil {
+		return fmt.Errorf("failed to create target_locks table: %w", err)
+	}
+	
+	if _, err := d.db.Exec(killSwitchTable); err != nil {
+		return fmt.Errorf("failed to create kill_switch_changes table: %w", err)
+	}
+	
+	if _, err := d.db.Exec(stopRequestsTable); err != nil {
+		return fmt.Errorf("failed to create run_stop_requests table: %w", err)
+	}
+	
 	return nil
 }
\ No newline at end of file
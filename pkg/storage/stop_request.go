@@ -0,0 +1,59 @@
+package storage
+
+import (
+	"database/sql"
+	"fmt"
+	"time"
+)
+
+// StopRequest asks the process that owns a run to stop it. A run can only be
+// stopped by the process running it, which checks for requests as it checks
+// the kill switch.
+type StopRequest struct {
+	RunID       string    `json:"run_id"`
+	Reason      string    `json:"reason,omitempty"`
+	RequestedAt time.Time `json:"requested_at"`
+}
+
+// RequestRunStop records a request to stop a run. A run is only asked to stop
+// once, later requests are ignored.
+func (d *Database) RequestRunStop(request *StopRequest) error {
+	query := `
+		INSERT INTO run_stop_requests (run_id, reason, requested_at)
+		VALUES ($1, $2, $3)
+		ON CONFLICT (run_id) DO NOTHING
+	`
+
+	if _, err := d.db.Exec(query, request.RunID, request.Reason, request.RequestedAt); err != nil {
+		return fmt.Errorf("failed to request run stop: %w", err)
+	}
+
+	return nil
+}
+
+// GetStopRequest retrieves the request to stop a run, or nil if its stop was never requested
+func (d *Database) GetStopRequest(runID string) (*StopRequest, error) {
+	query := `
+		SELECT run_id, reason, requested_at
+		FROM run_stop_requests
+		WHERE run_id = $1
+	`
+
+	var request StopRequest
+	var reason sql.NullString
+	err := d.db.QueryRow(query, runID).Scan(
+		&request.RunID,
+		&reason,
+		&request.RequestedAt,
+	)
+
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to get stop request: %w", err)
+	}
+	request.Reason = reason.String
+
+	return &request, nil
+}
@@ -0,0 +1,170 @@
+package storage
+
+import (
+	"database/sql"
+	"fmt"
+	"time"
+)
+
+// Transition is a change of the status of a run
+type Transition struct {
+	From   ExperimentStatus `json:"from,omitempty"` // Empty for the status the run was created with
+	To     ExperimentStatus `json:"to"`
+	Actor  string           `json:"actor"` // Component or user that made the change
+	Reason string           `json:"reason,omitempty"`
+	Time   time.Time        `json:"time"`
+}
+
+// runTransitions lists the statuses a run may move to from each status. A run
+// that has ended cannot move on.
+var runTransitions = map[ExperimentStatus][]ExperimentStatus{
+	StatusPending: {StatusRunning, StatusFailed, StatusCancelled},
+	StatusRunning: {StatusCompleted, StatusFailed, StatusCancelled, StatusAborted},
+}
+
+// CanTransition reports whether a run may move from one status to another
+func CanTransition(from, to ExperimentStatus) bool {
+	for _, allowed := range runTransitions[from] {
+		if allowed == to {
+			return true
+		}
+	}
+	return false
+}
+
+// Finished reports whether a run with the status has ended
+func Finished(status ExperimentStatus) bool {
+	_, moves := runTransitions[status]
+	return !moves
+}
+
+// InvalidTransitionError reports a status change the state machine does not allow
+type InvalidTransitionError struct {
+	RunID string
+	From  ExperimentStatus
+	To    ExperimentStatus
+}
+
+func (e *InvalidTransitionError) Error() string {
+	return fmt.Sprintf("run %s cannot move from %s to %s", e.RunID, e.From, e.To)
+}
+
+// TransitionRun moves a run to a new status and records the change with the
+// actor that made it and why. The message of a failed or aborted run is kept
+// as its error.
+func (d *Database) TransitionRun(id string, to ExperimentStatus, actor, reason string) error {
+	tx, err := d.db.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to transition run: %w", err)
+	}
+	defer tx.Rollback()
+
+	// Lock the run so concurrent changes are checked against each other
+	var from ExperimentStatus
+	err = tx.QueryRow(`SELECT status FROM experiment_runs WHERE id = $1 FOR UPDATE`, id).Scan(&from)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return fmt.Errorf("run not found: %s", id)
+		}
+		return fmt.Errorf("failed to transition run: %w", err)
+	}
+	if !CanTransition(from, to) {
+		return &InvalidTransitionError{RunID: id, From: from, To: to}
+	}
+
+	now := time.Now()
+	var startedAt, endedAt *time.Time
+	if to == StatusRunning {
+		startedAt = &now
+	}
+	if Finished(to) {
+		endedAt = &now
+	}
+	message := ""
+	if to == StatusFailed || to == StatusAborted {
+		message = reason
+	}
+
+	update := `
+		UPDATE experiment_runs
+		SET status = $1, error = $2, started_at = COALESCE($3, started_at), ended_at = COALESCE($4, ended_at)
+		WHERE id = $5
+	`
+	if _, err := tx.Exec(update, to, message, startedAt, endedAt, id); err != nil {
+		return fmt.Errorf("failed to transition run: %w", err)
+	}
+
+	if err := recordTransition(tx, id, &Transition{From: from, To: to, Actor: actor, Reason: reason, Time: now}); err != nil {
+		return err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to transition run: %w", err)
+	}
+
+	return nil
+}
+
+// recordTransition stores a status change of a run
+func recordTransition(tx *sql.Tx, runID string, transition *Transition) error {
+	query := `
+		INSERT INTO run_transitions (run_id, from_status, to_status, actor, reason, created_at)
+		VALUES ($1, $2, $3, $4, $5, $6)
+	`
+
+	_, err := tx.Exec(
+		query,
+		runID,
+		transition.From,
+		transition.To,
+		transition.Actor,
+		transition.Reason,
+		transition.Time,
+	)
+
+	if err != nil {
+		return fmt.Errorf("failed to record run transition: %w", err)
+	}
+
+	return nil
+}
+
+// ListRunTransitions retrieves the status changes of a run, oldest first
+func (d *Database) ListRunTransitions(runID string) ([]Transition, error) {
+	query := `
+		SELECT from_status, to_status, actor, reason, created_at
+		FROM run_transitions
+		WHERE run_id = $1
+		ORDER BY id
+	`
+
+	rows, err := d.db.Query(query, runID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list run transitions: %w", err)
+	}
+	defer rows.Close()
+
+	transitions := []Transition{}
+	for rows.Next() {
+		var transition Transition
+		var reason sql.NullString
+		err := rows.Scan(
+			&transition.From,
+			&transition.To,
+			&transition.Actor,
+			&reason,
+			&transition.Time,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan run transition: %w", err)
+		}
+		transition.Reason = reason.String
+		transitions = append(transitions, transition)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating run transitions: %w", err)
+	}
+
+	return transitions, nil
+}
@@ -0,0 +1,68 @@
+package tests
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/flack/chaos-engineering-as-a-platform/pkg/chaos/experiments"
+	"github.com/flack/chaos-engineering-as-a-platform/pkg/chaos/probes"
+)
+
+func TestAbortConditionHaltsAndRecoversExperiment(t *testing.T) {
+	// The deployment behind the service has no ready replicas left
+	clientset := newProbedService(0)
+	journal := newMemoryJournal()
+
+	definition, _ := experiments.Lookup("service-failure")
+	start := time.Now()
+	result, err := experiments.Execute(context.Background(), definition, experiments.Dependencies{
+		Clientset: clientset,
+		Journal:   journal,
+	}, experiments.Spec{
+		Namespace: "default",
+		Duration:  30,
+		Params:    map[string]string{"service": "api"},
+		Abort: &probes.AbortConditions{
+			Interval: 1,
+			MinReadyReplicas: &probes.ReadyReplicasCondition{
+				Kind:      "deployment",
+				Namespace: "default",
+				Name:      "api",
+				Replicas:  1,
+			},
+		},
+	})
+
+	var abortErr *experiments.AbortError
+	if !errors.As(err, &abortErr) {
+		t.Fatalf("Expected the experiment to be aborted, got error: %v", err)
+	}
+	if elapsed := time.Since(start); elapsed > 10*time.Second {
+		t.Errorf("Expected the experiment to be halted early, it ran for %s", elapsed)
+	}
+
+	if result == nil || result.Aborted == nil || result.Success {
+		t.Fatalf("Expected the result to record the abort, got %+v", result)
+	}
+	if result.Aborted.Reason != "deployment api has 0 ready replicas, below 1" {
+		t.Errorf("Unexpected abort reason: %s", result.Aborted.Reason)
+	}
+	if result.Aborted.Time.Before(start) {
+		t.Errorf("Expected the abort time to be recorded, got %s", result.Aborted.Time)
+	}
+
+	if len(journal.recorded) != 1 || !journal.resolved[journal.recorded[0].ID] {
+		t.Fatalf("Expected the injected change to be recovered, got %v", journal.recorded)
+	}
+	service, err := clientset.CoreV1().Services("default").Get(context.Background(), "api", metav1.GetOptions{})
+	if err != nil {
+		t.Fatalf("Failed to get service: %v", err)
+	}
+	if service.Spec.Selector["app"] != "api" {
+		t.Errorf("Expected the service selector to be restored, got %v", service.Spec.Selector)
+	}
+}
@@ -0,0 +1,152 @@
+package tests
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/kubernetes/fake"
+	k8stesting "k8s.io/client-go/testing"
+
+	"github.com/flack/chaos-engineering-as-a-platform/pkg/chaos/experiments"
+)
+
+// newReadyReplica creates a ready pod owned by the ReplicaSet of deployment web
+func newReadyReplica(name string) *corev1.Pod {
+	pod := newRunningPod(name, "default", map[string]string{"app": "web", "pod-template-hash": "7d4b9c"})
+	controller := true
+	pod.OwnerReferences = []metav1.OwnerReference{{
+		APIVersion: "apps/v1",
+		Kind:       "ReplicaSet",
+		Name:       "web-7d4b9c",
+		Controller: &controller,
+	}}
+	pod.Status.Conditions = []corev1.PodCondition{{Type: corev1.PodReady, Status: corev1.ConditionTrue}}
+	return pod
+}
+
+func TestBlastRadiusLimitsAffectedPods(t *testing.T) {
+	objects := []runtime.Object{}
+	for i := 0; i < 6; i++ {
+		objects = append(objects, newReadyReplica(fmt.Sprintf("web-7d4b9c-%d", i)))
+	}
+	definition, _ := experiments.Lookup("pod-failure")
+
+	plan, err := experiments.DryRun(context.Background(), definition, experiments.Dependencies{
+		Clientset:   fake.NewSimpleClientset(objects...),
+		Journal:     newMemoryJournal(),
+		BlastRadius: &experiments.BlastRadius{MaxPods: 10, MaxPercentage: 50, MinReadyReplicas: 1},
+	}, experiments.Spec{
+		Namespace: "default",
+		Selector:  "app=web",
+		Duration:  30,
+		Params:    map[string]string{"percentage": "100"},
+	})
+	if err != nil {
+		t.Fatalf("Expected dry run to succeed, got error: %v", err)
+	}
+
+	// Half of the deployment may be affected, the rest is left out with a reason
+	if len(plan.AffectedResources) != 3 {
+		t.Errorf("Expected 3 pods to be planned for deletion, got %v", plan.AffectedResources)
+	}
+	if len(plan.BlastRadius) != 3 {
+		t.Errorf("Expected 3 pods to be left out by the blast radius, got %v", plan.BlastRadius)
+	}
+}
+
+func TestBlastRadiusRejectsExperimentWithNothingAllowed(t *testing.T) {
+	definition, _ := experiments.Lookup("pod-failure")
+
+	// A single ready replica must be kept, so the only pod cannot be killed
+	_, err := experiments.DryRun(context.Background(), definition, experiments.Dependencies{
+		Clientset:   fake.NewSimpleClientset(newReadyReplica("web-7d4b9c-0")),
+		Journal:     newMemoryJournal(),
+		BlastRadius: &experiments.BlastRadius{MinReadyReplicas: 1},
+	}, experiments.Spec{
+		Namespace: "default",
+		Selector:  "app=web",
+		Duration:  30,
+		Params:    map[string]string{"percentage": "100"},
+	})
+
+	var blastErr *experiments.BlastRadiusError
+	if !errors.As(err, &blastErr) {
+		t.Fatalf("Expected a blast-radius error, got %v", err)
+	}
+	if len(blastErr.Reasons) != 1 {
+		t.Errorf("Expected one reason, got %v", blastErr.Reasons)
+	}
+}
+
+func TestBlastRadiusCountsEveryKillRound(t *testing.T) {
+	objects := []runtime.Object{}
+	for i := 0; i < 4; i++ {
+		objects = append(objects, newReadyReplica(fmt.Sprintf("web-7d4b9c-%d", i)))
+	}
+	clientset := fake.NewSimpleClientset(objects...)
+
+	// The deployment replaces every deleted pod, so it keeps four replicas
+	replacements := 0
+	clientset.PrependReactor("delete", "pods", func(action k8stesting.Action) (bool, runtime.Object, error) {
+		name := action.(k8stesting.DeleteAction).GetName()
+		if err := clientset.Tracker().Delete(corev1.SchemeGroupVersion.WithResource("pods"), "default", name); err != nil {
+			return true, nil, err
+		}
+		replacements++
+		replacement := newReadyReplica(fmt.Sprintf("web-7d4b9c-r%d", replacements))
+		return true, nil, clientset.Tracker().Add(replacement)
+	})
+
+	definition, _ := experiments.Lookup("pod-failure")
+	result, err := experiments.Execute(context.Background(), definition, experiments.Dependencies{
+		Clientset:   clientset,
+		Journal:     newMemoryJournal(),
+		BlastRadius: &experiments.BlastRadius{MaxPercentage: 50},
+	}, experiments.Spec{
+		Namespace: "default",
+		Selector:  "app=web",
+		Duration:  3,
+		Params:    map[string]string{"percentage": "0", "interval": "1"},
+	})
+	if err != nil {
+		t.Fatalf("Expected experiment to succeed, got error: %v", err)
+	}
+
+	// Every round kills a single pod, but half of the deployment may be affected in the whole run
+	if len(result.AffectedResources) != 2 {
+		t.Errorf("Expected 2 pods to be killed across the rounds, got %v", result.AffectedResources)
+	}
+	if len(result.BlastRadius) == 0 {
+		t.Errorf("Expected later rounds to be limited by the blast radius")
+	}
+}
+
+func TestBlastRadiusAppliesToServiceFailure(t *testing.T) {
+	service := &corev1.Service{
+		ObjectMeta: metav1.ObjectMeta{Name: "web", Namespace: "default"},
+		Spec:       corev1.ServiceSpec{Selector: map[string]string{"app": "web"}},
+	}
+	clientset := fake.NewSimpleClientset(service, newReadyReplica("web-7d4b9c-0"), newReadyReplica("web-7d4b9c-1"))
+	definition, _ := experiments.Lookup("service-failure")
+
+	// Taking the service out of rotation affects both of its pods at once
+	_, err := experiments.DryRun(context.Background(), definition, experiments.Dependencies{
+		Clientset:   clientset,
+		Journal:     newMemoryJournal(),
+		BlastRadius: &experiments.BlastRadius{MaxPercentage: 50},
+	}, experiments.Spec{
+		Namespace: "default",
+		Duration:  30,
+		Params:    map[string]string{"service": "web"},
+	})
+
+	var blastErr *experiments.BlastRadiusError
+	if !errors.As(err, &blastErr) {
+		t.Fatalf("Expected a blast-radius error, got %v", err)
+	}
+}
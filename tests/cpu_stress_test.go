@@ -0,0 +1,130 @@
+package tests
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+
+	"github.com/flack/chaos-engineering-as-a-platform/pkg/chaos/experiments"
+)
+
+func TestCPUStressExperiment(t *testing.T) {
+	clientset := fake.NewSimpleClientset(
+		newRunningPod("web-1", "default", map[string]string{"app": "web"}),
+		newRunningPod("other-1", "default", map[string]string{"app": "other"}),
+	)
+	startChaosContainers(clientset)
+	executor := &fakePodExecutor{}
+
+	experiment := experiments.NewCPUStressExperiment(clientset, executor, experiments.CPUStressConfig{
+		Namespace: "default",
+		Selector:  "app=web",
+		Duration:  1,
+		Load:      70,
+		Workers:   2,
+		Cores:     "0-1",
+	})
+
+	result, err := experiment.Run(context.Background())
+	if err != nil {
+		t.Fatalf("Expected experiment to succeed, got error: %v", err)
+	}
+
+	if !result.Success {
+		t.Errorf("Expected result to be successful, got error '%s'", result.Error)
+	}
+
+	if len(result.AffectedResources) != 1 || result.AffectedResources[0] != "web-1" {
+		t.Errorf("Expected only web-1 to be affected, got %v", result.AffectedResources)
+	}
+
+	pod, err := clientset.CoreV1().Pods("default").Get(context.Background(), "web-1", metav1.GetOptions{})
+	if err != nil {
+		t.Fatalf("Failed to get pod: %v", err)
+	}
+
+	if len(pod.Spec.EphemeralContainers) != 1 {
+		t.Fatalf("Expected one chaos container, got %d", len(pod.Spec.EphemeralContainers))
+	}
+
+	container := pod.Spec.EphemeralContainers[0]
+	if container.Image != experiments.DefaultStressImage {
+		t.Errorf("Expected the default stress image, got %s", container.Image)
+	}
+
+	script := strings.Join(container.Command, " ")
+	if !strings.Contains(script, "stress-ng --cpu 2 --cpu-load 70 --timeout 1s --taskset 0-1") {
+		t.Errorf("Expected stress-ng in script, got '%s'", script)
+	}
+
+	if !strings.Contains(script, "trap 'kill $PID") {
+		t.Errorf("Expected script to stop stress-ng on termination, got '%s'", script)
+	}
+
+	if len(executor.commands) != 1 || !strings.Contains(executor.commands[0], "kill -TERM 1") {
+		t.Errorf("Expected the chaos container to be stopped once, got %v", executor.commands)
+	}
+}
+
+func TestCPUStressRejectsInvalidConfig(t *testing.T) {
+	cases := []struct {
+		name   string
+		config experiments.CPUStressConfig
+	}{
+		{name: "no load", config: experiments.CPUStressConfig{Load: 0}},
+		{name: "load above 100", config: experiments.CPUStressConfig{Load: 101}},
+		{name: "unsafe cores", config: experiments.CPUStressConfig{Load: 50, Cores: "0; reboot"}},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			clientset := fake.NewSimpleClientset(newRunningPod("web-1", "default", map[string]string{"app": "web"}))
+
+			tc.config.Namespace = "default"
+			tc.config.Selector = "app=web"
+			tc.config.Duration = 1
+			experiment := experiments.NewCPUStressExperiment(clientset, &fakePodExecutor{}, tc.config)
+
+			if _, err := experiment.Run(context.Background()); err == nil {
+				t.Fatalf("Expected the configuration to be rejected")
+			}
+
+			pod, _ := clientset.CoreV1().Pods("default").Get(context.Background(), "web-1", metav1.GetOptions{})
+			if len(pod.Spec.EphemeralContainers) != 0 {
+				t.Errorf("Expected nothing to be injected, got %d chaos containers", len(pod.Spec.EphemeralContainers))
+			}
+		})
+	}
+}
+
+func TestCPUStressStopsStressWhenCancelled(t *testing.T) {
+	clientset := fake.NewSimpleClientset(newRunningPod("web-1", "default", map[string]string{"app": "web"}))
+	startChaosContainers(clientset)
+	executor := &fakePodExecutor{}
+
+	experiment := experiments.NewCPUStressExperiment(clientset, executor, experiments.CPUStressConfig{
+		Namespace: "default",
+		Selector:  "app=web",
+		Duration:  300,
+		Load:      80,
+	})
+
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+
+	result, err := experiment.Run(ctx)
+	if err == nil {
+		t.Fatalf("Expected the cancelled experiment to return an error")
+	}
+	if result.Success {
+		t.Errorf("Expected the cancelled experiment not to succeed")
+	}
+
+	if len(executor.commands) != 1 || !strings.Contains(executor.commands[0], "kill -TERM 1") {
+		t.Errorf("Expected the chaos container to be stopped after cancellation, got %v", executor.commands)
+	}
+}
@@ -0,0 +1,130 @@
+package tests
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+
+	"github.com/flack/chaos-engineering-as-a-platform/pkg/chaos/experiments"
+)
+
+// newPodWithVolume returns a running pod whose app container mounts a volume at /data
+func newPodWithVolume(name string, readOnly bool) *corev1.Pod {
+	pod := newRunningPod(name, "default", map[string]string{"app": "web"})
+	pod.Spec.Volumes = []corev1.Volume{{Name: "data", VolumeSource: corev1.VolumeSource{EmptyDir: &corev1.EmptyDirVolumeSource{}}}}
+	pod.Spec.Containers[0].VolumeMounts = []corev1.VolumeMount{{Name: "data", MountPath: "/data", ReadOnly: readOnly}}
+	return pod
+}
+
+func TestDiskFailureFillsAndCleansUpVolume(t *testing.T) {
+	clientset := fake.NewSimpleClientset(newPodWithVolume("web-1", false))
+	startChaosContainers(clientset)
+	executor := &fakePodExecutor{}
+
+	experiment := experiments.NewDiskFailureExperiment(clientset, executor, experiments.DiskFailureConfig{
+		Namespace:  "default",
+		Selector:   "app=web",
+		Duration:   1,
+		Mode:       experiments.DiskFill,
+		Path:       "/data/cache",
+		Percentage: 80,
+	})
+
+	result, err := experiment.Run(context.Background())
+	if err != nil {
+		t.Fatalf("Expected experiment to succeed, got error: %v", err)
+	}
+	if !result.Success {
+		t.Errorf("Expected result to be successful, got error '%s'", result.Error)
+	}
+
+	pod, err := clientset.CoreV1().Pods("default").Get(context.Background(), "web-1", metav1.GetOptions{})
+	if err != nil {
+		t.Fatalf("Failed to get pod: %v", err)
+	}
+	if len(pod.Spec.EphemeralContainers) != 1 {
+		t.Fatalf("Expected one chaos container, got %d", len(pod.Spec.EphemeralContainers))
+	}
+
+	container := pod.Spec.EphemeralContainers[0]
+	if len(container.VolumeMounts) != 1 || container.VolumeMounts[0].Name != "data" || container.VolumeMounts[0].MountPath != "/data" {
+		t.Errorf("Expected the chaos container to mount the data volume at /data, got %v", container.VolumeMounts)
+	}
+
+	script := strings.Join(container.Command, " ")
+	for _, expected := range []string{"df -Pk /data/cache", "* 80 / 100", "fallocate -l ${NEED}k /data/cache/.chaos-disk-fill-", "trap 'kill $PID 2>/dev/null; rm -f /data/cache/.chaos-disk-fill-"} {
+		if !strings.Contains(script, expected) {
+			t.Errorf("Expected %q in script, got '%s'", expected, script)
+		}
+	}
+
+	// The filler file is removed through the target container even after the script stopped
+	if len(executor.commands) != 2 || !strings.Contains(executor.commands[0], "kill -TERM 1") || !strings.HasPrefix(executor.commands[1], "web-1/app: rm -f /data/cache/.chaos-disk-fill-") {
+		t.Errorf("Expected the chaos container to be stopped and the filler file removed, got %v", executor.commands)
+	}
+}
+
+func TestDiskFailureIOStress(t *testing.T) {
+	clientset := fake.NewSimpleClientset(newPodWithVolume("web-1", false))
+	startChaosContainers(clientset)
+
+	experiment := experiments.NewDiskFailureExperiment(clientset, &fakePodExecutor{}, experiments.DiskFailureConfig{
+		Namespace: "default",
+		Selector:  "app=web",
+		Duration:  1,
+		Mode:      experiments.DiskIOStress,
+		Path:      "/data",
+		Workers:   2,
+	})
+
+	if _, err := experiment.Run(context.Background()); err != nil {
+		t.Fatalf("Expected experiment to succeed, got error: %v", err)
+	}
+
+	pod, _ := clientset.CoreV1().Pods("default").Get(context.Background(), "web-1", metav1.GetOptions{})
+	script := strings.Join(pod.Spec.EphemeralContainers[0].Command, " ")
+	if !strings.Contains(script, "stress-ng --hdd 2 --temp-path /data --timeout 1s") {
+		t.Errorf("Expected stress-ng I/O workers in script, got '%s'", script)
+	}
+	if !strings.Contains(script, "rm -rf /data/tmp-stress-ng-*") {
+		t.Errorf("Expected script to remove the stress-ng files on termination, got '%s'", script)
+	}
+}
+
+func TestDiskFailureRejectsUnusableVolumes(t *testing.T) {
+	cases := []struct {
+		name string
+		pod  *corev1.Pod
+		path string
+	}{
+		{name: "relative path", pod: newPodWithVolume("web-1", false), path: "data"},
+		{name: "path outside volumes", pod: newPodWithVolume("web-1", false), path: "/var/log"},
+		{name: "read-only volume", pod: newPodWithVolume("web-1", true), path: "/data"},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			clientset := fake.NewSimpleClientset(tc.pod)
+
+			experiment := experiments.NewDiskFailureExperiment(clientset, &fakePodExecutor{}, experiments.DiskFailureConfig{
+				Namespace: "default",
+				Selector:  "app=web",
+				Duration:  1,
+				Path:      tc.path,
+			})
+
+			if _, err := experiment.Run(context.Background()); err == nil {
+				t.Fatalf("Expected the experiment to be rejected")
+			}
+
+			pod, _ := clientset.CoreV1().Pods("default").Get(context.Background(), "web-1", metav1.GetOptions{})
+			if len(pod.Spec.EphemeralContainers) != 0 {
+				t.Errorf("Expected nothing to be injected, got %d chaos containers", len(pod.Spec.EphemeralContainers))
+			}
+		})
+	}
+}
@@ -0,0 +1,52 @@
+package tests
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/flack/chaos-engineering-as-a-platform/pkg/chaos/experiments"
+)
+
+func TestDryRunPlansPodFailureWithoutKilling(t *testing.T) {
+	clientset := newPodFleet()
+	journal := newMemoryJournal()
+	definition, _ := experiments.Lookup("pod-failure")
+
+	plan, err := experiments.DryRun(context.Background(), definition, experiments.Dependencies{
+		Clientset: clientset,
+		Journal:   journal,
+	}, experiments.Spec{
+		Namespace: "default",
+		Selector:  "app=web",
+		Duration:  30,
+		Params:    map[string]string{"percentage": "50"},
+	})
+	if err != nil {
+		t.Fatalf("Expected dry run to succeed, got error: %v", err)
+	}
+
+	if len(plan.AffectedResources) != 3 || len(plan.Actions) != 3 {
+		t.Fatalf("Expected 3 pods to be planned for deletion, got %v", plan)
+	}
+
+	pods, err := clientset.CoreV1().Pods("default").List(context.Background(), metav1.ListOptions{})
+	if err != nil {
+		t.Fatalf("Failed to list pods: %v", err)
+	}
+	if len(pods.Items) != 6 {
+		t.Errorf("Expected no pod to be deleted by a dry run, %d left", len(pods.Items))
+	}
+	if len(journal.recorded) != 0 {
+		t.Errorf("Expected nothing to be journaled by a dry run, got %v", journal.recorded)
+	}
+
+	// Running with the planned seed kills exactly the planned pods
+	seed := plan.Seed
+	result := runPodFailure(t, experiments.SelectionStrategy(plan.Strategy), &seed, 50)
+	if !reflect.DeepEqual(result.AffectedResources, plan.AffectedResources) {
+		t.Errorf("Expected run with seed %d to kill %v, got %v", seed, plan.AffectedResources, result.AffectedResources)
+	}
+}
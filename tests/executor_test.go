@@ -0,0 +1,129 @@
+package tests
+
+import (
+	"context"
+	"database/sql/driver"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/flack/chaos-engineering-as-a-platform/pkg/chaos/executor"
+	"github.com/flack/chaos-engineering-as-a-platform/pkg/chaos/experiments"
+	"github.com/flack/chaos-engineering-as-a-platform/pkg/k8s"
+	"github.com/flack/chaos-engineering-as-a-platform/pkg/storage"
+)
+
+// createExecutorExperiment stores a pod-failure experiment on the web pods with the duration
+func createExecutorExperiment(t *testing.T, db *storage.Database, id string, duration int) {
+	t.Helper()
+	err := db.CreateExperiment(&storage.Experiment{
+		ID:         id,
+		Name:       "Test Experiment",
+		Type:       storage.PodFailure,
+		Status:     storage.StatusPending,
+		Target:     "app=web",
+		Parameters: `{"namespace":"default","selector":"app=web"}`,
+		CreatedAt:  time.Now(),
+		UpdatedAt:  time.Now(),
+		Duration:   duration,
+	})
+	if err != nil {
+		t.Fatalf("Failed to create experiment: %v", err)
+	}
+}
+
+func TestExecuteRefusesInvalidDurationBeforeCreatingARun(t *testing.T) {
+	db, memory := newMemoryDatabase(t)
+	createExecutorExperiment(t, db, "exp-1", 0)
+
+	chaosExecutor := executor.NewExecutor(k8s.NewMockClient(), db, testMetrics())
+	if _, err := chaosExecutor.ExecuteExperiment("exp-1", storage.RunTriggerAPI); err == nil {
+		t.Fatalf("Expected an experiment without a duration to be refused")
+	}
+
+	if runs := memory.rows("experiment_runs"); len(runs) != 0 {
+		t.Errorf("Expected no run to be created, got %v", runs)
+	}
+	if locks := memory.rows("target_locks"); len(locks) != 0 {
+		t.Errorf("Expected no target to be locked, got %v", locks)
+	}
+}
+
+func TestExecuteFailsRunThatCannotStart(t *testing.T) {
+	db, memory := newMemoryDatabase(t)
+	createExecutorExperiment(t, db, "exp-1", 1)
+
+	// The run cannot be moved to running
+	var locked bool
+	memory.fail = func(query string, args []driver.Value) bool {
+		if strings.HasPrefix(query, "INSERT INTO target_locks") {
+			locked = true
+		}
+		return strings.HasPrefix(query, "UPDATE experiment_runs") && args[0] == string(storage.StatusRunning)
+	}
+
+	chaosExecutor := executor.NewExecutor(k8s.NewMockClient(), db, testMetrics())
+	if _, err := chaosExecutor.ExecuteExperiment("exp-1", storage.RunTriggerAPI); err == nil {
+		t.Fatalf("Expected the run to fail when it cannot be started")
+	}
+
+	runs := memory.rows("experiment_runs")
+	if len(runs) != 1 || runs[0]["status"] != string(storage.StatusFailed) {
+		t.Errorf("Expected the run to be marked failed, got %v", runs)
+	}
+	if locks := memory.rows("target_locks"); !locked || len(locks) != 0 {
+		t.Errorf("Expected the target lock to be taken and released, got %v", locks)
+	}
+	if err := chaosExecutor.StopExperiment("exp-1"); !errors.Is(err, executor.ErrNotRunning) {
+		t.Errorf("Expected the run to be no longer tracked, got %v", err)
+	}
+}
+
+func TestExecuteStopsRunWhoseStopIsRequested(t *testing.T) {
+	db, memory := newMemoryDatabase(t)
+	createExecutorExperiment(t, db, "exp-1", 300)
+
+	client := k8s.NewMockClient()
+	pod := newRunningPod("web-0", "default", map[string]string{"app": "web"})
+	if _, err := client.GetClientset().CoreV1().Pods("default").Create(context.Background(), pod, metav1.CreateOptions{}); err != nil {
+		t.Fatalf("Failed to create pod: %v", err)
+	}
+	chaosExecutor := executor.NewExecutor(client, db, testMetrics())
+	chaosExecutor.SetBlastRadius(experiments.BlastRadius{})
+
+	done := make(chan error, 1)
+	go func() {
+		_, err := chaosExecutor.ExecuteExperiment("exp-1", storage.RunTriggerAPI)
+		done <- err
+	}()
+
+	// Another process asks the run to stop through the database
+	var runID string
+	for deadline := time.Now().Add(5 * time.Second); runID == ""; time.Sleep(10 * time.Millisecond) {
+		if runs := memory.rows("experiment_runs"); len(runs) == 1 {
+			runID = runs[0]["id"].(string)
+		} else if time.Now().After(deadline) {
+			t.Fatalf("Timed out waiting for the run to be created")
+		}
+	}
+	if err := db.RequestRunStop(&storage.StopRequest{RunID: runID, Reason: "Stopped", RequestedAt: time.Now()}); err != nil {
+		t.Fatalf("Failed to request stop: %v", err)
+	}
+
+	select {
+	case <-done:
+	case <-time.After(15 * time.Second):
+		t.Fatalf("Expected the run to stop once its stop was requested")
+	}
+
+	run, err := db.GetRun(runID)
+	if err != nil {
+		t.Fatalf("Failed to get run: %v", err)
+	}
+	if run.Status != storage.StatusCancelled || run.Transitions[len(run.Transitions)-1].Reason != "Stopped" {
+		t.Errorf("Expected the run to be cancelled for the request, got %+v", run)
+	}
+}
@@ -0,0 +1,125 @@
+package tests
+
+import (
+	"context"
+	"reflect"
+	"sync"
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+
+	"github.com/flack/chaos-engineering-as-a-platform/pkg/chaos/experiments"
+)
+
+// memoryJournal is an in-memory experiments.Journal
+type memoryJournal struct {
+	mu       sync.Mutex
+	recorded []experiments.Change
+	resolved map[string]bool
+}
+
+func newMemoryJournal() *memoryJournal {
+	return &memoryJournal{resolved: map[string]bool{}}
+}
+
+func (j *memoryJournal) Record(change experiments.Change) error {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	j.recorded = append(j.recorded, change)
+	return nil
+}
+
+func (j *memoryJournal) Resolve(change experiments.Change) error {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	j.resolved[change.ID] = true
+	return nil
+}
+
+func TestExecuteJournalsAndResolvesChanges(t *testing.T) {
+	clientset := fake.NewSimpleClientset(&corev1.Service{
+		ObjectMeta: metav1.ObjectMeta{Name: "api", Namespace: "default"},
+		Spec:       corev1.ServiceSpec{Selector: map[string]string{"app": "api"}},
+	})
+	journal := newMemoryJournal()
+
+	definition, exists := experiments.Lookup("service-failure")
+	if !exists {
+		t.Fatal("Expected service-failure to be registered")
+	}
+
+	_, err := experiments.Execute(context.Background(), definition, experiments.Dependencies{
+		Clientset: clientset,
+		Journal:   journal,
+	}, experiments.Spec{
+		Namespace: "default",
+		Duration:  0,
+		Params:    map[string]string{"service": "api"},
+	})
+	if err != nil {
+		t.Fatalf("Expected experiment to succeed, got error: %v", err)
+	}
+
+	if len(journal.recorded) != 1 || journal.recorded[0].Kind != experiments.ChangeServiceSelector {
+		t.Fatalf("Expected one service change to be recorded, got %v", journal.recorded)
+	}
+	if !journal.resolved[journal.recorded[0].ID] {
+		t.Errorf("Expected the service change to be resolved after the run")
+	}
+}
+
+func TestRecoverChangeRestoresServiceAfterCrash(t *testing.T) {
+	// A service left failed by an experiment whose process died mid-run
+	clientset := fake.NewSimpleClientset(&corev1.Service{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:        "api",
+			Namespace:   "default",
+			Annotations: map[string]string{experiments.OriginalSelectorAnnotation: `{"app":"api"}`},
+		},
+		Spec: corev1.ServiceSpec{Selector: map[string]string{experiments.BlackholeSelectorLabel: "abc12345"}},
+	})
+	journal := newMemoryJournal()
+	definition, _ := experiments.Lookup("service-failure")
+
+	change := experiments.Change{
+		ID:        "change-1",
+		Kind:      experiments.ChangeServiceSelector,
+		Namespace: "default",
+		Name:      "api",
+		Data:      map[string]string{"token": "abc12345"},
+	}
+	err := experiments.RecoverChange(context.Background(), definition, experiments.Dependencies{
+		Clientset: clientset,
+		Journal:   journal,
+	}, change)
+	if err != nil {
+		t.Fatalf("Expected recovery to succeed, got error: %v", err)
+	}
+
+	service, err := clientset.CoreV1().Services("default").Get(context.Background(), "api", metav1.GetOptions{})
+	if err != nil {
+		t.Fatalf("Failed to get service: %v", err)
+	}
+	if !reflect.DeepEqual(service.Spec.Selector, map[string]string{"app": "api"}) {
+		t.Errorf("Expected original selector to be restored, got %v", service.Spec.Selector)
+	}
+	if !journal.resolved["change-1"] {
+		t.Errorf("Expected the change to be resolved after recovery")
+	}
+
+	// A change recorded by another experiment's token must leave the service alone
+	service.Spec.Selector = map[string]string{experiments.BlackholeSelectorLabel: "other"}
+	service.Annotations = map[string]string{experiments.OriginalSelectorAnnotation: `{"app":"api"}`}
+	if _, err := clientset.CoreV1().Services("default").Update(context.Background(), service, metav1.UpdateOptions{}); err != nil {
+		t.Fatalf("Failed to update service: %v", err)
+	}
+	if err := experiments.RecoverChange(context.Background(), definition, experiments.Dependencies{Clientset: clientset}, change); err != nil {
+		t.Fatalf("Expected recovery to succeed, got error: %v", err)
+	}
+	service, _ = clientset.CoreV1().Services("default").Get(context.Background(), "api", metav1.GetOptions{})
+	if service.Spec.Selector[experiments.BlackholeSelectorLabel] != "other" {
+		t.Errorf("Expected service failed by another experiment to be left alone, got %v", service.Spec.Selector)
+	}
+}
@@ -0,0 +1,107 @@
+package tests
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"sync/atomic"
+	"testing"
+
+	"github.com/flack/chaos-engineering-as-a-platform/pkg/config"
+	"github.com/flack/chaos-engineering-as-a-platform/pkg/k8s/operator"
+)
+
+func TestStopAllHaltsEveryRunningExperiment(t *testing.T) {
+	var cleanups int32
+	target := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/cleanup" {
+			atomic.AddInt32(&cleanups, 1)
+		}
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer target.Close()
+
+	chaosOperator, err := operator.NewChaosOperator(&config.Config{MockKubernetes: true}, nil, testMetrics())
+	if err != nil {
+		t.Fatalf("Failed to create operator: %v", err)
+	}
+
+	for _, id := range []string{"halt-b", "halt-a"} {
+		_, err := chaosOperator.RunExperiment(&operator.ExperimentConfig{
+			ID:       id,
+			Target:   target.URL,
+			Params:   map[string]string{"target_type": "external", "endpoint": "/inject"},
+			Duration: 300,
+		})
+		if err != nil {
+			t.Fatalf("Failed to run experiment %s: %v", id, err)
+		}
+	}
+
+	stopped := chaosOperator.StopAll("Halted by the kill switch")
+	if !reflect.DeepEqual(stopped, []string{"halt-a", "halt-b"}) {
+		t.Errorf("Expected both experiments to be stopped, got %v", stopped)
+	}
+
+	// StopAll returns once every experiment has been cleaned up
+	if count := atomic.LoadInt32(&cleanups); count != 2 {
+		t.Errorf("Expected both experiments to be cleaned up, got %d cleanups", count)
+	}
+	if _, err := chaosOperator.GetExperimentStatus("halt-a"); err == nil {
+		t.Errorf("Expected the stopped experiment to be removed")
+	}
+	if stopped := chaosOperator.StopAll("Halted by the kill switch"); len(stopped) != 0 {
+		t.Errorf("Expected nothing left to stop, got %v", stopped)
+	}
+}
+
+func TestStopAllDoesNotHoldUpNewRuns(t *testing.T) {
+	// The cleanup of the stopped experiment blocks until the test lets it finish
+	cleaning := make(chan struct{})
+	release := make(chan struct{})
+	target := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/cleanup" && r.URL.Query().Get("run") == "slow" {
+			close(cleaning)
+			<-release
+		}
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer target.Close()
+
+	chaosOperator, err := operator.NewChaosOperator(&config.Config{MockKubernetes: true}, nil, testMetrics())
+	if err != nil {
+		t.Fatalf("Failed to create operator: %v", err)
+	}
+	if _, err := chaosOperator.RunExperiment(&operator.ExperimentConfig{
+		ID:       "slow",
+		Target:   target.URL,
+		Params:   map[string]string{"target_type": "external", "endpoint": "/inject", "cleanup_endpoint": "/cleanup?run=slow"},
+		Duration: 300,
+	}); err != nil {
+		t.Fatalf("Failed to run experiment: %v", err)
+	}
+
+	done := make(chan []string, 1)
+	go func() {
+		done <- chaosOperator.StopAll("Halted by the kill switch")
+	}()
+	<-cleaning
+
+	// Another experiment starts while the stopped one is still recovering
+	if _, err := chaosOperator.RunExperiment(&operator.ExperimentConfig{
+		ID:       "next",
+		Target:   target.URL,
+		Params:   map[string]string{"target_type": "external", "endpoint": "/inject"},
+		Duration: 300,
+	}); err != nil {
+		t.Fatalf("Failed to run experiment during the recovery: %v", err)
+	}
+
+	close(release)
+	if stopped := <-done; !reflect.DeepEqual(stopped, []string{"slow"}) {
+		t.Errorf("Expected only the running experiment to be stopped, got %v", stopped)
+	}
+	if stopped := chaosOperator.StopAll("Test finished"); !reflect.DeepEqual(stopped, []string{"next"}) {
+		t.Errorf("Expected the new experiment to be left running, got %v", stopped)
+	}
+}
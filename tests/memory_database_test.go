@@ -0,0 +1,278 @@
+package tests
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"fmt"
+	"io"
+	"reflect"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/flack/chaos-engineering-as-a-platform/pkg/storage"
+)
+
+var (
+	insertStatement = regexp.MustCompile(`^INSERT INTO (\w+) \(([^)]*)\)`)
+	updateStatement = regexp.MustCompile(`^UPDATE (\w+) SET (.*?) WHERE `)
+	deleteStatement = regexp.MustCompile(`^DELETE FROM (\w+) WHERE `)
+	assignment      = regexp.MustCompile(`^(\w+) = (?:COALESCE\()?\$(\d+)`)
+	condition       = regexp.MustCompile(`(?:\w+\.)?(\w+) = \$(\d+)`)
+	orderClause     = regexp.MustCompile(`ORDER BY (?:\w+\.)?(\w+)( DESC)?`)
+	columnName      = regexp.MustCompile(`[A-Za-z_]+`)
+)
+
+// memoryDatabase is a database/sql driver keeping its tables in memory. It
+// understands the statements the storage package makes: inserts naming their
+// columns, updates setting columns from parameters, and deletes and selects
+// filtered on columns equal to parameters, the selects ordered by a single
+// column. A selected expression answers with the last column it names.
+type memoryDatabase struct {
+	mu     sync.Mutex
+	tables map[string][]map[string]driver.Value
+	fail   func(query string, args []driver.Value) bool // Statements to fail, if set
+}
+
+// newMemoryDatabase returns a storage database backed by an empty memory database
+func newMemoryDatabase(t *testing.T) (*storage.Database, *memoryDatabase) {
+	t.Helper()
+	memory := &memoryDatabase{tables: map[string][]map[string]driver.Value{}}
+	db := sql.OpenDB(memory)
+	t.Cleanup(func() { db.Close() })
+	return storage.NewDatabaseFromDB(db), memory
+}
+
+// rows returns a copy of the rows stored in a table
+func (m *memoryDatabase) rows(table string) []map[string]driver.Value {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	rows := make([]map[string]driver.Value, 0, len(m.tables[table]))
+	for _, row := range m.tables[table] {
+		copied := map[string]driver.Value{}
+		for column, value := range row {
+			copied[column] = value
+		}
+		rows = append(rows, copied)
+	}
+	return rows
+}
+
+func (m *memoryDatabase) Connect(context.Context) (driver.Conn, error) { return m.Open("") }
+func (m *memoryDatabase) Driver() driver.Driver                        { return m }
+func (m *memoryDatabase) Open(string) (driver.Conn, error)             { return memoryConn{m}, nil }
+
+func (m *memoryDatabase) exec(query string, args []driver.Value) (driver.Result, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	query = strings.Join(strings.Fields(query), " ")
+	if m.fail != nil && m.fail(query, args) {
+		return nil, fmt.Errorf("memory database failed %q", query)
+	}
+
+	if match := insertStatement.FindStringSubmatch(query); match != nil {
+		row := map[string]driver.Value{}
+		for i, column := range splitTopLevel(match[2]) {
+			row[column] = args[i]
+		}
+		m.tables[match[1]] = append(m.tables[match[1]], row)
+		return driver.RowsAffected(1), nil
+	}
+
+	if match := updateStatement.FindStringSubmatch(query); match != nil {
+		var updated int64
+		for _, row := range m.tables[match[1]] {
+			if !matches(row, query, args) {
+				continue
+			}
+			for _, set := range splitTopLevel(match[2]) {
+				parts := assignment.FindStringSubmatch(set)
+				if parts == nil {
+					return nil, fmt.Errorf("memory database cannot set %q", set)
+				}
+				// COALESCE keeps the current value when the parameter is NULL
+				value := args[parameter(parts[2])]
+				if value == nil && strings.Contains(set, "COALESCE") {
+					continue
+				}
+				row[parts[1]] = value
+			}
+			updated++
+		}
+		return driver.RowsAffected(updated), nil
+	}
+
+	if match := deleteStatement.FindStringSubmatch(query); match != nil {
+		kept := []map[string]driver.Value{}
+		for _, row := range m.tables[match[1]] {
+			if !matches(row, query, args) {
+				kept = append(kept, row)
+			}
+		}
+		deleted := len(m.tables[match[1]]) - len(kept)
+		m.tables[match[1]] = kept
+		return driver.RowsAffected(deleted), nil
+	}
+
+	return nil, fmt.Errorf("memory database cannot execute %q", query)
+}
+
+func (m *memoryDatabase) query(query string, args []driver.Value) (driver.Rows, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	query = strings.Join(strings.Fields(query), " ")
+	from := topLevelIndex(query, " FROM ")
+	if !strings.HasPrefix(query, "SELECT ") || from < 0 {
+		return nil, fmt.Errorf("memory database cannot query %q", query)
+	}
+
+	rest := query[from+len(" FROM "):]
+	table := strings.Fields(rest)[0]
+	rows := []map[string]driver.Value{}
+	for _, row := range m.tables[table] {
+		if matches(row, rest, args) {
+			rows = append(rows, row)
+		}
+	}
+	if match := orderClause.FindStringSubmatch(rest); match != nil {
+		sort.SliceStable(rows, func(i, j int) bool {
+			if match[2] != "" {
+				return lessValue(rows[j][match[1]], rows[i][match[1]])
+			}
+			return lessValue(rows[i][match[1]], rows[j][match[1]])
+		})
+	}
+
+	result := &memoryRows{}
+	for _, item := range splitTopLevel(query[len("SELECT "):from]) {
+		names := columnName.FindAllString(item, -1)
+		result.columns = append(result.columns, names[len(names)-1])
+	}
+	for _, row := range rows {
+		values := make([]driver.Value, len(result.columns))
+		for i, column := range result.columns {
+			values[i] = row[column]
+		}
+		result.values = append(result.values, values)
+	}
+	return result, nil
+}
+
+// matches reports whether a row has the values the conditions of the WHERE
+// clause of a statement compare its columns to
+func matches(row map[string]driver.Value, statement string, args []driver.Value) bool {
+	where := strings.Index(statement, " WHERE ")
+	if where < 0 {
+		return true
+	}
+	clause := statement[where:]
+	if order := strings.Index(clause, " ORDER BY "); order >= 0 {
+		clause = clause[:order]
+	}
+
+	for _, match := range condition.FindAllStringSubmatch(clause, -1) {
+		if !reflect.DeepEqual(row[match[1]], args[parameter(match[2])]) {
+			return false
+		}
+	}
+	return true
+}
+
+// parameter returns the index of the argument a $n placeholder refers to
+func parameter(placeholder string) int {
+	n, _ := strconv.Atoi(placeholder)
+	return n - 1
+}
+
+// topLevelIndex returns the index of the first occurrence of sep outside parentheses
+func topLevelIndex(s, sep string) int {
+	depth := 0
+	for i := 0; i < len(s); i++ {
+		switch s[i] {
+		case '(':
+			depth++
+		case ')':
+			depth--
+		}
+		if depth == 0 && strings.HasPrefix(s[i:], sep) {
+			return i
+		}
+	}
+	return -1
+}
+
+// splitTopLevel splits a comma separated list, leaving commas inside parentheses alone
+func splitTopLevel(list string) []string {
+	var items []string
+	for {
+		i := topLevelIndex(list, ",")
+		if i < 0 {
+			return append(items, strings.TrimSpace(list))
+		}
+		items = append(items, strings.TrimSpace(list[:i]))
+		list = list[i+1:]
+	}
+}
+
+// lessValue orders two stored values of the same type
+func lessValue(a, b driver.Value) bool {
+	switch a := a.(type) {
+	case time.Time:
+		b, ok := b.(time.Time)
+		return ok && a.Before(b)
+	case string:
+		b, ok := b.(string)
+		return ok && a < b
+	case int64:
+		b, ok := b.(int64)
+		return ok && a < b
+	}
+	return false
+}
+
+type memoryConn struct{ db *memoryDatabase }
+
+func (c memoryConn) Prepare(query string) (driver.Stmt, error) { return memoryStmt{c.db, query}, nil }
+func (c memoryConn) Close() error                              { return nil }
+func (c memoryConn) Begin() (driver.Tx, error)                 { return memoryTx{}, nil }
+
+// memoryTx applies statements as they are made, so a rollback undoes nothing
+type memoryTx struct{}
+
+func (memoryTx) Commit() error   { return nil }
+func (memoryTx) Rollback() error { return nil }
+
+type memoryStmt struct {
+	db    *memoryDatabase
+	query string
+}
+
+func (s memoryStmt) Close() error  { return nil }
+func (s memoryStmt) NumInput() int { return -1 }
+
+func (s memoryStmt) Exec(args []driver.Value) (driver.Result, error) { return s.db.exec(s.query, args) }
+func (s memoryStmt) Query(args []driver.Value) (driver.Rows, error)  { return s.db.query(s.query, args) }
+
+type memoryRows struct {
+	columns []string
+	values  [][]driver.Value
+}
+
+func (r *memoryRows) Columns() []string { return r.columns }
+func (r *memoryRows) Close() error      { return nil }
+
+func (r *memoryRows) Next(dest []driver.Value) error {
+	if len(r.values) == 0 {
+		return io.EOF
+	}
+	copy(dest, r.values[0])
+	r.values = r.values[1:]
+	return nil
+}
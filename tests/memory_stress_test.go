@@ -0,0 +1,120 @@
+package tests
+
+import (
+	"context"
+	"reflect"
+	"testing"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+
+	"github.com/flack/chaos-engineering-as-a-platform/pkg/chaos/experiments"
+)
+
+// waitForChaosContainers polls until every pod has a chaos container attached
+func waitForChaosContainers(t *testing.T, clientset *fake.Clientset, namespace string, names []string) {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for _, name := range names {
+		for {
+			pod, err := clientset.CoreV1().Pods(namespace).Get(context.Background(), name, metav1.GetOptions{})
+			if err == nil && len(pod.Spec.EphemeralContainers) > 0 {
+				break
+			}
+			if time.Now().After(deadline) {
+				t.Fatalf("Timed out waiting for a chaos container in pod %s", name)
+			}
+			time.Sleep(10 * time.Millisecond)
+		}
+	}
+}
+
+// setPodStatus replaces the status of a pod as the kubelet would report it
+func setPodStatus(t *testing.T, clientset *fake.Clientset, namespace, name string, update func(*corev1.Pod)) {
+	t.Helper()
+	pod, err := clientset.CoreV1().Pods(namespace).Get(context.Background(), name, metav1.GetOptions{})
+	if err != nil {
+		t.Fatalf("Failed to get pod %s: %v", name, err)
+	}
+	update(pod)
+	if _, err := clientset.CoreV1().Pods(namespace).UpdateStatus(context.Background(), pod, metav1.UpdateOptions{}); err != nil {
+		t.Fatalf("Failed to update pod %s: %v", name, err)
+	}
+}
+
+func TestMemoryStressReportsPodOutcomes(t *testing.T) {
+	labels := map[string]string{"app": "web"}
+	restarted := newRunningPod("web-restarted", "default", labels)
+	restarted.Status.ContainerStatuses = []corev1.ContainerStatus{{Name: "app", RestartCount: 2}}
+	clientset := fake.NewSimpleClientset(
+		newRunningPod("web-stress-killed", "default", labels),
+		newRunningPod("web-evicted", "default", labels),
+		restarted,
+		newRunningPod("web-survived", "default", labels),
+	)
+	startChaosContainers(clientset)
+
+	experiment := experiments.NewMemoryStressExperiment(clientset, &fakePodExecutor{}, experiments.MemoryStressConfig{
+		Namespace: "default",
+		Selector:  "app=web",
+		Duration:  1,
+		Size:      512,
+	})
+
+	type outcome struct {
+		result *experiments.ExperimentResult
+		err    error
+	}
+	done := make(chan outcome, 1)
+	go func() {
+		result, err := experiment.Run(context.Background())
+		done <- outcome{result, err}
+	}()
+
+	waitForChaosContainers(t, clientset, "default", []string{"web-stress-killed", "web-evicted", "web-restarted", "web-survived"})
+
+	// The kernel picked the injected container as its victim
+	setPodStatus(t, clientset, "default", "web-stress-killed", func(pod *corev1.Pod) {
+		pod.Status.EphemeralContainerStatuses = []corev1.ContainerStatus{{
+			Name:  pod.Spec.EphemeralContainers[0].Name,
+			State: corev1.ContainerState{Terminated: &corev1.ContainerStateTerminated{Reason: "OOMKilled", ExitCode: 137}},
+		}}
+	})
+	setPodStatus(t, clientset, "default", "web-evicted", func(pod *corev1.Pod) {
+		pod.Status.Phase = corev1.PodFailed
+		pod.Status.Reason = "Evicted"
+	})
+	// The application container was OOM-killed and restarted during the window
+	setPodStatus(t, clientset, "default", "web-restarted", func(pod *corev1.Pod) {
+		pod.Status.ContainerStatuses = []corev1.ContainerStatus{{
+			Name:                 "app",
+			RestartCount:         3,
+			LastTerminationState: corev1.ContainerState{Terminated: &corev1.ContainerStateTerminated{Reason: "OOMKilled", ExitCode: 137}},
+		}}
+	})
+
+	var ran outcome
+	select {
+	case ran = <-done:
+	case <-time.After(10 * time.Second):
+		t.Fatalf("Timed out waiting for the experiment to finish")
+	}
+	if ran.err != nil {
+		t.Fatalf("Expected experiment to succeed, got error: %v", ran.err)
+	}
+
+	expected := map[string]string{
+		"web-stress-killed": experiments.OutcomeOOMKilled,
+		"web-evicted":       experiments.OutcomeEvicted,
+		"web-restarted":     experiments.OutcomeOOMKilled,
+		"web-survived":      experiments.OutcomeSurvived,
+	}
+	if !reflect.DeepEqual(ran.result.Outcomes, expected) {
+		t.Errorf("Expected outcomes %v, got %v", expected, ran.result.Outcomes)
+	}
+	if ran.result.Metrics["oom_killed_pods"] != 2 || ran.result.Metrics["evicted_pods"] != 1 {
+		t.Errorf("Expected 2 OOM-killed and 1 evicted pod in the metrics, got %v", ran.result.Metrics)
+	}
+}
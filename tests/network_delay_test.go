@@ -0,0 +1,125 @@
+package tests
+
+import (
+	"context"
+	"strings"
+	"sync"
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/kubernetes/fake"
+	k8stesting "k8s.io/client-go/testing"
+
+	"github.com/flack/chaos-engineering-as-a-platform/pkg/chaos/experiments"
+)
+
+// fakePodExecutor records the commands executed in pod containers
+type fakePodExecutor struct {
+	mu       sync.Mutex
+	commands []string
+}
+
+func (f *fakePodExecutor) ExecInContainer(ctx context.Context, namespace, pod, container string, command []string) (string, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.commands = append(f.commands, pod+"/"+container+": "+strings.Join(command, " "))
+	return "", nil
+}
+
+func newRunningPod(name, namespace string, labels map[string]string) *corev1.Pod {
+	return &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      name,
+			Namespace: namespace,
+			Labels:    labels,
+		},
+		Spec: corev1.PodSpec{
+			Containers: []corev1.Container{{Name: "app", Image: "nginx"}},
+		},
+		Status: corev1.PodStatus{Phase: corev1.PodRunning},
+	}
+}
+
+// reportChaosContainers stands in for the kubelet, reporting every chaos
+// container attached to a pod in the state returned for it
+func reportChaosContainers(clientset *fake.Clientset, state func(pod *corev1.Pod) corev1.ContainerState) {
+	clientset.PrependReactor("update", "pods", func(action k8stesting.Action) (bool, runtime.Object, error) {
+		if action.GetSubresource() != "ephemeralcontainers" {
+			return false, nil, nil
+		}
+		pod := action.(k8stesting.UpdateAction).GetObject().(*corev1.Pod)
+		attached := pod.Spec.EphemeralContainers[len(pod.Spec.EphemeralContainers)-1].Name
+		pod.Status.EphemeralContainerStatuses = append(pod.Status.EphemeralContainerStatuses, corev1.ContainerStatus{
+			Name:  attached,
+			State: state(pod),
+		})
+		return false, nil, nil
+	})
+}
+
+// startChaosContainers reports every chaos container attached to a pod as running
+func startChaosContainers(clientset *fake.Clientset) {
+	reportChaosContainers(clientset, func(*corev1.Pod) corev1.ContainerState {
+		return corev1.ContainerState{Running: &corev1.ContainerStateRunning{}}
+	})
+}
+
+func TestNetworkDelayExperiment(t *testing.T) {
+	clientset := fake.NewSimpleClientset(
+		newRunningPod("web-1", "default", map[string]string{"app": "web"}),
+		newRunningPod("other-1", "default", map[string]string{"app": "other"}),
+	)
+	startChaosContainers(clientset)
+	executor := &fakePodExecutor{}
+
+	experiment := experiments.NewNetworkDelayExperiment(clientset, executor, experiments.NetworkDelayConfig{
+		Namespace:   "default",
+		Selector:    "app=web",
+		Duration:    1,
+		Delay:       200,
+		Jitter:      20,
+		Correlation: 25,
+	})
+
+	result, err := experiment.Run(context.Background())
+	if err != nil {
+		t.Fatalf("Expected experiment to succeed, got error: %v", err)
+	}
+
+	if !result.Success {
+		t.Errorf("Expected result to be successful, got error '%s'", result.Error)
+	}
+
+	if len(result.AffectedResources) != 1 || result.AffectedResources[0] != "web-1" {
+		t.Errorf("Expected only web-1 to be affected, got %v", result.AffectedResources)
+	}
+
+	pod, err := clientset.CoreV1().Pods("default").Get(context.Background(), "web-1", metav1.GetOptions{})
+	if err != nil {
+		t.Fatalf("Failed to get pod: %v", err)
+	}
+
+	if len(pod.Spec.EphemeralContainers) != 1 {
+		t.Fatalf("Expected one chaos container, got %d", len(pod.Spec.EphemeralContainers))
+	}
+
+	container := pod.Spec.EphemeralContainers[0]
+	script := strings.Join(container.Command, " ")
+	if !strings.Contains(script, "netem delay 200ms 20ms 25%") {
+		t.Errorf("Expected netem delay in script, got '%s'", script)
+	}
+
+	if !strings.Contains(script, "trap 'tc qdisc del dev eth0 root") {
+		t.Errorf("Expected script to remove the qdisc on termination, got '%s'", script)
+	}
+
+	if container.SecurityContext == nil || len(container.SecurityContext.Capabilities.Add) == 0 {
+		t.Errorf("Expected chaos container to request NET_ADMIN")
+	}
+
+	if len(executor.commands) != 1 || !strings.Contains(executor.commands[0], "kill -TERM 1") {
+		t.Errorf("Expected the chaos container to be stopped once, got %v", executor.commands)
+	}
+}
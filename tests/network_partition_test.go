@@ -0,0 +1,155 @@
+package tests
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	networkingv1 "k8s.io/api/networking/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+
+	"github.com/flack/chaos-engineering-as-a-platform/pkg/chaos/experiments"
+)
+
+func TestNetworkPartitionExperiment(t *testing.T) {
+	clientset := fake.NewSimpleClientset(
+		newRunningPod("api-1", "default", map[string]string{"app": "api"}),
+	)
+
+	experiment := experiments.NewNetworkPartitionExperiment(clientset, experiments.NetworkPartitionConfig{
+		Namespace: "default",
+		Selector:  "app=api",
+		Duration:  1,
+		Direction: experiments.PartitionIngress,
+		Peers:     "app=frontend",
+	})
+
+	done := make(chan error, 1)
+	go func() {
+		_, err := experiment.Run(context.Background())
+		done <- err
+	}()
+
+	// While the experiment runs a labelled policy isolates the pods from the peers
+	time.Sleep(300 * time.Millisecond)
+	policy, err := clientset.NetworkingV1().NetworkPolicies("default").Get(context.Background(), experiment.PolicyName(), metav1.GetOptions{})
+	if err != nil {
+		t.Fatalf("Expected network policy to exist during the experiment: %v", err)
+	}
+
+	if policy.Labels[experiments.ChaosExperimentLabel] != "network-partition" {
+		t.Errorf("Expected policy to be labelled, got %v", policy.Labels)
+	}
+	if policy.Spec.PodSelector.MatchLabels["app"] != "api" {
+		t.Errorf("Expected policy to select app=api, got %v", policy.Spec.PodSelector)
+	}
+	if len(policy.Spec.PolicyTypes) != 1 || policy.Spec.PolicyTypes[0] != networkingv1.PolicyTypeIngress {
+		t.Errorf("Expected an ingress-only policy, got %v", policy.Spec.PolicyTypes)
+	}
+
+	// Ingress is allowed from every pod that is not a frontend pod
+	if len(policy.Spec.Ingress) != 1 || len(policy.Spec.Ingress[0].From) != 1 {
+		t.Fatalf("Expected one ingress rule with one peer, got %v", policy.Spec.Ingress)
+	}
+	requirement := policy.Spec.Ingress[0].From[0].PodSelector.MatchExpressions[0]
+	if requirement.Key != "app" || requirement.Operator != metav1.LabelSelectorOpNotIn || requirement.Values[0] != "frontend" {
+		t.Errorf("Expected peer to exclude app=frontend, got %v", requirement)
+	}
+
+	if err := <-done; err != nil {
+		t.Fatalf("Expected experiment to succeed, got error: %v", err)
+	}
+
+	policies, err := clientset.NetworkingV1().NetworkPolicies("default").List(context.Background(), metav1.ListOptions{})
+	if err != nil {
+		t.Fatalf("Failed to list network policies: %v", err)
+	}
+	if len(policies.Items) != 0 {
+		t.Errorf("Expected network policy to be deleted after the experiment, got %d", len(policies.Items))
+	}
+}
+
+func TestNetworkPartitionRefusesTrafficAllowedByOtherPolicies(t *testing.T) {
+	allowFrontend := &networkingv1.NetworkPolicy{
+		ObjectMeta: metav1.ObjectMeta{Name: "allow-frontend", Namespace: "default"},
+		Spec: networkingv1.NetworkPolicySpec{
+			PodSelector: metav1.LabelSelector{MatchLabels: map[string]string{"app": "api"}},
+			Ingress: []networkingv1.NetworkPolicyIngressRule{{
+				From: []networkingv1.NetworkPolicyPeer{{PodSelector: &metav1.LabelSelector{MatchLabels: map[string]string{"app": "frontend"}}}},
+			}},
+		},
+	}
+	clientset := fake.NewSimpleClientset(
+		newRunningPod("api-1", "default", map[string]string{"app": "api"}),
+		allowFrontend,
+	)
+
+	ingress := experiments.NewNetworkPartitionExperiment(clientset, experiments.NetworkPartitionConfig{
+		Namespace: "default",
+		Selector:  "app=api",
+		Duration:  1,
+		Direction: experiments.PartitionIngress,
+	})
+	_, err := ingress.Run(context.Background())
+	if err == nil || !strings.Contains(err.Error(), "allow-frontend (ingress)") {
+		t.Fatalf("Expected the partition to be refused because of allow-frontend, got %v", err)
+	}
+	if _, err := clientset.NetworkingV1().NetworkPolicies("default").Get(context.Background(), ingress.PolicyName(), metav1.GetOptions{}); err == nil {
+		t.Errorf("Expected no partition policy to be created")
+	}
+
+	// Egress is not allowed by the existing policy, so it can be partitioned
+	egress := experiments.NewNetworkPartitionExperiment(clientset, experiments.NetworkPartitionConfig{
+		Namespace: "default",
+		Selector:  "app=api",
+		Duration:  1,
+		Direction: experiments.PartitionEgress,
+	})
+	if _, err := egress.Run(context.Background()); err != nil {
+		t.Errorf("Expected the egress partition to succeed, got error: %v", err)
+	}
+}
+
+func TestNetworkPartitionAllowsPeersOtherPoliciesDoNotAllow(t *testing.T) {
+	allowFrontend := &networkingv1.NetworkPolicy{
+		ObjectMeta: metav1.ObjectMeta{Name: "allow-frontend", Namespace: "default"},
+		Spec: networkingv1.NetworkPolicySpec{
+			PodSelector: metav1.LabelSelector{MatchLabels: map[string]string{"app": "api"}},
+			Ingress: []networkingv1.NetworkPolicyIngressRule{{
+				From: []networkingv1.NetworkPolicyPeer{{PodSelector: &metav1.LabelSelector{MatchLabels: map[string]string{"app": "frontend"}}}},
+			}},
+		},
+	}
+	clientset := fake.NewSimpleClientset(
+		newRunningPod("api-1", "default", map[string]string{"app": "api"}),
+		newRunningPod("frontend-1", "default", map[string]string{"app": "frontend"}),
+		newRunningPod("batch-1", "default", map[string]string{"app": "batch"}),
+		allowFrontend,
+	)
+
+	// Cutting off the frontend is what allow-frontend keeps open
+	frontend := experiments.NewNetworkPartitionExperiment(clientset, experiments.NetworkPartitionConfig{
+		Namespace: "default",
+		Selector:  "app=api",
+		Duration:  1,
+		Direction: experiments.PartitionIngress,
+		Peers:     "app=frontend",
+	})
+	if _, err := frontend.Plan(context.Background()); err == nil || !strings.Contains(err.Error(), "allow-frontend (ingress)") {
+		t.Errorf("Expected the frontend partition to be refused because of allow-frontend, got %v", err)
+	}
+
+	// The batch pods are not allowed by it, so they can be cut off
+	batch := experiments.NewNetworkPartitionExperiment(clientset, experiments.NetworkPartitionConfig{
+		Namespace: "default",
+		Selector:  "app=api",
+		Duration:  1,
+		Direction: experiments.PartitionIngress,
+		Peers:     "app=batch",
+	})
+	if _, err := batch.Plan(context.Background()); err != nil {
+		t.Errorf("Expected the batch partition to be allowed, got error: %v", err)
+	}
+}
@@ -0,0 +1,192 @@
+package tests
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	policyv1 "k8s.io/api/policy/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/kubernetes/fake"
+	k8stesting "k8s.io/client-go/testing"
+
+	"github.com/flack/chaos-engineering-as-a-platform/pkg/chaos/experiments"
+)
+
+// evictionRecorder answers evictions like the API server, refusing the pods
+// a disruption budget protects and deleting the others
+type evictionRecorder struct {
+	mu       sync.Mutex
+	evicted  []string
+	cordoned []bool // Whether the node was cordoned at each eviction
+}
+
+func (r *evictionRecorder) install(t *testing.T, clientset *fake.Clientset, budgeted map[string]bool) {
+	t.Helper()
+	clientset.PrependReactor("create", "pods", func(action k8stesting.Action) (bool, runtime.Object, error) {
+		if action.GetSubresource() != "eviction" {
+			return false, nil, nil
+		}
+		eviction := action.(k8stesting.CreateAction).GetObject().(*policyv1.Eviction)
+		if budgeted[eviction.Name] {
+			return true, nil, apierrors.NewTooManyRequests("disruption budget", 5)
+		}
+
+		node, _ := clientset.Tracker().Get(corev1.SchemeGroupVersion.WithResource("nodes"), "", "node-0")
+		if err := clientset.Tracker().Delete(corev1.SchemeGroupVersion.WithResource("pods"), eviction.Namespace, eviction.Name); err != nil {
+			return true, nil, err
+		}
+
+		r.mu.Lock()
+		defer r.mu.Unlock()
+		r.evicted = append(r.evicted, eviction.Namespace+"/"+eviction.Name)
+		r.cordoned = append(r.cordoned, node.(*corev1.Node).Spec.Unschedulable)
+		return true, nil, nil
+	})
+}
+
+// newNodePod returns a running pod scheduled on the node
+func newNodePod(name, node string) *corev1.Pod {
+	pod := newRunningPod(name, "default", map[string]string{"app": "web"})
+	pod.Spec.NodeName = node
+	return pod
+}
+
+func TestNodeFailureCordonsDrainsAndUncordons(t *testing.T) {
+	daemon := newNodePod("agent-0", "node-0")
+	daemon.OwnerReferences = []metav1.OwnerReference{{Kind: "DaemonSet", Name: "agent"}}
+	completed := newNodePod("job-0", "node-0")
+	completed.Status.Phase = corev1.PodSucceeded
+	clientset := fake.NewSimpleClientset(
+		&corev1.Node{ObjectMeta: metav1.ObjectMeta{Name: "node-0"}},
+		newNodePod("web-0", "node-0"),
+		newNodePod("web-1", "node-0"),
+		daemon,
+		completed,
+	)
+	recorder := &evictionRecorder{}
+	recorder.install(t, clientset, nil)
+	journal := newMemoryJournal()
+
+	definition, _ := experiments.Lookup("node-failure")
+	result, err := experiments.Execute(context.Background(), definition, experiments.Dependencies{
+		Clientset: clientset,
+		Journal:   journal,
+	}, experiments.Spec{
+		Duration: 1,
+		Params:   map[string]string{"node": "node-0"},
+	})
+	if err != nil {
+		t.Fatalf("Expected experiment to succeed, got error: %v", err)
+	}
+
+	// Pods are evicted from the cordoned node, leaving DaemonSet and completed pods alone
+	if !reflect.DeepEqual(recorder.evicted, []string{"default/web-0", "default/web-1"}) {
+		t.Errorf("Expected the web pods on node-0 to be evicted, got %v", recorder.evicted)
+	}
+	if !reflect.DeepEqual(recorder.cordoned, []bool{true, true}) {
+		t.Errorf("Expected the node to be cordoned before the drain, got %v", recorder.cordoned)
+	}
+	expected := []string{"node/node-0", "default/web-0", "default/web-1"}
+	if !reflect.DeepEqual(result.AffectedResources, expected) {
+		t.Errorf("Expected %v to be affected, got %v", expected, result.AffectedResources)
+	}
+
+	node, err := clientset.CoreV1().Nodes().Get(context.Background(), "node-0", metav1.GetOptions{})
+	if err != nil {
+		t.Fatalf("Failed to get node: %v", err)
+	}
+	if node.Spec.Unschedulable {
+		t.Errorf("Expected the node to be uncordoned after the experiment")
+	}
+	if _, exists := node.Annotations[experiments.CordonedByAnnotation]; exists {
+		t.Errorf("Expected the cordon annotation to be removed, got %v", node.Annotations)
+	}
+	if len(journal.recorded) != 1 || !journal.resolved[journal.recorded[0].ID] {
+		t.Errorf("Expected the cordon to be journaled and resolved, got %v", journal.recorded)
+	}
+}
+
+func TestNodeFailureLeavesNodesCordonedBySomeoneElse(t *testing.T) {
+	node := &corev1.Node{ObjectMeta: metav1.ObjectMeta{Name: "node-0"}, Spec: corev1.NodeSpec{Unschedulable: true}}
+	clientset := fake.NewSimpleClientset(node, newNodePod("web-0", "node-0"))
+
+	experiment := experiments.NewNodeFailureExperiment(clientset, experiments.NodeFailureConfig{NodeName: "node-0", Duration: 1})
+	if _, err := experiment.Run(context.Background()); err == nil {
+		t.Fatalf("Expected the experiment to fail on a node that is already cordoned")
+	}
+
+	node, _ = clientset.CoreV1().Nodes().Get(context.Background(), "node-0", metav1.GetOptions{})
+	if !node.Spec.Unschedulable {
+		t.Errorf("Expected the node to stay cordoned")
+	}
+}
+
+func TestNodeFailureDrainEndsWithTheDuration(t *testing.T) {
+	clientset := fake.NewSimpleClientset(
+		&corev1.Node{ObjectMeta: metav1.ObjectMeta{Name: "node-0"}},
+		newNodePod("web-0", "node-0"),
+	)
+	recorder := &evictionRecorder{}
+	recorder.install(t, clientset, map[string]bool{"web-0": true})
+
+	// The disruption budget never allows the eviction, but the drain stops with the experiment
+	experiment := experiments.NewNodeFailureExperiment(clientset, experiments.NodeFailureConfig{
+		NodeName:     "node-0",
+		Duration:     1,
+		DrainTimeout: 300,
+	})
+	start := time.Now()
+	result, err := experiment.Run(context.Background())
+	if err != nil {
+		t.Fatalf("Expected experiment to succeed, got error: %v", err)
+	}
+	if elapsed := time.Since(start); elapsed > 3*time.Second {
+		t.Errorf("Expected the node to be held for the duration only, took %s", elapsed)
+	}
+	if result.Outcomes["default/web-0"] != experiments.OutcomePDBBlocked {
+		t.Errorf("Expected the pod to be reported as blocked by its budget, got %v", result.Outcomes)
+	}
+
+	node, _ := clientset.CoreV1().Nodes().Get(context.Background(), "node-0", metav1.GetOptions{})
+	if node.Spec.Unschedulable {
+		t.Errorf("Expected the node to be uncordoned after the experiment")
+	}
+}
+
+func TestNodeFailureRejectsZeroDrainTimeout(t *testing.T) {
+	definition, _ := experiments.Lookup("node-failure")
+	err := definition.Validate(experiments.Spec{
+		Params: map[string]string{"node": "node-0", "drain_timeout": "0"},
+	})
+
+	var fieldErrs experiments.ValidationError
+	if !errors.As(err, &fieldErrs) || len(fieldErrs) != 1 || fieldErrs[0].Field != "drain_timeout" {
+		t.Errorf("Expected drain_timeout to be rejected, got %v", err)
+	}
+}
+
+func TestUncordonNodeLeavesNodesCordonedByAnotherExperiment(t *testing.T) {
+	clientset := fake.NewSimpleClientset(&corev1.Node{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:        "node-0",
+			Annotations: map[string]string{experiments.CordonedByAnnotation: "other"},
+		},
+		Spec: corev1.NodeSpec{Unschedulable: true},
+	})
+
+	if err := experiments.UncordonNode(context.Background(), clientset, "node-0", "mine"); err != nil {
+		t.Fatalf("Failed to uncordon node: %v", err)
+	}
+
+	node, _ := clientset.CoreV1().Nodes().Get(context.Background(), "node-0", metav1.GetOptions{})
+	if !node.Spec.Unschedulable || node.Annotations[experiments.CordonedByAnnotation] != "other" {
+		t.Errorf("Expected the node to stay cordoned by the other experiment, got %+v", node)
+	}
+}
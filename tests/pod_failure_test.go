@@ -0,0 +1,219 @@
+package tests
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/kubernetes/fake"
+	k8stesting "k8s.io/client-go/testing"
+
+	"github.com/flack/chaos-engineering-as-a-platform/pkg/chaos/experiments"
+)
+
+// newPodFleet creates six web pods spread over three nodes with increasing age
+func newPodFleet() *fake.Clientset {
+	now := time.Now()
+	objects := []runtime.Object{}
+	for i := 0; i < 6; i++ {
+		pod := newRunningPod(fmt.Sprintf("web-%d", i), "default", map[string]string{"app": "web"})
+		pod.Spec.NodeName = fmt.Sprintf("node-%d", i%3)
+		pod.CreationTimestamp = metav1.NewTime(now.Add(-time.Duration(i) * time.Hour))
+		objects = append(objects, pod)
+	}
+	return fake.NewSimpleClientset(objects...)
+}
+
+func runPodFailure(t *testing.T, strategy experiments.SelectionStrategy, seed *int64, percentage int) *experiments.ExperimentResult {
+	t.Helper()
+
+	experiment := experiments.NewPodFailureExperiment(newPodFleet(), experiments.PodFailureConfig{
+		Namespace:  "default",
+		Selector:   "app=web",
+		Percentage: percentage,
+		Strategy:   strategy,
+		Seed:       seed,
+	})
+
+	result, err := experiment.Run(context.Background())
+	if err != nil {
+		t.Fatalf("Expected experiment to succeed, got error: %v", err)
+	}
+	return result
+}
+
+func TestPodFailureSeededSelectionIsReproducible(t *testing.T) {
+	seed := int64(42)
+	first := runPodFailure(t, experiments.SelectRandom, &seed, 50)
+	second := runPodFailure(t, experiments.SelectRandom, &seed, 50)
+
+	if first.Seed != seed {
+		t.Errorf("Expected seed %d to be recorded, got %d", seed, first.Seed)
+	}
+	if len(first.AffectedResources) != 3 {
+		t.Fatalf("Expected 3 pods to be deleted, got %v", first.AffectedResources)
+	}
+	if !reflect.DeepEqual(first.AffectedResources, second.AffectedResources) {
+		t.Errorf("Expected the same victims for the same seed, got %v and %v", first.AffectedResources, second.AffectedResources)
+	}
+
+	// Replaying with the recorded seed of an unseeded run gives the same victims
+	unseeded := runPodFailure(t, experiments.SelectRandom, nil, 50)
+	replay := runPodFailure(t, experiments.SelectRandom, &unseeded.Seed, 50)
+	if !reflect.DeepEqual(unseeded.AffectedResources, replay.AffectedResources) {
+		t.Errorf("Expected replay to hit %v, got %v", unseeded.AffectedResources, replay.AffectedResources)
+	}
+}
+
+func TestPodFailureSelectionStrategies(t *testing.T) {
+	oldest := runPodFailure(t, experiments.SelectOldest, nil, 34)
+	if !reflect.DeepEqual(oldest.AffectedResources, []string{"web-5", "web-4"}) {
+		t.Errorf("Expected the two oldest pods, got %v", oldest.AffectedResources)
+	}
+
+	newest := runPodFailure(t, experiments.SelectNewest, nil, 34)
+	if !reflect.DeepEqual(newest.AffectedResources, []string{"web-0", "web-1"}) {
+		t.Errorf("Expected the two newest pods, got %v", newest.AffectedResources)
+	}
+
+	spread := runPodFailure(t, experiments.SelectSpreadNodes, nil, 50)
+	nodes := map[string]bool{}
+	for _, name := range spread.AffectedResources {
+		var i int
+		fmt.Sscanf(name, "web-%d", &i)
+		nodes[fmt.Sprintf("node-%d", i%3)] = true
+	}
+	if len(nodes) != 3 {
+		t.Errorf("Expected victims on three different nodes, got %v", spread.AffectedResources)
+	}
+}
+
+func TestPodFailureRepeatedHardKills(t *testing.T) {
+	clientset := newPodFleet()
+	gracePeriod := int64(0)
+
+	experiment := experiments.NewPodFailureExperiment(clientset, experiments.PodFailureConfig{
+		Namespace:   "default",
+		Selector:    "app=web",
+		Duration:    2,
+		Percentage:  1,
+		GracePeriod: &gracePeriod,
+		Interval:    1,
+	})
+
+	result, err := experiment.Run(context.Background())
+	if err != nil {
+		t.Fatalf("Expected experiment to succeed, got error: %v", err)
+	}
+
+	if len(result.Kills) < 2 {
+		t.Fatalf("Expected at least two kill rounds, got %v", result.Kills)
+	}
+	for i := 1; i < len(result.Kills); i++ {
+		if result.Kills[i].Time.Before(result.Kills[i-1].Time) {
+			t.Errorf("Expected kills to be recorded in order, got %v", result.Kills)
+		}
+	}
+
+	for _, action := range clientset.Actions() {
+		deleteAction, ok := action.(k8stesting.DeleteAction)
+		if !ok {
+			continue
+		}
+		grace := deleteAction.GetDeleteOptions().GracePeriodSeconds
+		if grace == nil || *grace != 0 {
+			t.Errorf("Expected pods to be deleted with a grace period of 0, got %v", grace)
+		}
+	}
+}
+
+// newKillablePod returns a running pod whose app container can be killed in container-kill mode
+func newKillablePod(name string, shareProcessNamespace bool) *corev1.Pod {
+	pod := newRunningPod(name, "default", map[string]string{"app": "web"})
+	pod.Spec.ShareProcessNamespace = &shareProcessNamespace
+	pod.Status.ContainerStatuses = []corev1.ContainerStatus{{
+		Name:        "app",
+		ContainerID: "containerd://" + name + "-0123abcd",
+		State:       corev1.ContainerState{Running: &corev1.ContainerStateRunning{}},
+	}}
+	return pod
+}
+
+func TestPodFailureContainerKill(t *testing.T) {
+	clientset := fake.NewSimpleClientset(
+		newKillablePod("web-killed", true),
+		newKillablePod("web-survived", true),
+		newKillablePod("web-isolated", false),
+	)
+
+	// The chaos container finds the main process of web-killed, which
+	// restarts, and finds nothing in web-survived
+	reportChaosContainers(clientset, func(pod *corev1.Pod) corev1.ContainerState {
+		if pod.Name == "web-killed" {
+			pod.Status.ContainerStatuses[0].RestartCount++
+			return corev1.ContainerState{Terminated: &corev1.ContainerStateTerminated{ExitCode: 0}}
+		}
+		return corev1.ContainerState{Terminated: &corev1.ContainerStateTerminated{ExitCode: 1, Message: "no process of container found"}}
+	})
+
+	experiment := experiments.NewPodFailureExperiment(clientset, experiments.PodFailureConfig{
+		Namespace:  "default",
+		Selector:   "app=web",
+		Duration:   1,
+		Percentage: 100,
+		Mode:       experiments.ContainerKill,
+		Container:  "app",
+	})
+
+	result, err := experiment.Run(context.Background())
+	if err != nil {
+		t.Fatalf("Expected experiment to succeed, got error: %v", err)
+	}
+
+	kills := map[string]experiments.PodKill{}
+	for _, kill := range result.Kills {
+		kills[kill.Pod] = kill
+	}
+	if kill := kills["web-killed"]; kill.Error != "" || kill.Container != "app" {
+		t.Errorf("Expected the app container of web-killed to be killed, got %+v", kill)
+	}
+	if kill := kills["web-survived"]; !strings.Contains(kill.Error, "exit code 1: no process of container found") {
+		t.Errorf("Expected a kill that restarted nothing to be recorded as failed, got %+v", kill)
+	}
+	if kill := kills["web-isolated"]; !strings.Contains(kill.Error, "does not share its process namespace") {
+		t.Errorf("Expected a pod without a shared process namespace to be refused, got %+v", kill)
+	}
+	if !reflect.DeepEqual(result.AffectedResources, []string{"web-killed"}) {
+		t.Errorf("Expected only web-killed to be affected, got %v", result.AffectedResources)
+	}
+
+	pod, err := clientset.CoreV1().Pods("default").Get(context.Background(), "web-killed", metav1.GetOptions{})
+	if err != nil {
+		t.Fatalf("Failed to get pod: %v", err)
+	}
+	if len(pod.Spec.EphemeralContainers) != 1 {
+		t.Fatalf("Expected one chaos container, got %d", len(pod.Spec.EphemeralContainers))
+	}
+	container := pod.Spec.EphemeralContainers[0]
+	script := strings.Join(container.Command, " ")
+	if !strings.Contains(script, "grep -q web-killed-0123abcd $DIR/cgroup") || !strings.Contains(script, "exec kill -KILL ${DIR#/proc/}") {
+		t.Errorf("Expected the script to signal the main process of the container by its PID, got '%s'", script)
+	}
+	if strings.Contains(script, "kill -KILL 1") {
+		t.Errorf("Expected the script not to signal PID 1, got '%s'", script)
+	}
+	if container.Image != experiments.DefaultKillImage {
+		t.Errorf("Expected the default kill image, got %s", container.Image)
+	}
+
+	isolated, _ := clientset.CoreV1().Pods("default").Get(context.Background(), "web-isolated", metav1.GetOptions{})
+	if len(isolated.Spec.EphemeralContainers) != 0 {
+		t.Errorf("Expected nothing to be attached to a pod without a shared process namespace")
+	}
+}
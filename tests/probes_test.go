@@ -0,0 +1,114 @@
+package tests
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+
+	"github.com/flack/chaos-engineering-as-a-platform/pkg/chaos/experiments"
+	"github.com/flack/chaos-engineering-as-a-platform/pkg/chaos/probes"
+)
+
+func newProbedService(availableReplicas int32) *fake.Clientset {
+	return fake.NewSimpleClientset(
+		&corev1.Service{
+			ObjectMeta: metav1.ObjectMeta{Name: "api", Namespace: "default"},
+			Spec:       corev1.ServiceSpec{Selector: map[string]string{"app": "api"}},
+		},
+		&appsv1.Deployment{
+			ObjectMeta: metav1.ObjectMeta{Name: "api", Namespace: "default"},
+			Status:     appsv1.DeploymentStatus{AvailableReplicas: availableReplicas},
+		},
+	)
+}
+
+func replicaProbe(minAvailable int32) probes.Probe {
+	return probes.Probe{
+		Name: "api-available",
+		Type: probes.TypeKubernetes,
+		Kubernetes: &probes.KubernetesCheck{
+			Kind:                 "deployment",
+			Namespace:            "default",
+			Name:                 "api",
+			MinAvailableReplicas: &minAvailable,
+		},
+	}
+}
+
+func TestProbesRecordHypothesisVerdict(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	definition, _ := experiments.Lookup("service-failure")
+	result, err := experiments.Execute(context.Background(), definition, experiments.Dependencies{
+		Clientset: newProbedService(3),
+		Journal:   newMemoryJournal(),
+	}, experiments.Spec{
+		Namespace: "default",
+		Params:    map[string]string{"service": "api"},
+		Probes: []probes.Probe{
+			replicaProbe(2),
+			{Name: "api-up", Type: probes.TypeHTTP, HTTP: &probes.HTTPCheck{URL: server.URL, ExpectedStatus: http.StatusOK}},
+		},
+	})
+	if err != nil {
+		t.Fatalf("Expected experiment to succeed, got error: %v", err)
+	}
+
+	if result.Hypothesis == nil || result.Hypothesis.Verdict != probes.VerdictPassed {
+		t.Fatalf("Expected the hypothesis to pass, got %+v", result.Hypothesis)
+	}
+	for _, probe := range result.Hypothesis.Probes {
+		phases := []probes.Phase{}
+		for _, sample := range probe.Samples {
+			phases = append(phases, sample.Phase)
+		}
+		if len(phases) != 2 || phases[0] != probes.PhaseBefore || phases[1] != probes.PhaseAfter {
+			t.Errorf("Expected probe %s to be sampled before and after the run, got %v", probe.Name, phases)
+		}
+	}
+}
+
+func TestProbesPreventInjectionWithoutSteadyState(t *testing.T) {
+	clientset := newProbedService(1)
+	journal := newMemoryJournal()
+
+	definition, _ := experiments.Lookup("service-failure")
+	result, err := experiments.Execute(context.Background(), definition, experiments.Dependencies{
+		Clientset: clientset,
+		Journal:   journal,
+	}, experiments.Spec{
+		Namespace: "default",
+		Params:    map[string]string{"service": "api"},
+		Probes:    []probes.Probe{replicaProbe(2)},
+	})
+	if err == nil {
+		t.Fatal("Expected experiment to fail when the steady state does not hold")
+	}
+
+	if len(journal.recorded) != 0 {
+		t.Errorf("Expected nothing to be injected, got %v", journal.recorded)
+	}
+	if result == nil || result.Hypothesis == nil || result.Hypothesis.Verdict != probes.VerdictFailed {
+		t.Fatalf("Expected a failed hypothesis to be reported, got %+v", result)
+	}
+	if failed := result.Hypothesis.Failed(); len(failed) != 1 || failed[0] != "api-available" {
+		t.Errorf("Expected api-available to fail, got %v", failed)
+	}
+
+	service, err := clientset.CoreV1().Services("default").Get(context.Background(), "api", metav1.GetOptions{})
+	if err != nil {
+		t.Fatalf("Failed to get service: %v", err)
+	}
+	if service.Spec.Selector["app"] != "api" {
+		t.Errorf("Expected the service selector to be untouched, got %v", service.Spec.Selector)
+	}
+}
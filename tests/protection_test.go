@@ -0,0 +1,140 @@
+package tests
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+
+	"github.com/flack/chaos-engineering-as-a-platform/pkg/chaos/experiments"
+)
+
+func TestProtectionRejectsProtectedTargets(t *testing.T) {
+	definition, _ := experiments.Lookup("pod-failure")
+	protection := experiments.DefaultProtection
+
+	cases := []struct {
+		name      string
+		namespace string
+		objects   func() *fake.Clientset
+		expected  []string
+	}{
+		{
+			name:      "denied namespace",
+			namespace: "kube-system",
+			objects: func() *fake.Clientset {
+				return fake.NewSimpleClientset(newRunningPod("coredns-0", "kube-system", map[string]string{"app": "web"}))
+			},
+			expected: []string{"namespace kube-system"},
+		},
+		{
+			name:      "opted-out deployment",
+			namespace: "default",
+			objects: func() *fake.Clientset {
+				deployment := &appsv1.Deployment{ObjectMeta: metav1.ObjectMeta{
+					Name:        "web",
+					Namespace:   "default",
+					Annotations: map[string]string{experiments.ProtectedLabel: "true"},
+				}}
+				controller := true
+				replicaSet := &appsv1.ReplicaSet{ObjectMeta: metav1.ObjectMeta{
+					Name:      "web-7d4b9c",
+					Namespace: "default",
+					OwnerReferences: []metav1.OwnerReference{{
+						APIVersion: "apps/v1", Kind: "Deployment", Name: "web", Controller: &controller,
+					}},
+				}}
+				return fake.NewSimpleClientset(deployment, replicaSet, newReadyReplica("web-7d4b9c-0"))
+			},
+			expected: []string{"deployment default/web"},
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			clientset := tc.objects()
+			_, err := experiments.Execute(context.Background(), definition, experiments.Dependencies{
+				Clientset:  clientset,
+				Journal:    newMemoryJournal(),
+				Protection: &protection,
+			}, experiments.Spec{
+				Namespace: tc.namespace,
+				Selector:  "app=web",
+				Duration:  1,
+				Params:    map[string]string{"percentage": "100"},
+			})
+
+			var protectedErr *experiments.ProtectedError
+			if !errors.As(err, &protectedErr) {
+				t.Fatalf("Expected a protected error, got %v", err)
+			}
+			if !reflect.DeepEqual(protectedErr.Resources, tc.expected) {
+				t.Errorf("Expected %v to be reported, got %v", tc.expected, protectedErr.Resources)
+			}
+
+			pods, _ := clientset.CoreV1().Pods(tc.namespace).List(context.Background(), metav1.ListOptions{})
+			if len(pods.Items) != 1 {
+				t.Errorf("Expected the protected pod to be left alone, %d left", len(pods.Items))
+			}
+		})
+	}
+}
+
+func TestNodeFailureLeavesProtectedPodsRunning(t *testing.T) {
+	node := &corev1.Node{ObjectMeta: metav1.ObjectMeta{Name: "node-0"}}
+	web := newRunningPod("web-0", "default", nil)
+	web.Spec.NodeName = "node-0"
+	dns := newRunningPod("coredns-0", "kube-system", nil)
+	dns.Spec.NodeName = "node-0"
+	protection := experiments.DefaultProtection
+
+	definition, _ := experiments.Lookup("node-failure")
+	plan, err := experiments.DryRun(context.Background(), definition, experiments.Dependencies{
+		Clientset:  fake.NewSimpleClientset(node, web, dns),
+		Protection: &protection,
+	}, experiments.Spec{
+		Duration: 30,
+		Params:   map[string]string{"node": "node-0"},
+	})
+	if err != nil {
+		t.Fatalf("Expected dry run to succeed, got error: %v", err)
+	}
+
+	expected := []string{"node/node-0", "default/web-0"}
+	if !reflect.DeepEqual(plan.AffectedResources, expected) {
+		t.Errorf("Expected only %v to be affected, got %v", expected, plan.AffectedResources)
+	}
+}
+
+func TestOptInModeExcludesPodsWithoutAnnotation(t *testing.T) {
+	optedIn := newRunningPod("web-0", "default", map[string]string{"app": "web"})
+	optedIn.Annotations = map[string]string{experiments.OptInAnnotation: "true"}
+	other := newRunningPod("web-1", "default", map[string]string{"app": "web"})
+	protection := experiments.Protection{OptIn: true}
+
+	definition, _ := experiments.Lookup("pod-failure")
+	plan, err := experiments.DryRun(context.Background(), definition, experiments.Dependencies{
+		Clientset:  fake.NewSimpleClientset(optedIn, other),
+		Protection: &protection,
+	}, experiments.Spec{
+		Namespace: "default",
+		Selector:  "app=web",
+		Duration:  30,
+		Params:    map[string]string{"percentage": "100"},
+	})
+	if err != nil {
+		t.Fatalf("Expected dry run to succeed, got error: %v", err)
+	}
+
+	if !reflect.DeepEqual(plan.AffectedResources, []string{"web-0"}) {
+		t.Errorf("Expected only the opted-in pod to be planned, got %v", plan.AffectedResources)
+	}
+	if !reflect.DeepEqual(plan.Excluded, []string{"pod default/web-1"}) {
+		t.Errorf("Expected the other pod to be excluded, got %v", plan.Excluded)
+	}
+}
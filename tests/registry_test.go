@@ -0,0 +1,50 @@
+package tests
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/flack/chaos-engineering-as-a-platform/pkg/chaos/experiments"
+)
+
+func TestValidateReportsFieldErrors(t *testing.T) {
+	definition, exists := experiments.Lookup("pod-failure")
+	if !exists {
+		t.Fatal("Expected pod-failure to be registered")
+	}
+
+	err := definition.Validate(experiments.Spec{
+		Namespace: "default",
+		Params: map[string]string{
+			"percentage": "150",
+			"strategy":   "loudest",
+			"seed":       "abc",
+			"percentge":  "50",
+		},
+	})
+
+	var fieldErrs experiments.ValidationError
+	if !errors.As(err, &fieldErrs) {
+		t.Fatalf("Expected a validation error, got %v", err)
+	}
+
+	fields := []string{}
+	for _, fieldErr := range fieldErrs {
+		fields = append(fields, fieldErr.Field)
+	}
+	expected := []string{"selector", "percentage", "strategy", "seed", "percentge"}
+	if !reflect.DeepEqual(fields, expected) {
+		t.Errorf("Expected errors for %v, got %v", expected, fieldErrs)
+	}
+
+	// The resolved selector satisfies the required parameter
+	err = definition.Validate(experiments.Spec{
+		Namespace: "default",
+		Selector:  "app=api",
+		Params:    map[string]string{"percentage": "50", "mode": "container-kill"},
+	})
+	if err != nil {
+		t.Errorf("Expected valid parameters to pass, got %v", err)
+	}
+}
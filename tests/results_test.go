@@ -0,0 +1,151 @@
+package tests
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/flack/chaos-engineering-as-a-platform/pkg/api/routes"
+	"github.com/flack/chaos-engineering-as-a-platform/pkg/chaos/executor"
+	"github.com/flack/chaos-engineering-as-a-platform/pkg/chaos/experiments"
+	"github.com/flack/chaos-engineering-as-a-platform/pkg/config"
+	"github.com/flack/chaos-engineering-as-a-platform/pkg/storage"
+)
+
+// createTestExperiment stores a pod-failure experiment with the ID
+func createTestExperiment(t *testing.T, db *storage.Database, id string) {
+	t.Helper()
+	err := db.CreateExperiment(&storage.Experiment{
+		ID:         id,
+		Name:       "Test Experiment",
+		Type:       storage.PodFailure,
+		Status:     storage.StatusPending,
+		Target:     "app=web",
+		Parameters: "{}",
+		CreatedAt:  time.Now(),
+		UpdatedAt:  time.Now(),
+		Duration:   60,
+	})
+	if err != nil {
+		t.Fatalf("Failed to create experiment: %v", err)
+	}
+}
+
+func TestExperimentResultsAreListedMostRecentFirst(t *testing.T) {
+	db, _ := newMemoryDatabase(t)
+	start := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+
+	saved := []*storage.ExperimentResult{
+		{
+			ID:           "result-1",
+			ExperimentID: "exp-1",
+			RunID:        "run-1",
+			Status:       storage.StatusCompleted,
+			StartTime:    start,
+			EndTime:      start.Add(time.Minute),
+			Metrics:      map[string]float64{"pods_killed": 2},
+			Logs:         []string{"first", "second"},
+			Details:      json.RawMessage(`{"success":true}`),
+		},
+		// A result saved without a run that never finished
+		{ID: "result-2", ExperimentID: "exp-1", Status: storage.StatusFailed, StartTime: start.Add(time.Hour)},
+		{ID: "result-3", ExperimentID: "exp-2", Status: storage.StatusCompleted, StartTime: start},
+	}
+	for _, result := range saved {
+		if err := db.SaveExperimentResult(result); err != nil {
+			t.Fatalf("Failed to save result: %v", err)
+		}
+	}
+
+	results, err := db.ListExperimentResults("exp-1")
+	if err != nil {
+		t.Fatalf("Failed to list results: %v", err)
+	}
+	if len(results) != 2 || results[0].ID != "result-2" || results[1].ID != "result-1" {
+		t.Fatalf("Expected the results of exp-1 most recent first, got %+v", results)
+	}
+
+	unfinished := results[0]
+	if unfinished.RunID != "" || !unfinished.EndTime.IsZero() || len(unfinished.Metrics) != 0 || len(unfinished.Logs) != 0 {
+		t.Errorf("Expected the result without a run to keep its empty fields, got %+v", unfinished)
+	}
+	if string(unfinished.Details) != "{}" {
+		t.Errorf("Expected empty details to be stored as an empty document, got %s", unfinished.Details)
+	}
+
+	finished := results[1]
+	if finished.RunID != "run-1" || !finished.StartTime.Equal(start) || !finished.EndTime.Equal(start.Add(time.Minute)) {
+		t.Errorf("Expected the run and times to be kept, got %+v", finished)
+	}
+	if !reflect.DeepEqual(finished.Metrics, map[string]float64{"pods_killed": 2}) {
+		t.Errorf("Expected the metrics to be kept, got %v", finished.Metrics)
+	}
+	if !reflect.DeepEqual(finished.Logs, []string{"first", "second"}) {
+		t.Errorf("Expected the logs to be kept line by line, got %v", finished.Logs)
+	}
+	if string(finished.Details) != `{"success":true}` {
+		t.Errorf("Expected the details to be kept, got %s", finished.Details)
+	}
+}
+
+func TestResultsEndpointReturnsExperimentResults(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	db, _ := newMemoryDatabase(t)
+	createTestExperiment(t, db, "exp-1")
+
+	start := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	err := executor.SaveResult(db, &experiments.ExperimentResult{
+		ID:                "result-1",
+		ExperimentID:      "exp-1",
+		RunID:             "run-1",
+		ExperimentType:    "pod-failure",
+		StartTime:         start,
+		EndTime:           start.Add(time.Minute),
+		Duration:          60,
+		Success:           true,
+		AffectedResources: []string{"default/web-0"},
+		Kills:             []experiments.PodKill{{Pod: "default/web-0", Time: start}},
+	})
+	if err != nil {
+		t.Fatalf("Failed to save result: %v", err)
+	}
+
+	router := routes.SetupRouter(db, testMetrics(), nil, nil, &config.Config{})
+	recorder := httptest.NewRecorder()
+	router.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/api/v1/experiments/exp-1/results", nil))
+	if recorder.Code != http.StatusOK {
+		t.Fatalf("Expected the results to be returned, got %d: %s", recorder.Code, recorder.Body.String())
+	}
+
+	var results []experiments.ExperimentResult
+	if err := json.Unmarshal(recorder.Body.Bytes(), &results); err != nil {
+		t.Fatalf("Failed to parse response: %v", err)
+	}
+	if len(results) != 1 {
+		t.Fatalf("Expected one result, got %d", len(results))
+	}
+	result := results[0]
+	if result.RunID != "run-1" || result.ExperimentType != "pod-failure" || !result.Success || result.Duration != 60 {
+		t.Errorf("Expected the result to be returned as the experiment reported it, got %+v", result)
+	}
+	if !reflect.DeepEqual(result.AffectedResources, []string{"default/web-0"}) || len(result.Kills) != 1 {
+		t.Errorf("Expected the affected resources and kills to be returned, got %+v", result)
+	}
+}
+
+func TestResultsEndpointRejectsUnknownExperiments(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	db, _ := newMemoryDatabase(t)
+
+	router := routes.SetupRouter(db, testMetrics(), nil, nil, &config.Config{})
+	recorder := httptest.NewRecorder()
+	router.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/api/v1/experiments/missing/results", nil))
+	if recorder.Code != http.StatusNotFound {
+		t.Errorf("Expected an unknown experiment to be rejected, got %d: %s", recorder.Code, recorder.Body.String())
+	}
+}
@@ -0,0 +1,122 @@
+package tests
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/flack/chaos-engineering-as-a-platform/pkg/api/routes"
+	"github.com/flack/chaos-engineering-as-a-platform/pkg/config"
+	"github.com/flack/chaos-engineering-as-a-platform/pkg/k8s/operator"
+	"github.com/flack/chaos-engineering-as-a-platform/pkg/storage"
+)
+
+func TestStopEndpointStopsOperatorExperiment(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	var cleanups int32
+	target := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/cleanup" {
+			atomic.AddInt32(&cleanups, 1)
+		}
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer target.Close()
+
+	cfg := &config.Config{MockKubernetes: true}
+	chaosOperator, err := operator.NewChaosOperator(cfg, nil, testMetrics())
+	if err != nil {
+		t.Fatalf("Failed to create operator: %v", err)
+	}
+	if _, err := chaosOperator.RunExperiment(&operator.ExperimentConfig{
+		ID:       "api-stop",
+		Target:   target.URL,
+		Params:   map[string]string{"target_type": "external", "endpoint": "/inject"},
+		Duration: 300,
+	}); err != nil {
+		t.Fatalf("Failed to run experiment: %v", err)
+	}
+
+	// The router the api-server serves stops experiments on the operator it was given
+	router := routes.SetupRouter(nil, testMetrics(), chaosOperator, nil, cfg)
+	recorder := httptest.NewRecorder()
+	router.ServeHTTP(recorder, httptest.NewRequest(http.MethodPost, "/api/v1/experiments/api-stop/stop", nil))
+
+	if recorder.Code != http.StatusOK {
+		t.Fatalf("Expected the experiment to be stopped, got %d: %s", recorder.Code, recorder.Body.String())
+	}
+	if count := atomic.LoadInt32(&cleanups); count != 1 {
+		t.Errorf("Expected the experiment to be cleaned up before the response, got %d cleanups", count)
+	}
+	if _, err := chaosOperator.GetExperimentStatus("api-stop"); err == nil {
+		t.Errorf("Expected the stopped experiment to be removed")
+	}
+}
+
+func TestStopEndpointAsksOwningProcessToStop(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	var cleanups int32
+	target := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/cleanup" {
+			atomic.AddInt32(&cleanups, 1)
+		}
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer target.Close()
+
+	db, _ := newMemoryDatabase(t)
+	createTestExperiment(t, db, "remote-stop")
+
+	// The experiment runs on the chaos-operator, not on the api-server asked to stop it
+	owner, err := operator.NewChaosOperator(&config.Config{MockKubernetes: true, OperatorID: "chaos-operator"}, db, testMetrics())
+	if err != nil {
+		t.Fatalf("Failed to create operator: %v", err)
+	}
+	if err := owner.Start(); err != nil {
+		t.Fatalf("Failed to start operator: %v", err)
+	}
+	defer owner.Stop()
+	run, err := owner.RunExperiment(&operator.ExperimentConfig{
+		ID:       "remote-stop",
+		Target:   target.URL,
+		Params:   map[string]string{"target_type": "external", "endpoint": "/inject"},
+		Duration: 300,
+	})
+	if err != nil {
+		t.Fatalf("Failed to run experiment: %v", err)
+	}
+
+	cfg := &config.Config{MockKubernetes: true, OperatorID: "api-server"}
+	apiOperator, err := operator.NewChaosOperator(cfg, db, testMetrics())
+	if err != nil {
+		t.Fatalf("Failed to create operator: %v", err)
+	}
+	router := routes.SetupRouter(db, testMetrics(), apiOperator, nil, cfg)
+
+	start := time.Now()
+	recorder := httptest.NewRecorder()
+	router.ServeHTTP(recorder, httptest.NewRequest(http.MethodPost, "/api/v1/experiments/remote-stop/stop", nil))
+	if recorder.Code != http.StatusOK {
+		t.Fatalf("Expected the experiment to be stopped, got %d: %s", recorder.Code, recorder.Body.String())
+	}
+	if elapsed := time.Since(start); elapsed > time.Minute {
+		t.Errorf("Expected the owner to stop the run on its next check, took %s", elapsed)
+	}
+	if count := atomic.LoadInt32(&cleanups); count != 1 {
+		t.Errorf("Expected the experiment to be cleaned up before the response, got %d cleanups", count)
+	}
+
+	stored, err := db.GetRun(run.ID)
+	if err != nil {
+		t.Fatalf("Failed to get run: %v", err)
+	}
+	last := stored.Transitions[len(stored.Transitions)-1]
+	if stored.Status != storage.StatusCancelled || last.Actor != "operator/chaos-operator" || last.Reason != "Stopped" {
+		t.Errorf("Expected the run to be cancelled by its owner, got %+v", stored)
+	}
+}
@@ -0,0 +1,33 @@
+package tests
+
+import (
+	"testing"
+
+	"github.com/flack/chaos-engineering-as-a-platform/pkg/storage"
+)
+
+func TestRunStatusTransitions(t *testing.T) {
+	cases := []struct {
+		from    storage.ExperimentStatus
+		to      storage.ExperimentStatus
+		allowed bool
+	}{
+		{storage.StatusPending, storage.StatusRunning, true},
+		{storage.StatusPending, storage.StatusFailed, true},
+		{storage.StatusPending, storage.StatusCompleted, false},
+		{storage.StatusRunning, storage.StatusCompleted, true},
+		{storage.StatusRunning, storage.StatusAborted, true},
+		{storage.StatusRunning, storage.StatusCancelled, true},
+		{storage.StatusRunning, storage.StatusPending, false},
+		{storage.StatusRunning, storage.StatusRunning, false},
+		{storage.StatusCompleted, storage.StatusRunning, false},
+		{storage.StatusFailed, storage.StatusCompleted, false},
+		{storage.StatusCancelled, storage.StatusRunning, false},
+	}
+
+	for _, tc := range cases {
+		if allowed := storage.CanTransition(tc.from, tc.to); allowed != tc.allowed {
+			t.Errorf("Expected moving from %s to %s to be allowed: %v, got %v", tc.from, tc.to, tc.allowed, allowed)
+		}
+	}
+}
@@ -0,0 +1,169 @@
+package tests
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+
+	"github.com/flack/chaos-engineering-as-a-platform/pkg/api/routes"
+	"github.com/flack/chaos-engineering-as-a-platform/pkg/config"
+	"github.com/flack/chaos-engineering-as-a-platform/pkg/k8s/operator"
+	"github.com/flack/chaos-engineering-as-a-platform/pkg/storage"
+)
+
+// createTestRun stores a pending run of the experiment created at the time
+func createTestRun(t *testing.T, db *storage.Database, id, experimentID string, createdAt time.Time) {
+	t.Helper()
+	err := db.CreateRun(&storage.Run{
+		ID:           id,
+		ExperimentID: experimentID,
+		Trigger:      storage.RunTriggerAPI,
+		Status:       storage.StatusPending,
+		CreatedAt:    createdAt,
+		Owner:        "operator/api-server",
+	}, "operator/api-server")
+	if err != nil {
+		t.Fatalf("Failed to create run: %v", err)
+	}
+}
+
+func TestCreateRunRecordsItsFirstTransition(t *testing.T) {
+	db, _ := newMemoryDatabase(t)
+	start := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	createTestRun(t, db, "run-1", "exp-1", start)
+
+	run, err := db.GetRun("run-1")
+	if err != nil {
+		t.Fatalf("Failed to get run: %v", err)
+	}
+	if run.ExperimentID != "exp-1" || run.Trigger != storage.RunTriggerAPI || run.Status != storage.StatusPending || run.Owner != "operator/api-server" {
+		t.Errorf("Expected the run to be stored as created, got %+v", run)
+	}
+	if run.StartedAt != nil || run.EndedAt != nil || run.Result != nil {
+		t.Errorf("Expected a pending run to have no start, end or result, got %+v", run)
+	}
+
+	if len(run.Transitions) != 1 {
+		t.Fatalf("Expected the creation to be the first transition, got %+v", run.Transitions)
+	}
+	created := run.Transitions[0]
+	if created.From != "" || created.To != storage.StatusPending || created.Actor != "operator/api-server" || created.Reason != "Triggered by api" {
+		t.Errorf("Expected the run to be created pending by its actor, got %+v", created)
+	}
+}
+
+func TestExperimentRunsAreListedMostRecentFirst(t *testing.T) {
+	db, _ := newMemoryDatabase(t)
+	start := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	createTestRun(t, db, "run-1", "exp-1", start)
+	createTestRun(t, db, "run-2", "exp-1", start.Add(time.Hour))
+	createTestRun(t, db, "run-3", "exp-2", start.Add(2*time.Hour))
+
+	if err := db.TransitionRun("run-1", storage.StatusRunning, "executor", "Started"); err != nil {
+		t.Fatalf("Failed to start run: %v", err)
+	}
+	if err := db.TransitionRun("run-1", storage.StatusFailed, "executor", "Target unreachable"); err != nil {
+		t.Fatalf("Failed to fail run: %v", err)
+	}
+
+	runs, err := db.ListExperimentRuns("exp-1")
+	if err != nil {
+		t.Fatalf("Failed to list runs: %v", err)
+	}
+	if len(runs) != 2 || runs[0].ID != "run-2" || runs[1].ID != "run-1" {
+		t.Fatalf("Expected the runs of exp-1 most recent first, got %+v", runs)
+	}
+
+	failed := runs[1]
+	if failed.Status != storage.StatusFailed || failed.Error != "Target unreachable" {
+		t.Errorf("Expected the failed run to keep its error, got %+v", failed)
+	}
+	if failed.StartedAt == nil || failed.EndedAt == nil {
+		t.Errorf("Expected the failed run to have started and ended, got %+v", failed)
+	}
+}
+
+func TestRunsEndpointReturnsExperimentRuns(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	db, _ := newMemoryDatabase(t)
+	createTestExperiment(t, db, "exp-1")
+	start := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	createTestRun(t, db, "run-1", "exp-1", start)
+	createTestRun(t, db, "run-2", "exp-1", start.Add(time.Hour))
+
+	router := routes.SetupRouter(db, testMetrics(), nil, nil, &config.Config{})
+	recorder := httptest.NewRecorder()
+	router.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/api/v1/experiments/exp-1/runs", nil))
+	if recorder.Code != http.StatusOK {
+		t.Fatalf("Expected the runs to be returned, got %d: %s", recorder.Code, recorder.Body.String())
+	}
+
+	var runs []storage.Run
+	if err := json.Unmarshal(recorder.Body.Bytes(), &runs); err != nil {
+		t.Fatalf("Failed to parse response: %v", err)
+	}
+	if len(runs) != 2 || runs[0].ID != "run-2" || runs[1].ID != "run-1" || runs[0].Trigger != storage.RunTriggerAPI {
+		t.Errorf("Expected both runs most recent first, got %+v", runs)
+	}
+
+	recorder = httptest.NewRecorder()
+	router.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/api/v1/experiments/missing/runs", nil))
+	if recorder.Code != http.StatusNotFound {
+		t.Errorf("Expected an unknown experiment to be rejected, got %d: %s", recorder.Code, recorder.Body.String())
+	}
+}
+
+func TestOperatorRunIsActiveUntilItEnds(t *testing.T) {
+	target := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer target.Close()
+
+	db, _ := newMemoryDatabase(t)
+	metrics := testMetrics()
+	chaosOperator, err := operator.NewChaosOperator(&config.Config{MockKubernetes: true, OperatorID: "api-server"}, db, metrics)
+	if err != nil {
+		t.Fatalf("Failed to create operator: %v", err)
+	}
+
+	active := testutil.ToFloat64(metrics.ActiveExperiments)
+	run, err := chaosOperator.RunExperiment(&operator.ExperimentConfig{
+		ID:       "short",
+		Target:   target.URL,
+		Params:   map[string]string{"target_type": "external", "endpoint": "/inject"},
+		Duration: 1,
+	})
+	if err != nil {
+		t.Fatalf("Failed to run experiment: %v", err)
+	}
+	if gauge := testutil.ToFloat64(metrics.ActiveExperiments); gauge != active+1 {
+		t.Errorf("Expected the run to be counted as active, got %v", gauge)
+	}
+
+	// A run that ends on its own is no longer active
+	deadline := time.Now().Add(10 * time.Second)
+	for testutil.ToFloat64(metrics.ActiveExperiments) != active {
+		if time.Now().After(deadline) {
+			t.Fatalf("Expected the finished run to stop counting as active, got %v", testutil.ToFloat64(metrics.ActiveExperiments))
+		}
+		time.Sleep(50 * time.Millisecond)
+	}
+
+	stored, err := db.GetRun(run.ID)
+	if err != nil {
+		t.Fatalf("Failed to get run: %v", err)
+	}
+	if len(stored.Transitions) != 3 || stored.Status != storage.StatusCompleted {
+		t.Fatalf("Expected the run to be created, started and completed, got %+v", stored.Transitions)
+	}
+	for _, transition := range stored.Transitions {
+		if transition.Actor != "operator/api-server" {
+			t.Errorf("Expected every change to be made by the operator that owns the run, got %+v", transition)
+		}
+	}
+}
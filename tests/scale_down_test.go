@@ -0,0 +1,96 @@
+package tests
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	appsv1 "k8s.io/api/apps/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+
+	"github.com/flack/chaos-engineering-as-a-platform/pkg/chaos/experiments"
+)
+
+func TestScaleDownRestoresReplicasOnCancel(t *testing.T) {
+	replicas := int32(5)
+	clientset := fake.NewSimpleClientset(&appsv1.Deployment{
+		ObjectMeta: metav1.ObjectMeta{Name: "web", Namespace: "default"},
+		Spec:       appsv1.DeploymentSpec{Replicas: &replicas},
+	})
+
+	experiment := experiments.NewScaleDownExperiment(clientset, experiments.ScaleDownConfig{
+		Namespace:  "default",
+		Name:       "web",
+		Percentage: 40,
+		Duration:   60,
+	})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan *experiments.ExperimentResult, 1)
+	go func() {
+		result, _ := experiment.Run(ctx)
+		done <- result
+	}()
+
+	// While the experiment runs the deployment is scaled down and remembers its original size
+	time.Sleep(300 * time.Millisecond)
+	deployment, err := clientset.AppsV1().Deployments("default").Get(context.Background(), "web", metav1.GetOptions{})
+	if err != nil {
+		t.Fatalf("Failed to get deployment: %v", err)
+	}
+	if *deployment.Spec.Replicas != 3 {
+		t.Errorf("Expected deployment to be scaled down to 3 replicas, got %d", *deployment.Spec.Replicas)
+	}
+	if deployment.Annotations[experiments.OriginalReplicasAnnotation] != "5" {
+		t.Errorf("Expected original replicas annotation to be 5, got %v", deployment.Annotations)
+	}
+
+	cancel()
+	result := <-done
+
+	if result.Metrics["original_replicas"] != 5 || result.Metrics["reduced_replicas"] != 3 {
+		t.Errorf("Expected metrics to record 5 and 3 replicas, got %v", result.Metrics)
+	}
+
+	deployment, err = clientset.AppsV1().Deployments("default").Get(context.Background(), "web", metav1.GetOptions{})
+	if err != nil {
+		t.Fatalf("Failed to get deployment: %v", err)
+	}
+	if *deployment.Spec.Replicas != 5 {
+		t.Errorf("Expected deployment to be restored to 5 replicas after cancel, got %d", *deployment.Spec.Replicas)
+	}
+	if _, exists := deployment.Annotations[experiments.OriginalReplicasAnnotation]; exists {
+		t.Errorf("Expected original replicas annotation to be removed, got %v", deployment.Annotations)
+	}
+}
+
+func TestScaleDownRestoresUnsetReplicas(t *testing.T) {
+	clientset := fake.NewSimpleClientset(&appsv1.Deployment{
+		ObjectMeta: metav1.ObjectMeta{Name: "web", Namespace: "default"},
+	})
+
+	zero := int32(0)
+	experiment := experiments.NewScaleDownExperiment(clientset, experiments.ScaleDownConfig{
+		Namespace: "default",
+		Name:      "web",
+		Replicas:  &zero,
+		Duration:  1,
+	})
+	result, err := experiment.Run(context.Background())
+	if err != nil {
+		t.Fatalf("Expected experiment to succeed, got error: %v", err)
+	}
+	if result.Metrics["original_replicas"] != 1 || result.Metrics["reduced_replicas"] != 0 {
+		t.Errorf("Expected metrics to record 1 and 0 replicas, got %v", result.Metrics)
+	}
+
+	// The replica count is left unset again rather than pinned to the default
+	deployment, err := clientset.AppsV1().Deployments("default").Get(context.Background(), "web", metav1.GetOptions{})
+	if err != nil {
+		t.Fatalf("Failed to get deployment: %v", err)
+	}
+	if deployment.Spec.Replicas != nil {
+		t.Errorf("Expected the replica count to be restored unset, got %d", *deployment.Spec.Replicas)
+	}
+}
@@ -0,0 +1,71 @@
+package tests
+
+import (
+	"context"
+	"reflect"
+	"testing"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+
+	"github.com/flack/chaos-engineering-as-a-platform/pkg/chaos/experiments"
+)
+
+func TestServiceFailureExperimentRestoresSelector(t *testing.T) {
+	originalSelector := map[string]string{"app": "api", "tier": "backend"}
+	clientset := fake.NewSimpleClientset(&corev1.Service{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "api",
+			Namespace: "default",
+			Labels:    map[string]string{"app": "api"},
+		},
+		Spec: corev1.ServiceSpec{Selector: originalSelector},
+	})
+
+	experiment := experiments.NewServiceFailureExperiment(clientset, experiments.ServiceFailureConfig{
+		Namespace: "default",
+		Selector:  "app=api",
+		Duration:  1,
+	})
+
+	type runResult struct {
+		result *experiments.ExperimentResult
+		err    error
+	}
+	done := make(chan runResult, 1)
+	go func() {
+		result, err := experiment.Run(context.Background())
+		done <- runResult{result, err}
+	}()
+
+	// While the experiment runs the service must select no pods
+	time.Sleep(300 * time.Millisecond)
+	service, err := clientset.CoreV1().Services("default").Get(context.Background(), "api", metav1.GetOptions{})
+	if err != nil {
+		t.Fatalf("Failed to get service: %v", err)
+	}
+	if _, ok := service.Spec.Selector[experiments.BlackholeSelectorLabel]; !ok {
+		t.Errorf("Expected service selector to be replaced during the experiment, got %v", service.Spec.Selector)
+	}
+
+	run := <-done
+	if run.err != nil {
+		t.Fatalf("Expected experiment to succeed, got error: %v", run.err)
+	}
+	if len(run.result.AffectedResources) != 1 || run.result.AffectedResources[0] != "api" {
+		t.Errorf("Expected service api to be affected, got %v", run.result.AffectedResources)
+	}
+
+	service, err = clientset.CoreV1().Services("default").Get(context.Background(), "api", metav1.GetOptions{})
+	if err != nil {
+		t.Fatalf("Failed to get service: %v", err)
+	}
+	if !reflect.DeepEqual(service.Spec.Selector, originalSelector) {
+		t.Errorf("Expected selector %v to be restored, got %v", originalSelector, service.Spec.Selector)
+	}
+	if _, ok := service.Annotations[experiments.OriginalSelectorAnnotation]; ok {
+		t.Errorf("Expected restore annotation to be removed")
+	}
+}
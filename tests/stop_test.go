@@ -0,0 +1,57 @@
+package tests
+
+import (
+	"context"
+	"sync"
+	"testing"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/flack/chaos-engineering-as-a-platform/pkg/k8s"
+	"github.com/flack/chaos-engineering-as-a-platform/pkg/k8s/operator"
+	"github.com/flack/chaos-engineering-as-a-platform/pkg/monitoring"
+	"github.com/flack/chaos-engineering-as-a-platform/pkg/storage"
+)
+
+var (
+	metricsOnce sync.Once
+	metrics     *monitoring.Metrics
+)
+
+// testMetrics returns the metrics shared by the tests, which can only be registered once
+func testMetrics() *monitoring.Metrics {
+	metricsOnce.Do(func() {
+		metrics = monitoring.NewMetrics()
+	})
+	return metrics
+}
+
+func TestStopCancelsRunningExperiment(t *testing.T) {
+	client := k8s.NewMockClient()
+	pod := newRunningPod("web-0", "default", map[string]string{"app": "web"})
+	if _, err := client.GetClientset().CoreV1().Pods("default").Create(context.Background(), pod, metav1.CreateOptions{}); err != nil {
+		t.Fatalf("Failed to create pod: %v", err)
+	}
+
+	controller, err := operator.NewExperimentController(&operator.ExperimentConfig{
+		ID:       "stop-test",
+		Type:     "pod-failure",
+		Params:   map[string]string{"namespace": "default", "selector": "app=web", "percentage": "100"},
+		Duration: 300,
+	}, client, nil, testMetrics())
+	if err != nil {
+		t.Fatalf("Failed to create controller: %v", err)
+	}
+
+	if err := controller.Start(); err != nil {
+		t.Fatalf("Failed to start experiment: %v", err)
+	}
+	if err := controller.Stop("Stopped"); err != nil {
+		t.Fatalf("Failed to stop experiment: %v", err)
+	}
+
+	// Stop returns once the run has ended, so its status is final
+	if status := controller.GetStatus(); status != string(storage.StatusCancelled) {
+		t.Errorf("Expected the experiment to be cancelled, got %s", status)
+	}
+}
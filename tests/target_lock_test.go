@@ -0,0 +1,189 @@
+package tests
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/flack/chaos-engineering-as-a-platform/pkg/chaos/experiments"
+	"github.com/flack/chaos-engineering-as-a-platform/pkg/config"
+	"github.com/flack/chaos-engineering-as-a-platform/pkg/k8s/operator"
+	"github.com/flack/chaos-engineering-as-a-platform/pkg/storage"
+)
+
+func TestTargetKeySharedByEquivalentTargets(t *testing.T) {
+	podFailure, _ := experiments.Lookup("pod-failure")
+	cpuStress, _ := experiments.Lookup("cpu-stress")
+
+	// The order of the requirements does not change the target
+	first := experiments.TargetKey(podFailure, experiments.Spec{Namespace: "shop", Selector: "app=checkout,tier=web"})
+	second := experiments.TargetKey(cpuStress, experiments.Spec{Namespace: "shop", Selector: "tier=web, app=checkout"})
+	if first != second {
+		t.Errorf("Expected equivalent selectors to share a key, got %s and %s", first, second)
+	}
+
+	other := experiments.TargetKey(podFailure, experiments.Spec{Namespace: "default", Selector: "app=checkout,tier=web"})
+	if other == first {
+		t.Errorf("Expected the same selector in another namespace to have its own key, got %s", other)
+	}
+}
+
+func TestTargetKeyNamesObjects(t *testing.T) {
+	nodeFailure, _ := experiments.Lookup("node-failure")
+	scaleDown, _ := experiments.Lookup("scale-down")
+
+	cases := []struct {
+		name       string
+		definition experiments.Definition
+		spec       experiments.Spec
+		expected   string
+	}{
+		{
+			name:       "node",
+			definition: nodeFailure,
+			spec:       experiments.Spec{Namespace: "default", Params: map[string]string{"node": "node-0"}},
+			expected:   "cluster/node/node-0",
+		},
+		{
+			name:       "deployment",
+			definition: scaleDown,
+			spec:       experiments.Spec{Namespace: "shop", Params: map[string]string{"namespace": "shop", "name": "checkout"}},
+			expected:   "shop/deployment/checkout",
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			if key := experiments.TargetKey(tc.definition, tc.spec); key != tc.expected {
+				t.Errorf("Expected key %s, got %s", tc.expected, key)
+			}
+		})
+	}
+}
+
+// memoryLocks keeps target locks in memory, as the target_locks table does
+type memoryLocks struct {
+	mu    sync.Mutex
+	locks map[string]*storage.TargetLock
+}
+
+func newMemoryLocks() *memoryLocks {
+	return &memoryLocks{locks: map[string]*storage.TargetLock{}}
+}
+
+func (l *memoryLocks) AcquireTargetLock(lock *storage.TargetLock) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if holder, exists := l.locks[lock.Target]; exists && holder.ExpiresAt.After(lock.AcquiredAt) {
+		return &storage.LockConflictError{Lock: holder}
+	}
+	l.locks[lock.Target] = lock
+	return nil
+}
+
+func (l *memoryLocks) ReleaseTargetLock(target, experimentID string) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if holder, exists := l.locks[target]; exists && holder.ExperimentID == experimentID {
+		delete(l.locks, target)
+	}
+	return nil
+}
+
+func (l *memoryLocks) held() int {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return len(l.locks)
+}
+
+// newLockingOperator returns an operator on a mock cluster with web pods, locking targets in memory
+func newLockingOperator(t *testing.T) (*operator.ChaosOperator, *memoryLocks) {
+	t.Helper()
+	chaosOperator, err := operator.NewChaosOperator(&config.Config{MockKubernetes: true, OperatorID: "api-server"}, nil, testMetrics())
+	if err != nil {
+		t.Fatalf("Failed to create operator: %v", err)
+	}
+	for _, name := range []string{"web-0", "web-1"} {
+		pod := newRunningPod(name, "default", map[string]string{"app": "web", "tier": "frontend"})
+		if _, err := chaosOperator.GetClient().GetClientset().CoreV1().Pods("default").Create(context.Background(), pod, metav1.CreateOptions{}); err != nil {
+			t.Fatalf("Failed to create pod: %v", err)
+		}
+	}
+	locks := newMemoryLocks()
+	chaosOperator.SetTargetLocks(locks)
+	return chaosOperator, locks
+}
+
+// podFailureConfig returns an experiment that kills the pods matching the selector
+func podFailureConfig(id, selector string, duration int) *operator.ExperimentConfig {
+	return &operator.ExperimentConfig{
+		ID:       id,
+		Type:     "pod-failure",
+		Params:   map[string]string{"namespace": "default", "selector": selector, "percentage": "50"},
+		Duration: duration,
+	}
+}
+
+func TestOperatorRejectsRunsOnLockedTarget(t *testing.T) {
+	chaosOperator, locks := newLockingOperator(t)
+
+	if _, err := chaosOperator.RunExperiment(podFailureConfig("holder", "app=web,tier=frontend", 300)); err != nil {
+		t.Fatalf("Failed to run experiment: %v", err)
+	}
+
+	// The same target selected in another order is rejected right away, not queued
+	_, err := chaosOperator.RunExperiment(podFailureConfig("contender", "tier=frontend,app=web", 300))
+	var conflict *storage.LockConflictError
+	if !errors.As(err, &conflict) {
+		t.Fatalf("Expected a lock conflict, got %v", err)
+	}
+	if conflict.Lock.ExperimentID != "holder" || conflict.Lock.Owner != "operator/api-server" {
+		t.Errorf("Expected the lock to be held by the operator run of holder, got %+v", conflict.Lock)
+	}
+	if _, err := chaosOperator.GetExperimentStatus("contender"); err == nil {
+		t.Errorf("Expected the rejected experiment not to be tracked")
+	}
+
+	// Stopping the holder frees the target for the next run
+	if err := chaosOperator.StopExperiment("holder"); err != nil {
+		t.Fatalf("Failed to stop experiment: %v", err)
+	}
+	if count := locks.held(); count != 0 {
+		t.Fatalf("Expected the lock to be released after the stop, %d held", count)
+	}
+	if _, err := chaosOperator.RunExperiment(podFailureConfig("contender", "tier=frontend,app=web", 300)); err != nil {
+		t.Fatalf("Expected the freed target to be locked again, got %v", err)
+	}
+	chaosOperator.StopAll("Test finished")
+}
+
+func TestOperatorReleasesLockWhenRunEnds(t *testing.T) {
+	chaosOperator, locks := newLockingOperator(t)
+
+	if _, err := chaosOperator.RunExperiment(podFailureConfig("short", "app=web", 1)); err != nil {
+		t.Fatalf("Failed to run experiment: %v", err)
+	}
+	if count := locks.held(); count != 1 {
+		t.Fatalf("Expected the target to be locked during the run, %d held", count)
+	}
+
+	deadline := time.Now().Add(10 * time.Second)
+	for {
+		status, _ := chaosOperator.GetExperimentStatus("short")
+		if status != string(storage.StatusRunning) {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("Timed out waiting for the run to end")
+		}
+		time.Sleep(50 * time.Millisecond)
+	}
+
+	if count := locks.held(); count != 0 {
+		t.Errorf("Expected the lock to be released when the run ended, %d held", count)
+	}
+}